@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// display-config 导出或导入当前用户的显示配置，用于把标准的显示布局部署到显示器相同的多台机器上。
+//
+//	display-config export [file]
+//	display-config import [file]
+//
+// 不指定 file 时使用标准输出或标准输入。
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func init() {
+	log.SetFlags(log.Lshortfile)
+}
+
+const (
+	displayServiceName = "org.deepin.dde.Display1"
+	displayPath        = "/org/deepin/dde/Display1"
+	displayInterface   = "org.deepin.dde.Display1"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s export|import [file]\n", os.Args[0])
+	os.Exit(2)
+}
+
+func exportConfig(obj dbus.BusObject, filename string) error {
+	var config string
+	err := obj.Call(displayInterface+".ExportConfig", 0).Store(&config)
+	if err != nil {
+		return err
+	}
+	config += "\n"
+	if filename == "" {
+		_, err = io.WriteString(os.Stdout, config)
+		return err
+	}
+	return os.WriteFile(filename, []byte(config), 0644)
+}
+
+func importConfig(obj dbus.BusObject, filename string) error {
+	var data []byte
+	var err error
+	if filename == "" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+	return obj.Call(displayInterface+".ImportConfig", 0, string(data)).Err
+}
+
+func main() {
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		usage()
+	}
+	var filename string
+	if len(os.Args) == 3 {
+		filename = os.Args[2]
+	}
+
+	sessionBus, err := dbus.SessionBus()
+	if err != nil {
+		log.Fatal(err)
+	}
+	obj := sessionBus.Object(displayServiceName, displayPath)
+
+	switch os.Args[1] {
+	case "export":
+		err = exportConfig(obj, filename)
+	case "import":
+		err = importConfig(obj, filename)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -5,17 +5,16 @@
 package main
 
 import (
-	"bytes"
 	"github.com/linuxdeepin/go-x11-client/ext/input"
 	"github.com/linuxdeepin/go-x11-client/ext/xfixes"
-	"os/exec"
-	"sort"
-	"strings"
+	"sync"
+	"time"
 
+	"github.com/linuxdeepin/go-lib/log"
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display"
 	"golang.org/x/xerrors"
-	"github.com/linuxdeepin/go-lib/log"
 )
 
 var logger *log.Logger
@@ -23,6 +22,9 @@ var _hasRandr1d2 bool // 是否 randr 版本大于等于 1.2
 
 const evMaskForHideCursor uint32 = input.XIEventMaskRawMotion | input.XIEventMaskRawTouchBegin
 
+// 显示器插拔后等待一会儿再设置，一次插拔会产生多个事件
+const configureDelay = 500 * time.Millisecond
+
 func init() {
 	logger = log.NewLogger("deepin-greeter-display")
 }
@@ -31,7 +33,11 @@ type Manager struct {
 	xConn           *x.Conn
 	configTimestamp x.Timestamp
 	outputs         map[randr.Output]*Output
-	cursorShowed        bool
+	cursorShowed    bool
+	display         *display.GreeterDisplay
+
+	configureMu    sync.Mutex
+	configureTimer *time.Timer
 }
 
 type Output struct {
@@ -66,6 +72,7 @@ func newManager() (*Manager, error) {
 		}
 
 		m.configTimestamp = resources.ConfigTimestamp
+		m.display = display.NewGreeterDisplay(m.xConn)
 
 		for _, output := range resources.Outputs {
 			outputInfo, err := m.getOutputInfo(output)
@@ -90,11 +97,13 @@ func (m *Manager) listenEvent() {
 	eventChan := m.xConn.MakeAndAddEventChan(50)
 	root := m.xConn.GetDefaultScreen().Root
 	err := randr.SelectInputChecked(m.xConn, root,
-		randr.NotifyMaskOutputChange|randr.NotifyMaskScreenChange).Check(m.xConn)
+		randr.NotifyMaskOutputChange|randr.NotifyMaskCrtcChange|randr.NotifyMaskScreenChange).Check(m.xConn)
 	if err != nil {
 		logger.Warning("failed to select randr event:", err)
 		return
 	}
+	// 按保存的配置设置一次显示器
+	m.delayConfigure()
 
 	rrExtData := m.xConn.GetExtensionData(randr.Ext())
 	inputExtData := m.xConn.GetExtensionData(input.Ext())
@@ -106,11 +115,16 @@ func (m *Manager) listenEvent() {
 			switch event.SubCode {
 			case randr.NotifyOutputChange:
 				e, _ := event.NewOutputChangeNotifyEvent()
+				m.display.HandleEvent(e)
 				m.handleOutputChanged(e)
+			case randr.NotifyCrtcChange:
+				e, _ := event.NewCrtcChangeNotifyEvent()
+				m.display.HandleEvent(e)
 			}
 
 		case randr.ScreenChangeNotifyEventCode + rrExtData.FirstEvent:
 			event, _ := randr.NewScreenChangeNotifyEvent(ev)
+			m.display.HandleScreenChanged(event)
 			m.handleScreenChanged(event)
 
 		case x.GeGenericEventCode:
@@ -178,7 +192,7 @@ func (m *Manager) handleOutputChanged(ev *randr.OutputChangeNotifyEvent) {
 	}
 
 	if oldConnected != connected {
-		m.configure()
+		m.delayConfigure()
 	}
 }
 
@@ -189,47 +203,30 @@ func (m *Manager) handleScreenChanged(ev *randr.ScreenChangeNotifyEvent) {
 	}
 }
 
-func (m *Manager) configure() {
-	var connectedOutputs []*Output
-	for _, output := range m.outputs {
-		if output.connected {
-			connectedOutputs = append(connectedOutputs, output)
-		}
-	}
-	sort.Slice(connectedOutputs, func(i, j int) bool {
-		return connectedOutputs[i].id < connectedOutputs[j].id
-	})
-
-	var args []string
-	var prev string
-	first := true
-	for _, output := range connectedOutputs {
-		args = append(args, "--output", output.name, "--auto")
-
-		if first {
-			args = append(args, "--pos", "0x0")
-		}
-		if prev != "" {
-			args = append(args, "--right-of", prev)
-		}
-
-		first = false
-		prev = output.name
+// delayConfigure 在事件循环之外设置显示器，设置时需要处理 randr 事件
+func (m *Manager) delayConfigure() {
+	m.configureMu.Lock()
+	defer m.configureMu.Unlock()
+	if m.configureTimer == nil {
+		m.configureTimer = time.AfterFunc(configureDelay, m.configure)
+		return
 	}
+	m.configureTimer.Reset(configureDelay)
+}
 
-	for _, output := range m.outputs {
-		if !output.connected {
-			args = append(args, "--output", output.name, "--off")
-		}
+// configure 应用会话保存的系统级显示配置，没有匹配的配置时自动扩展
+func (m *Manager) configure() {
+	err := m.display.LoadSysConfig()
+	if err != nil {
+		logger.Warning("failed to load system display config:", err)
 	}
-
-	logger.Debugf("$ xrandr %s", strings.Join(args, " "))
-	cmd := exec.Command("xrandr", args...)
-	var errBuf bytes.Buffer
-	cmd.Stderr = &errBuf
-	err := cmd.Run()
+	matched, err := m.display.Apply()
 	if err != nil {
-		logger.Warningf("xrandr exit err: %v, stderr: %s", err, errBuf.Bytes())
+		logger.Warning("failed to apply display config:", err)
+		return
+	}
+	if !matched {
+		logger.Debug("no saved display config, auto extend")
 	}
 }
 
@@ -245,7 +242,6 @@ func (m *Manager) initXExtensions() {
 		return
 	}
 
-
 	err = m.doXISelectEvents(evMaskForHideCursor)
 	if err != nil {
 		logger.Warning(err)
@@ -0,0 +1,357 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	sensorproxy "github.com/linuxdeepin/go-dbus-factory/system/net.hadess.sensorproxy"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 自适应亮度，从 iio-sensor-proxy 的 net.hadess.SensorProxy 读取环境光照度，按照度亮度曲线调节亮度。
+// 只通过 D-Bus 读取传感器，所以在会话总线上运行一个模拟的 net.hadess.SensorProxy 服务，
+// 并设置环境变量 DEEPIN_DISPLAY_LIGHT_SENSOR_BUS=session，配合模拟显示器就可以在没有硬件的环境中测试。
+//
+// 用户通过 SetAndSaveBrightness 或者 ChangeBrightness 手动调节亮度时，用当前照度和新亮度修改曲线；
+// SetBrightness 主要被电源模块用于临时变暗，只暂停自适应调节，直到 RefreshBrightness 恢复。
+
+const envLightSensorBus = "DEEPIN_DISPLAY_LIGHT_SENSOR_BUS"
+
+const (
+	adaptiveBrightnessStep = 200 * time.Millisecond
+	// 亮度每秒最多改变的值
+	adaptiveBrightnessRate = 0.25
+	// 目标亮度和当前亮度相差小于此值时不调节，避免频繁地微调
+	adaptiveBrightnessThreshold = 0.03
+	lightSmoothBrighten         = 2 * time.Second
+	lightSmoothDarken           = 8 * time.Second
+)
+
+type adaptiveBrightness struct {
+	mu       sync.Mutex
+	started  bool
+	enabled  bool
+	sensor   sensorproxy.SensorProxy
+	claimed  bool
+	curve    core.LightCurve
+	smoother core.LightSmoother
+	timer    *time.Timer
+
+	lux    float64
+	hasLux bool
+	// 正在向目标亮度调节
+	adjusting bool
+	// 被 SetBrightness 暂停
+	paused bool
+}
+
+func (a *adaptiveBrightness) resetTimerNoLock(delay time.Duration, fn func()) {
+	if a.timer == nil {
+		a.timer = time.AfterFunc(delay, fn)
+		return
+	}
+	a.timer.Stop()
+	a.timer.Reset(delay)
+}
+
+func (a *adaptiveBrightness) isActive() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enabled && a.claimed
+}
+
+func (m *Manager) initAdaptiveBrightness() {
+	a := &m.adaptiveBrightness
+	a.curve = core.DefaultLightCurve
+	a.smoother = core.LightSmoother{
+		Brighten: lightSmoothBrighten,
+		Darken:   lightSmoothDarken,
+	}
+}
+
+// loadAdaptiveBrightness 从配置加载开关
+func (m *Manager) loadAdaptiveBrightness(enabled bool) {
+	m.PropsMu.Lock()
+	m.setPropAdaptiveBrightness(enabled)
+	m.PropsMu.Unlock()
+
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.enabled == enabled {
+		return
+	}
+	a.enabled = enabled
+	if !a.started {
+		return
+	}
+	if enabled {
+		m.claimLightSensorNoLock()
+	} else {
+		m.releaseLightSensorNoLock()
+	}
+}
+
+// loadLightCurve 从配置加载学习过的曲线
+func (m *Manager) loadLightCurve(data string) error {
+	curve, err := core.ParseLightCurve(data)
+	if err != nil {
+		return err
+	}
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	a.curve = curve
+	if a.started && a.enabled && a.hasLux {
+		a.resetTimerNoLock(0, m.runAdaptiveBrightness)
+	}
+	a.mu.Unlock()
+	return nil
+}
+
+// dbus 上导出的方法
+func (m *Manager) setAdaptiveBrightness(enabled bool) error {
+	err := setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyAdaptiveBrightness, dbus.MakeVariant(enabled))
+	if err != nil {
+		return err
+	}
+	m.loadAdaptiveBrightness(enabled)
+	return nil
+}
+
+// dbus 上导出的方法
+func (m *Manager) resetLightCurve() error {
+	err := setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyLightCurve, dbus.MakeVariant(""))
+	if err != nil {
+		return err
+	}
+	return m.loadLightCurve("")
+}
+
+func getLightSensorBus(sysBus *dbus.Conn) (*dbus.Conn, error) {
+	if os.Getenv(envLightSensorBus) == "session" {
+		return dbus.SessionBus()
+	}
+	return sysBus, nil
+}
+
+func (m *Manager) startAdaptiveBrightness() {
+	bus, err := getLightSensorBus(m.sysBus)
+	if err != nil || bus == nil {
+		logger.Warning("failed to get light sensor bus:", err)
+		return
+	}
+	sigLoop := m.sysSigLoop
+	if bus != m.sysBus {
+		sigLoop = dbusutil.NewSignalLoop(bus, 10)
+		sigLoop.Start()
+	}
+
+	sensor := sensorproxy.NewSensorProxy(bus)
+	sensor.InitSignalExt(sigLoop, true)
+	err = sensor.LightLevel().ConnectChanged(func(hasValue bool, value float64) {
+		if !hasValue {
+			return
+		}
+		m.handleLightLevelChanged(value)
+	})
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	a.sensor = sensor
+	a.started = true
+	if a.enabled {
+		m.claimLightSensorNoLock()
+	}
+	a.mu.Unlock()
+}
+
+func (m *Manager) claimLightSensorNoLock() {
+	a := &m.adaptiveBrightness
+	if a.claimed || a.sensor == nil {
+		return
+	}
+	has, err := a.sensor.HasAmbientLight().Get(0)
+	if err != nil {
+		logger.Warning("failed to get HasAmbientLight:", err)
+		return
+	}
+	if !has {
+		logger.Info("no ambient light sensor, adaptive brightness does not work")
+		return
+	}
+	err = a.sensor.ClaimLight(0)
+	if err != nil {
+		logger.Warning("failed to claim light sensor:", err)
+		return
+	}
+	a.claimed = true
+	a.smoother.Reset()
+	a.paused = false
+
+	unit, _ := a.sensor.LightLevelUnit().Get(0)
+	if unit != "" && unit != "lux" {
+		logger.Info("light level unit is", unit, ", the curve is applied to it as lux")
+	}
+	lux, err := a.sensor.LightLevel().Get(0)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	a.lux = lux
+	a.hasLux = true
+	a.resetTimerNoLock(0, m.runAdaptiveBrightness)
+}
+
+func (m *Manager) releaseLightSensorNoLock() {
+	a := &m.adaptiveBrightness
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.adjusting = false
+	a.hasLux = false
+	if !a.claimed {
+		return
+	}
+	a.claimed = false
+	err := a.sensor.ReleaseLight(0)
+	if err != nil {
+		logger.Warning("failed to release light sensor:", err)
+	}
+}
+
+func (m *Manager) handleLightLevelChanged(lux float64) {
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled || !a.claimed {
+		return
+	}
+	a.lux = lux
+	wasIdle := !a.hasLux || !a.adjusting && a.smoother.Settled(a.lux)
+	a.hasLux = true
+	if wasIdle {
+		a.resetTimerNoLock(0, m.runAdaptiveBrightness)
+	}
+}
+
+// pauseAdaptiveBrightness 亮度被临时设置，暂停自适应调节
+func (m *Manager) pauseAdaptiveBrightness() {
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	a.paused = true
+	a.mu.Unlock()
+}
+
+// resumeAdaptiveBrightness 恢复自适应调节，返回是否已经接管亮度
+func (m *Manager) resumeAdaptiveBrightness() bool {
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.paused = false
+	if !a.enabled || !a.claimed || !a.hasLux {
+		return false
+	}
+	a.adjusting = true
+	a.resetTimerNoLock(0, m.runAdaptiveBrightness)
+	return true
+}
+
+// getAdaptiveBrightnessMonitors 返回自适应亮度调节的显示器，传感器一般在笔记本上，有内置显示器时只调节内置显示器
+func (m *Manager) getAdaptiveBrightnessMonitors() Monitors {
+	var result Monitors
+	builtinMonitor := m.getBuiltinMonitor()
+	for _, monitor := range m.getConnectedMonitors() {
+		if builtinMonitor != nil && monitor.ID != builtinMonitor.ID {
+			continue
+		}
+		monitor.PropsMu.RLock()
+		enabled := monitor.Enabled
+		monitor.PropsMu.RUnlock()
+		if !enabled {
+			continue
+		}
+		if can, _ := m.CanSetBrightness(monitor.Name); !can {
+			continue
+		}
+		result = append(result, monitor)
+	}
+	return result
+}
+
+// learnAdaptiveBrightness 用户手动调节了显示器 name 的亮度，修改曲线并保存
+func (m *Manager) learnAdaptiveBrightness(name string, value float64) {
+	if m.getAdaptiveBrightnessMonitors().GetByName(name) == nil {
+		return
+	}
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	if !a.enabled || !a.claimed || !a.hasLux {
+		a.mu.Unlock()
+		return
+	}
+	lux := a.smoother.Value()
+	a.curve = a.curve.Adjust(lux, value)
+	a.adjusting = false
+	a.paused = false
+	data := a.curve.String()
+	a.mu.Unlock()
+
+	logger.Debugf("adaptive brightness learn %v at %v lux", value, lux)
+	err := setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyLightCurve, dbus.MakeVariant(data))
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+func (m *Manager) runAdaptiveBrightness() {
+	a := &m.adaptiveBrightness
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled || !a.claimed || !a.hasLux || a.paused {
+		return
+	}
+
+	smoothed := a.smoother.Update(a.lux, time.Now())
+	target := a.curve.Brightness(smoothed)
+	maxDelta := adaptiveBrightnessRate * adaptiveBrightnessStep.Seconds()
+
+	reached := true
+	for _, monitor := range m.getAdaptiveBrightnessMonitors() {
+		monitor.PropsMu.RLock()
+		name := monitor.Name
+		current := monitor.Brightness
+		monitor.PropsMu.RUnlock()
+
+		if !a.adjusting && math.Abs(target-current) < adaptiveBrightnessThreshold {
+			continue
+		}
+		br := core.StepBrightness(current, target, maxDelta)
+		if br != target {
+			reached = false
+		}
+		if br == current {
+			continue
+		}
+		err := m.setBrightness(name, br)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	m.syncPropBrightness()
+
+	a.adjusting = !reached
+	if a.adjusting || !a.smoother.Settled(a.lux) {
+		a.resetTimerNoLock(adaptiveBrightnessStep, m.runAdaptiveBrightness)
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 type InvalidOutputNameError struct {
@@ -87,14 +89,7 @@ func (m *Manager) changeBrightness(raised bool) error {
 			v = 1.0
 		}
 
-		var br float64
-		br = v + step
-		if br > 1.0 {
-			br = 1.0
-		}
-		if br < 0.0 {
-			br = 0.0
-		}
+		br := core.ClampBrightness(v+step, 0)
 		logger.Debug("[changeBrightness] will set to:", monitor.Name, br)
 		err := m.setBrightnessAndSync(monitor.Name, br)
 		if err != nil {
@@ -102,6 +97,7 @@ func (m *Manager) changeBrightness(raised bool) error {
 			continue
 		}
 		successMap[monitor.Name] = br
+		m.learnAdaptiveBrightness(monitor.Name, br)
 	}
 	err := m.saveBrightnessInCfg(successMap)
 	if err != nil {
@@ -142,6 +138,9 @@ func (m *Manager) getBrightnessSetter() string {
 		}
 	}
 
+	if m.settings == nil {
+		return brightness.SetterAuto
+	}
 	return m.settings.GetString(gsKeySetter)
 }
 
@@ -175,15 +174,59 @@ func (m *Manager) isBuiltinMonitor(name string) bool {
 	return false
 }
 
+// brightnessTarget 设置亮度时需要的显示器信息
+type brightnessTarget struct {
+	monitorId  uint32
+	edidBase64 string
+	isBuiltin  bool
+	// 配置的亮度设置方式，可能是 auto
+	setter string
+}
+
+func (m *Manager) getBrightnessTarget(monitor *Monitor) brightnessTarget {
+	monitor.PropsMu.RLock()
+	edidBase64 := monitor.edidBase64
+	monitor.PropsMu.RUnlock()
+	return brightnessTarget{
+		monitorId:  monitor.ID,
+		edidBase64: edidBase64,
+		isBuiltin:  m.isBuiltinMonitor(monitor.Name),
+		setter:     m.getBrightnessSetter(),
+	}
+}
+
+// setHWBrightness 用背光、DDC/CI 或者 gamma 设置亮度，X 和 wayland 后端共用
+func setHWBrightness(conn *x.Conn, target brightnessTarget, value float64, temperature int) error {
+	return brightness.Set(value, temperature, target.setter, target.isBuiltin,
+		target.monitorId, target.edidBase64, conn)
+}
+
+// getHWBrightnessSetter 返回显示器实际使用的亮度设置方式，自动模式下会检测背光和 DDC/CI 支持
+func getHWBrightnessSetter(target brightnessTarget) string {
+	return brightness.GetSetter(target.setter, target.isBuiltin, target.edidBase64)
+}
+
+// canSetHWBrightness 自动模式下，只有能通过背光或者 DDC/CI 控制硬件亮度时才算支持；明确配置为 gamma 时仍用 gamma 模拟亮度
+func canSetHWBrightness(target brightnessTarget) bool {
+	switch target.setter {
+	case brightness.SetterAuto:
+		return getHWBrightnessSetter(target) != brightness.SetterGamma
+	case brightness.SetterDDCCI:
+		return brightness.SupportDDCCI(target.edidBase64)
+	}
+	return true
+}
+
 func (m *Manager) setMonitorBrightness(monitor *Monitor, brightnessValue float64, temperature int) error {
 	if !isValidColorTempValue(int32(temperature)) {
 		temperature = defaultTemperatureManual
 	}
+	return m.mm.setBrightness(m.getBrightnessTarget(monitor), brightnessValue, temperature)
+}
 
-	isBuiltin := m.isBuiltinMonitor(monitor.Name)
-	err := brightness.Set(brightnessValue, temperature, m.getBrightnessSetter(), isBuiltin,
-		monitor.ID, m.xConn)
-	return err
+// getMonitorBrightnessSetter 返回显示器实际使用的亮度设置方式
+func (m *Manager) getMonitorBrightnessSetter(monitor *Monitor) string {
+	return m.mm.getBrightnessSetter(m.getBrightnessTarget(monitor))
 }
 
 func (m *Manager) setBrightnessAux(fake bool, name string, value float64) error {
@@ -199,7 +242,7 @@ func (m *Manager) setBrightnessAux(fake bool, name string, value float64) error
 
 	value = math.Round(value*1000) / 1000 // 通过该方法，用来对亮度值(亮度值范围为0-1)四舍五入保留小数点后三位有效数字
 	if !fake && enabled {
-		temperature := m.getColorTemperatureValue()
+		temperature := m.getMonitorColorTemperatureValue(monitor)
 		// 保持最小亮度，不能全黑
 		if value <= 0.1 {
 			value = 0.1
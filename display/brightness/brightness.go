@@ -5,8 +5,10 @@
 package brightness
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sync"
 
 	"github.com/godbus/dbus/v5"
 	backlight "github.com/linuxdeepin/go-dbus-factory/system/org.deepin.dde.backlighthelper1"
@@ -27,11 +29,13 @@ const (
 	SetterAuto      = "auto"
 	SetterGamma     = "gamma"
 	SetterBacklight = "backlight"
+	SetterDDCCI     = "ddcci"
 )
 
 var logger = log.NewLogger("daemon/display/brightness")
 
 var helper backlight.Backlight
+var ddcciHelper backlight.DDCCI
 
 func InitBacklightHelper() {
 	var err error
@@ -40,9 +44,27 @@ func InitBacklightHelper() {
 		return
 	}
 	helper = backlight.NewBacklight(sysBus)
+	ddcciHelper = backlight.NewDDCCI(sysBus)
+	RefreshDDCCI()
 }
 
-func Set(brightness float64, temperature int, setter string, isBuiltin bool, outputId uint32, conn *x.Conn) error {
+// GetSetter 返回显示器实际使用的亮度设置方式，SetterBacklight，SetterDDCCI 或 SetterGamma
+func GetSetter(setter string, isBuiltin bool, edidBase64 string) string {
+	switch setter {
+	case SetterBacklight, SetterDDCCI, SetterGamma:
+		return setter
+	}
+	// case SetterAuto
+	if isBuiltin && supportBacklight() {
+		return SetterBacklight
+	}
+	if !isBuiltin && SupportDDCCI(edidBase64) {
+		return SetterDDCCI
+	}
+	return SetterGamma
+}
+
+func Set(brightness float64, temperature int, setter string, isBuiltin bool, outputId uint32, edidBase64 string, conn *x.Conn) error {
 	if brightness < 0 {
 		brightness = 0
 	} else if brightness > 1 {
@@ -69,6 +91,24 @@ func Set(brightness float64, temperature int, setter string, isBuiltin bool, out
 		return errs
 	}
 
+	// 亮度用 DDC/CI 设置，色温用 gamma
+	setDDCCIGamma := func() error {
+		var errs error
+		err := setDDCCIBrightness(brightness, edidBase64)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+		}
+
+		err = setOutputCrtcGamma(gammaSetting{
+			brightness:  1,
+			temperature: temperature,
+		}, output, conn)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+		}
+		return errs
+	}
+
 	// 亮度和色温都用 gamma 值设置
 	setGamma := func() error {
 		return setOutputCrtcGamma(gammaSetting{
@@ -78,18 +118,78 @@ func Set(brightness float64, temperature int, setter string, isBuiltin bool, out
 	}
 
 	setFn := setGamma
-	switch setter {
+	switch GetSetter(setter, isBuiltin, edidBase64) {
 	case SetterBacklight:
 		setFn = setBlGamma
-	case SetterAuto:
-		if isBuiltin && supportBacklight() {
-			setFn = setBlGamma
-		}
+	case SetterDDCCI:
+		setFn = setDDCCIGamma
 		//case SetterGamma
 	}
 	return setFn()
 }
 
+var ddcciSupportCache = struct {
+	mu sync.Mutex
+	m  map[string]bool
+}{m: make(map[string]bool)}
+
+// RefreshDDCCI 重新检测支持 DDC/CI 的显示器，在显示器插拔后调用。
+// 检测期间持有缓存的锁，避免 SupportDDCCI 把检测完成前的结果缓存下来。
+func RefreshDDCCI() {
+	ddcciSupportCache.mu.Lock()
+	defer ddcciSupportCache.mu.Unlock()
+
+	if ddcciHelper != nil {
+		err := ddcciHelper.RefreshDisplays(0)
+		if err != nil {
+			logger.Warning("failed to refresh ddc/ci displays:", err)
+		}
+	}
+	ddcciSupportCache.m = make(map[string]bool)
+}
+
+// SupportDDCCI 显示器是否支持通过 DDC/CI 调节亮度，结果会被缓存
+func SupportDDCCI(edidBase64 string) bool {
+	if ddcciHelper == nil || edidBase64 == "" {
+		return false
+	}
+	ddcciSupportCache.mu.Lock()
+	defer ddcciSupportCache.mu.Unlock()
+	if support, ok := ddcciSupportCache.m[edidBase64]; ok {
+		return support
+	}
+
+	support, err := ddcciHelper.CheckSupport(0, edidBase64)
+	if err != nil {
+		logger.Warning("failed to check ddc/ci support:", err)
+		// 检测失败时不缓存，下次再检测
+		return false
+	}
+	ddcciSupportCache.m[edidBase64] = support
+	return support
+}
+
+// GetDDCCI 读取显示器的 VCP 亮度值，范围是 0 到 1
+func GetDDCCI(edidBase64 string) (float64, error) {
+	if ddcciHelper == nil {
+		return 0, errors.New("ddc/ci helper is nil")
+	}
+	br, err := ddcciHelper.GetBrightness(0, edidBase64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(br) / 100, nil
+}
+
+func setDDCCIBrightness(value float64, edidBase64 string) error {
+	if ddcciHelper == nil {
+		return errors.New("ddc/ci helper is nil")
+	}
+	percent := int32(math.Round(value * 100))
+	logger.Debugf("ddcci set brightness %d", percent)
+	return ddcciHelper.SetBrightness(0, edidBase64, percent)
+}
+
 // unused function
 //func Get(setter string, isButiltin bool, outputId uint32, conn *x.Conn) (float64, error) {
 //	output := randr.Output(outputId)
@@ -163,6 +263,7 @@ func setOutputCrtcGamma(setting gammaSetting, output randr.Output, conn *x.Conn)
 	}
 
 	red, green, blue := initGammaRamp(int(gamma.Size))
+	setting.calibration = getCalibration(output)
 	fillColorRamp(red, green, blue, setting)
 	return randr.SetCrtcGammaChecked(conn, outputInfo.Crtc,
 		red, green, blue).Check(conn)
@@ -4,6 +4,8 @@
 
 package brightness
 
+import "math"
+
 // 从 redshift 项目复制的
 
 /* Whitepoint values for temperatures at 100K intervals.
@@ -265,6 +267,8 @@ func interpolateColor(a float64, c1, c2 []float64) [3]float64 {
 type gammaSetting struct {
 	brightness  float64
 	temperature int
+	// 校色曲线，可以为 nil
+	calibration *VCGT
 }
 
 func fillColorRamp(gammaR, gammaG, gammaB []uint16, setting gammaSetting) {
@@ -277,8 +281,15 @@ func fillColorRamp(gammaR, gammaG, gammaB []uint16, setting gammaSetting) {
 	size := len(gammaR)
 
 	for i := 0; i < size; i++ {
-		gammaR[i] = uint16(float64(gammaR[i]) * setting.brightness * whitePoint[0])
-		gammaG[i] = uint16(float64(gammaG[i]) * setting.brightness * whitePoint[1])
-		gammaB[i] = uint16(float64(gammaB[i]) * setting.brightness * whitePoint[2])
+		r, g, b := float64(gammaR[i]), float64(gammaG[i]), float64(gammaB[i])
+		if setting.calibration != nil {
+			// 先经过校色曲线，再调整亮度和色温
+			r = setting.calibration.Apply(0, r/math.MaxUint16) * math.MaxUint16
+			g = setting.calibration.Apply(1, g/math.MaxUint16) * math.MaxUint16
+			b = setting.calibration.Apply(2, b/math.MaxUint16) * math.MaxUint16
+		}
+		gammaR[i] = uint16(r * setting.brightness * whitePoint[0])
+		gammaG[i] = uint16(g * setting.brightness * whitePoint[1])
+		gammaB[i] = uint16(b * setting.brightness * whitePoint[2])
 	}
 }
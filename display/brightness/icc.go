@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package brightness
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// ICC 配置文件中的 vcgt（video card gamma table）标签保存了校色得到的显卡 gamma 曲线，
+// 设置 gamma 时先经过这条曲线，再乘以亮度和色温。
+
+const (
+	iccHeaderSize   = 128
+	iccTagEntrySize = 12
+
+	vcgtTypeTable   = 0
+	vcgtTypeFormula = 1
+)
+
+var errInvalidICCProfile = errors.New("invalid ICC profile")
+
+// VCGT 三个通道的校色曲线，曲线上的值都在 0 到 1 之间
+type VCGT struct {
+	curves [3][]float64
+}
+
+// ParseICCProfile 检查 ICC 配置文件的格式，返回其中的 vcgt，没有 vcgt 标签时返回 nil
+func ParseICCProfile(data []byte) (*VCGT, error) {
+	if len(data) < iccHeaderSize+4 || string(data[36:40]) != "acsp" {
+		return nil, errInvalidICCProfile
+	}
+	tagCount := int(binary.BigEndian.Uint32(data[iccHeaderSize:]))
+	tagTable := data[iccHeaderSize+4:]
+	if tagCount < 0 || len(tagTable) < tagCount*iccTagEntrySize {
+		return nil, errInvalidICCProfile
+	}
+	for i := 0; i < tagCount; i++ {
+		entry := tagTable[i*iccTagEntrySize:]
+		if string(entry[:4]) != "vcgt" {
+			continue
+		}
+		offset := int64(binary.BigEndian.Uint32(entry[4:]))
+		size := int64(binary.BigEndian.Uint32(entry[8:]))
+		if offset+size > int64(len(data)) {
+			return nil, errInvalidICCProfile
+		}
+		return parseVCGT(data[offset : offset+size])
+	}
+	return nil, nil
+}
+
+func parseVCGT(data []byte) (*VCGT, error) {
+	if len(data) < 12 || string(data[:4]) != "vcgt" {
+		return nil, errors.New("invalid vcgt tag")
+	}
+	gammaType := binary.BigEndian.Uint32(data[8:])
+	data = data[12:]
+	switch gammaType {
+	case vcgtTypeTable:
+		return parseVCGTTable(data)
+	case vcgtTypeFormula:
+		return parseVCGTFormula(data)
+	}
+	return nil, fmt.Errorf("unknown vcgt type %d", gammaType)
+}
+
+func parseVCGTTable(data []byte) (*VCGT, error) {
+	if len(data) < 6 {
+		return nil, errors.New("invalid vcgt table")
+	}
+	channels := int(binary.BigEndian.Uint16(data))
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	entrySize := int(binary.BigEndian.Uint16(data[4:]))
+	data = data[6:]
+	if (channels != 1 && channels != 3) || count < 2 || (entrySize != 1 && entrySize != 2) ||
+		len(data) < channels*count*entrySize {
+		return nil, errors.New("invalid vcgt table")
+	}
+
+	var vcgt VCGT
+	for c := 0; c < channels; c++ {
+		curve := make([]float64, count)
+		for i := range curve {
+			idx := (c*count + i) * entrySize
+			if entrySize == 1 {
+				curve[i] = float64(data[idx]) / math.MaxUint8
+			} else {
+				curve[i] = float64(binary.BigEndian.Uint16(data[idx:])) / math.MaxUint16
+			}
+		}
+		vcgt.curves[c] = curve
+	}
+	if channels == 1 {
+		vcgt.curves[1] = vcgt.curves[0]
+		vcgt.curves[2] = vcgt.curves[0]
+	}
+	return &vcgt, nil
+}
+
+// vcgt 公式类型的曲线按这个数量采样
+const vcgtFormulaSize = 256
+
+func parseVCGTFormula(data []byte) (*VCGT, error) {
+	if len(data) < 36 {
+		return nil, errors.New("invalid vcgt formula")
+	}
+	// 每个通道依次是 gamma、最小值、最大值，都是 s15Fixed16Number
+	s15f16 := func(b []byte) float64 {
+		return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+	}
+	var vcgt VCGT
+	for c := 0; c < 3; c++ {
+		gamma := s15f16(data[c*12:])
+		min := s15f16(data[c*12+4:])
+		max := s15f16(data[c*12+8:])
+		if gamma <= 0 {
+			return nil, errors.New("invalid vcgt formula")
+		}
+		curve := make([]float64, vcgtFormulaSize)
+		for i := range curve {
+			x := float64(i) / (vcgtFormulaSize - 1)
+			curve[i] = clamp01(min + (max-min)*math.Pow(x, gamma))
+		}
+		vcgt.curves[c] = curve
+	}
+	return &vcgt, nil
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	} else if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// Apply 用通道 channel 的曲线映射 value，value 在 0 到 1 之间，曲线上的点之间线性插值
+func (v *VCGT) Apply(channel int, value float64) float64 {
+	curve := v.curves[channel]
+	pos := clamp01(value) * float64(len(curve)-1)
+	idx := int(pos)
+	if idx >= len(curve)-1 {
+		return curve[len(curve)-1]
+	}
+	frac := pos - float64(idx)
+	return curve[idx]*(1-frac) + curve[idx+1]*frac
+}
+
+var calibrations = struct {
+	mu sync.Mutex
+	m  map[randr.Output]*VCGT
+}{m: make(map[randr.Output]*VCGT)}
+
+// SetCalibration 设置输出的校色曲线，vcgt 为 nil 时清除，下次设置亮度或色温时生效，返回校色曲线是否改变
+func SetCalibration(outputId uint32, vcgt *VCGT) bool {
+	calibrations.mu.Lock()
+	defer calibrations.mu.Unlock()
+	old := calibrations.m[randr.Output(outputId)]
+	if vcgt == nil {
+		delete(calibrations.m, randr.Output(outputId))
+		return old != nil
+	}
+	calibrations.m[randr.Output(outputId)] = vcgt
+	return old == nil || !reflect.DeepEqual(old.curves, vcgt.curves)
+}
+
+func getCalibration(output randr.Output) *VCGT {
+	calibrations.mu.Lock()
+	defer calibrations.mu.Unlock()
+	return calibrations.m[output]
+}
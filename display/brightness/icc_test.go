@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package brightness
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// buildICCProfile 生成只有一个 vcgt 标签的 ICC 配置文件
+func buildICCProfile(vcgt []byte) []byte {
+	data := make([]byte, iccHeaderSize+4+iccTagEntrySize)
+	copy(data[36:], "acsp")
+	binary.BigEndian.PutUint32(data[iccHeaderSize:], 1)
+	entry := data[iccHeaderSize+4:]
+	copy(entry, "vcgt")
+	binary.BigEndian.PutUint32(entry[4:], uint32(len(data)))
+	binary.BigEndian.PutUint32(entry[8:], uint32(len(vcgt)))
+	return append(data, vcgt...)
+}
+
+func TestParseICCProfileTable(t *testing.T) {
+	tag := []byte("vcgt\x00\x00\x00\x00\x00\x00\x00\x00")
+	// 3 个通道，每个 2 项，每项 2 字节
+	tag = append(tag, 0, 3, 0, 2, 0, 2)
+	for _, v := range []uint16{0, 0xffff, 0, 0x8000, 0x1000, 0xf000} {
+		tag = binary.BigEndian.AppendUint16(tag, v)
+	}
+	vcgt, err := ParseICCProfile(buildICCProfile(tag))
+	if err != nil || vcgt == nil {
+		t.Fatalf("ParseICCProfile: %v, %v", vcgt, err)
+	}
+	if v := vcgt.Apply(0, 0.5); math.Abs(v-0.5) > 1e-6 {
+		t.Errorf("red(0.5) = %v", v)
+	}
+	if v := vcgt.Apply(1, 1); math.Abs(v-float64(0x8000)/math.MaxUint16) > 1e-6 {
+		t.Errorf("green(1) = %v", v)
+	}
+	if v := vcgt.Apply(2, 0); math.Abs(v-float64(0x1000)/math.MaxUint16) > 1e-6 {
+		t.Errorf("blue(0) = %v", v)
+	}
+}
+
+func TestParseICCProfileFormula(t *testing.T) {
+	tag := []byte("vcgt\x00\x00\x00\x00\x00\x00\x00\x01")
+	for c := 0; c < 3; c++ {
+		// gamma 1，最小值 0，最大值 0.5
+		tag = binary.BigEndian.AppendUint32(tag, 0x10000)
+		tag = binary.BigEndian.AppendUint32(tag, 0)
+		tag = binary.BigEndian.AppendUint32(tag, 0x8000)
+	}
+	vcgt, err := ParseICCProfile(buildICCProfile(tag))
+	if err != nil || vcgt == nil {
+		t.Fatalf("ParseICCProfile: %v, %v", vcgt, err)
+	}
+	if v := vcgt.Apply(1, 1); math.Abs(v-0.5) > 1e-6 {
+		t.Errorf("green(1) = %v", v)
+	}
+}
+
+func TestParseICCProfileInvalid(t *testing.T) {
+	if _, err := ParseICCProfile([]byte("not an icc profile")); err == nil {
+		t.Error("expect error for invalid data")
+	}
+	// 没有 vcgt 标签
+	data := make([]byte, iccHeaderSize+4)
+	copy(data[36:], "acsp")
+	vcgt, err := ParseICCProfile(data)
+	if err != nil || vcgt != nil {
+		t.Errorf("ParseICCProfile without vcgt: %v, %v", vcgt, err)
+	}
+}
+
+func TestFillColorRampCalibration(t *testing.T) {
+	const size = 256
+	r, g, b := initGammaRamp(size)
+	vcgt := &VCGT{curves: [3][]float64{{0, 0.5}, {0, 1}, {0, 1}}}
+	fillColorRamp(r, g, b, gammaSetting{
+		brightness:  1,
+		temperature: 6500,
+		calibration: vcgt,
+	})
+	if r[size-1] > math.MaxUint16/2 || g[size-1] < math.MaxUint16-512 {
+		t.Errorf("unexpected ramp end: r %d, g %d", r[size-1], g[size-1])
+	}
+}
+
+func TestSetCalibration(t *testing.T) {
+	const output = 100
+	defer SetCalibration(output, nil)
+
+	vcgt := &VCGT{curves: [3][]float64{{0, 0.5}, {0, 1}, {0, 1}}}
+	if !SetCalibration(output, vcgt) {
+		t.Error("set new calibration: want changed")
+	}
+	// 重新解析同一个配置文件得到相同的曲线
+	same := &VCGT{curves: [3][]float64{{0, 0.5}, {0, 1}, {0, 1}}}
+	if SetCalibration(output, same) {
+		t.Error("set same calibration: want unchanged")
+	}
+	if !SetCalibration(output, nil) {
+		t.Error("clear calibration: want changed")
+	}
+	if SetCalibration(output, nil) {
+		t.Error("clear empty calibration: want unchanged")
+	}
+}
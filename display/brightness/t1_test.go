@@ -4,7 +4,13 @@
 
 package brightness
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	backlight "github.com/linuxdeepin/go-dbus-factory/system/org.deepin.dde.backlighthelper1"
+)
 
 func TestFillColorRamp(t *testing.T) {
 	const size = 1024
@@ -24,3 +30,72 @@ func TestFillColorRamp(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSetter(t *testing.T) {
+	// 没有 backlight 和 DDC/CI helper 时，自动模式使用 gamma
+	if s := GetSetter(SetterAuto, true, ""); s != SetterGamma {
+		t.Errorf("GetSetter auto builtin = %q, want %q", s, SetterGamma)
+	}
+	if s := GetSetter(SetterAuto, false, "AP///////wA="); s != SetterGamma {
+		t.Errorf("GetSetter auto external = %q, want %q", s, SetterGamma)
+	}
+	for _, setter := range []string{SetterGamma, SetterBacklight, SetterDDCCI} {
+		if s := GetSetter(setter, false, ""); s != setter {
+			t.Errorf("GetSetter %q = %q", setter, s)
+		}
+	}
+}
+
+// stubDDCCI 在 RefreshDisplays 完成前，显示器都不支持 DDC/CI
+type stubDDCCI struct {
+	backlight.DDCCI
+	started    chan struct{}
+	refreshing chan struct{}
+	refreshed  bool
+}
+
+func (s *stubDDCCI) RefreshDisplays(flags dbus.Flags) error {
+	close(s.started)
+	<-s.refreshing
+	s.refreshed = true
+	return nil
+}
+
+func (s *stubDDCCI) CheckSupport(flags dbus.Flags, edidChecksum string) (bool, error) {
+	return s.refreshed, nil
+}
+
+func TestRefreshDDCCI(t *testing.T) {
+	stub := &stubDDCCI{
+		started:    make(chan struct{}),
+		refreshing: make(chan struct{}),
+	}
+	ddcciHelper = stub
+	defer func() {
+		ddcciHelper = nil
+	}()
+
+	const edid = "AP///////wA="
+	done := make(chan struct{})
+	go func() {
+		RefreshDDCCI()
+		close(done)
+	}()
+
+	// 检测期间查询的结果不能被缓存
+	<-stub.started
+	support := make(chan bool)
+	go func() {
+		support <- SupportDDCCI(edid)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(stub.refreshing)
+	<-done
+
+	if !<-support {
+		t.Error("SupportDDCCI during refresh = false, want true")
+	}
+	if !SupportDDCCI(edid) {
+		t.Error("SupportDDCCI after refresh = false, want true")
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 亮度计划，到达计划中的时间点后，在 ramp 时间内把所有显示器的亮度平滑地调整到计划的亮度。
+// 用户手动调节亮度后，直到下一个时间点之前都不再按计划调节。
+
+const (
+	brightnessScheduleStep = 30 * time.Second
+	// 启动或者唤醒时，已经错过的时间点在这个时间内调整到位
+	brightnessScheduleCatchUp = time.Minute
+	// 没有调整时也定期检查，因为休眠期间定时器不计时
+	brightnessScheduleMaxWait     = 5 * time.Minute
+	defaultBrightnessScheduleRamp = 15 * time.Minute
+)
+
+type brightnessScheduler struct {
+	mu       sync.Mutex
+	started  bool
+	timer    *time.Timer
+	schedule core.BrightnessSchedule
+	ramp     time.Duration
+
+	// 当前时间点的开始时间，和调整结束的时间
+	since   time.Time
+	rampEnd time.Time
+	done    bool
+	// 手动调节亮度后，在此时间之前不按计划调节
+	yieldUntil time.Time
+}
+
+func (s *brightnessScheduler) resetTimerNoLock(delay time.Duration, fn func()) {
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, fn)
+		return
+	}
+	s.timer.Stop()
+	s.timer.Reset(delay)
+}
+
+// loadBrightnessSchedule 更新亮度计划，已经开始运行时立即按新的计划调整亮度
+func (m *Manager) loadBrightnessSchedule(data string) error {
+	schedule, err := core.ParseBrightnessSchedule(data)
+	if err != nil {
+		return err
+	}
+	m.PropsMu.Lock()
+	m.setPropBrightnessSchedule(data)
+	m.PropsMu.Unlock()
+
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	s.schedule = schedule
+	s.since = time.Time{}
+	s.yieldUntil = time.Time{}
+	if s.started {
+		s.resetTimerNoLock(0, m.runBrightnessSchedule)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (m *Manager) setBrightnessScheduleRamp(ramp time.Duration) {
+	if ramp < 0 {
+		ramp = defaultBrightnessScheduleRamp
+	}
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	s.ramp = ramp
+	s.mu.Unlock()
+}
+
+// dbus 上导出的方法
+func (m *Manager) setBrightnessSchedule(data string) error {
+	_, err := core.ParseBrightnessSchedule(data)
+	if err != nil {
+		return err
+	}
+	err = setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyBrightnessSchedule, dbus.MakeVariant(data))
+	if err != nil {
+		return err
+	}
+	return m.loadBrightnessSchedule(data)
+}
+
+func (m *Manager) startBrightnessSchedule() {
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	s.started = true
+	s.resetTimerNoLock(0, m.runBrightnessSchedule)
+	s.mu.Unlock()
+}
+
+// triggerBrightnessSchedule 立即检查一次亮度计划，比如从休眠中唤醒时
+func (m *Manager) triggerBrightnessSchedule() {
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	if s.started {
+		s.resetTimerNoLock(0, m.runBrightnessSchedule)
+	}
+	s.mu.Unlock()
+}
+
+// yieldBrightnessSchedule 用户手动调节了亮度，到下一个时间点之前不再按计划调节
+func (m *Manager) yieldBrightnessSchedule() {
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.schedule) == 0 {
+		return
+	}
+	now := getTimeZoneNow()
+	next, err := s.schedule.Next(now, m.colorTempRunner.getGeoPosition())
+	if err != nil {
+		return
+	}
+	logger.Debug("brightness schedule yield to manual change until", next)
+	s.yieldUntil = next
+	s.done = true
+}
+
+func (m *Manager) runBrightnessSchedule() {
+	s := &m.brightnessScheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.schedule) == 0 {
+		return
+	}
+
+	now := getTimeZoneNow()
+	pos := m.colorTempRunner.getGeoPosition()
+	next, err := s.schedule.Next(now, pos)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	wait := next.Sub(now)
+	if wait > brightnessScheduleMaxWait {
+		wait = brightnessScheduleMaxWait
+	}
+
+	if now.Before(s.yieldUntil) {
+		s.resetTimerNoLock(wait, m.runBrightnessSchedule)
+		return
+	}
+
+	level, since, err := s.schedule.Target(now, pos)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	if !since.Equal(s.since) {
+		// 到达新的时间点
+		s.since = since
+		s.done = false
+		s.rampEnd = since.Add(s.ramp)
+		if catchUp := now.Add(brightnessScheduleCatchUp); s.rampEnd.Before(catchUp) {
+			s.rampEnd = catchUp
+		}
+		logger.Debugf("brightness schedule: ramp to %v until %v", level, s.rampEnd)
+	}
+
+	if !s.done {
+		s.done = m.rampBrightness(level, now, s.rampEnd)
+		if !s.done {
+			wait = brightnessScheduleStep
+		}
+	}
+	s.resetTimerNoLock(wait, m.runBrightnessSchedule)
+}
+
+// rampBrightness 把所有显示器的亮度向 level 调整一步，返回是否已经调整到位，调整到位时保存亮度
+func (m *Manager) rampBrightness(level float64, now, rampEnd time.Time) (reached bool) {
+	reached = !now.Add(brightnessScheduleStep).Before(rampEnd)
+	valueMap := make(map[string]float64)
+	monitors := m.getConnectedMonitors()
+	var adaptiveMonitors Monitors
+	if m.adaptiveBrightness.isActive() {
+		// 由自适应亮度调节的显示器不按计划调节
+		adaptiveMonitors = m.getAdaptiveBrightnessMonitors()
+	}
+	for _, monitor := range monitors {
+		monitor.PropsMu.RLock()
+		name := monitor.Name
+		enabled := monitor.Enabled
+		current := monitor.Brightness
+		monitor.PropsMu.RUnlock()
+
+		if !enabled || adaptiveMonitors.GetByName(name) != nil {
+			continue
+		}
+		if can, _ := m.CanSetBrightness(name); !can {
+			continue
+		}
+
+		br := core.RampBrightness(current, level, now, rampEnd, brightnessScheduleStep)
+		if br != current {
+			err := m.setBrightness(name, br)
+			if err != nil {
+				logger.Warning(err)
+				continue
+			}
+		}
+		valueMap[name] = br
+	}
+	m.syncPropBrightness()
+
+	if reached {
+		err := m.saveBrightnessInCfg(valueMap)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	return reached
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/startdde/display/brightness"
+)
+
+// 色彩管理，每个显示器可以指定一个 ICC 配置文件，按显示器的 uuid 保存在用户配置的 Monitors 中。
+// 配置文件中的 vcgt 校色曲线和亮度、色温一起设置到 gamma 中；
+// 配置文件的内容设置到根窗口的 _ICC_PROFILE 和 _ICC_PROFILE_<n> 属性上，供 X11 应用做色彩管理，
+// 按 ICC Profiles in X 规范，n 是 Xinerama 屏幕的序号，主屏在最前面。
+
+const atomICCProfile = "_ICC_PROFILE"
+
+// readColorProfile 读取并检查 ICC 配置文件，返回文件内容和其中的校色曲线
+func readColorProfile(filename string) ([]byte, *brightness.VCGT, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	vcgt, err := brightness.ParseICCProfile(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return data, vcgt, nil
+}
+
+// getICCProfileAtomName 返回第 idx 个 Xinerama 屏幕的 ICC 属性名
+func getICCProfileAtomName(idx int) string {
+	if idx == 0 {
+		return atomICCProfile
+	}
+	return fmt.Sprintf("%s_%d", atomICCProfile, idx)
+}
+
+type iccProfileScreen struct {
+	name    string
+	x       int16
+	y       int16
+	profile []byte
+}
+
+// sortICCProfileScreens 按 Xinerama 屏幕的顺序排列，主屏在最前面，其余的按位置排列
+func sortICCProfileScreens(screens []iccProfileScreen, primary string) {
+	sort.SliceStable(screens, func(i, j int) bool {
+		a, b := screens[i], screens[j]
+		if (a.name == primary) != (b.name == primary) {
+			return a.name == primary
+		}
+		if a.x != b.x {
+			return a.x < b.x
+		}
+		return a.y < b.y
+	})
+}
+
+// loadMonitorColorProfile 从用户配置加载显示器的 ICC 配置文件，设置属性和校色曲线，返回校色曲线是否改变。
+// force 为 false 时，只在显示器的 uuid 或者配置的文件改变后才重新读取。
+func (m *Manager) loadMonitorColorProfile(monitor *Monitor, force bool) bool {
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	loaded := monitor.iccProfileUuid == uuid && uuid != ""
+	current := monitor.ColorProfile
+	monitor.PropsMu.RUnlock()
+	filename := m.getUserMonitorConfig(uuid).ColorProfile
+	if !force && loaded && filename == current {
+		return false
+	}
+
+	var data []byte
+	var vcgt *brightness.VCGT
+	if filename != "" {
+		var err error
+		data, vcgt, err = readColorProfile(filename)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	changed := brightness.SetCalibration(monitor.ID, vcgt)
+
+	monitor.PropsMu.Lock()
+	monitor.iccProfile = data
+	monitor.iccProfileUuid = uuid
+	monitor.setPropColorProfile(filename)
+	monitor.PropsMu.Unlock()
+	return changed
+}
+
+func (m *Manager) loadMonitorsColorProfile() {
+	for _, monitor := range m.getConnectedMonitors() {
+		m.loadMonitorColorProfile(monitor, false)
+	}
+}
+
+// updateMonitorColorProfile 显示器的 uuid 或者配置的文件改变后重新加载，校色曲线改变时重新设置亮度
+func (m *Manager) updateMonitorColorProfile(monitor *Monitor) {
+	if !m.loadMonitorColorProfile(monitor, false) {
+		return
+	}
+	m.updateICCProfileAtoms()
+
+	monitor.PropsMu.RLock()
+	name := monitor.Name
+	br := monitor.Brightness
+	enabled := monitor.Enabled
+	monitor.PropsMu.RUnlock()
+	if !enabled {
+		return
+	}
+	go func() {
+		_setColorTempMu.Lock()
+		defer _setColorTempMu.Unlock()
+		err := m.setBrightness(name, br)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}()
+}
+
+// updateICCProfileAtoms 按当前的布局设置根窗口上的 _ICC_PROFILE 属性
+func (m *Manager) updateICCProfileAtoms() {
+	if _useWayland || m.xConn == nil {
+		return
+	}
+	m.PropsMu.RLock()
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	var screens []iccProfileScreen
+	for _, monitor := range m.getConnectedMonitors() {
+		monitor.PropsMu.RLock()
+		if monitor.Enabled {
+			screens = append(screens, iccProfileScreen{
+				name:    monitor.Name,
+				x:       monitor.X,
+				y:       monitor.Y,
+				profile: monitor.iccProfile,
+			})
+		}
+		monitor.PropsMu.RUnlock()
+	}
+	sortICCProfileScreens(screens, primary)
+
+	m.iccProfileAtomsMu.Lock()
+	defer m.iccProfileAtomsMu.Unlock()
+	root := m.xConn.GetDefaultScreen().Root
+	num := len(screens)
+	if m.iccProfileAtomNum > num {
+		num = m.iccProfileAtomNum
+	}
+	for i := 0; i < num; i++ {
+		atom, err := m.xConn.GetAtom(getICCProfileAtomName(i))
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		if i < len(screens) && len(screens[i].profile) > 0 {
+			err = x.ChangePropertyChecked(m.xConn, x.PropModeReplace, root, atom,
+				x.AtomCardinal, 8, screens[i].profile).Check(m.xConn)
+		} else {
+			err = x.DeletePropertyChecked(m.xConn, root, atom).Check(m.xConn)
+		}
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	m.iccProfileAtomNum = len(screens)
+}
+
+// dbus 上导出的方法
+func (m *Manager) setMonitorColorProfile(monitor *Monitor, filename string) error {
+	if filename != "" {
+		_, _, err := readColorProfile(filename)
+		if err != nil {
+			return err
+		}
+	}
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	name := monitor.Name
+	br := monitor.Brightness
+	monitor.PropsMu.RUnlock()
+
+	err := m.updateUserMonitorConfig(uuid, func(cfg *UserMonitorConfig) {
+		cfg.ColorProfile = filename
+	})
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	m.loadMonitorColorProfile(monitor, true)
+	m.updateICCProfileAtoms()
+	_setColorTempMu.Lock()
+	defer _setColorTempMu.Unlock()
+	return m.setBrightness(name, br)
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getICCProfileAtomName(t *testing.T) {
+	assert.Equal(t, "_ICC_PROFILE", getICCProfileAtomName(0))
+	assert.Equal(t, "_ICC_PROFILE_2", getICCProfileAtomName(2))
+}
+
+func Test_sortICCProfileScreens(t *testing.T) {
+	screens := []iccProfileScreen{
+		{name: "DP-1", x: 1920},
+		{name: "HDMI-1", x: 0},
+		{name: "eDP-1", x: 3840},
+	}
+	sortICCProfileScreens(screens, "eDP-1")
+	var names []string
+	for _, screen := range screens {
+		names = append(names, screen.name)
+	}
+	assert.Equal(t, []string{"eDP-1", "HDMI-1", "DP-1"}, names)
+}
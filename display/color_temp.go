@@ -5,14 +5,9 @@
 package display
 
 import (
-	"bufio"
-	"bytes"
 	"errors"
-	"math"
-	"os"
 	"os/exec"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +17,7 @@ import (
 	"github.com/godbus/dbus/v5"
 	geoclue2 "github.com/linuxdeepin/go-dbus-factory/system/org.freedesktop.geoclue2"
 	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 const (
@@ -35,8 +31,6 @@ const (
 )
 
 const (
-	timeZoneFile = "/usr/share/zoneinfo/zone1970.tab"
-
 	defaultAutoColorTemperatureConf = "6500:3500"
 	defaultTemperature              = 6500
 )
@@ -67,15 +61,15 @@ func (m *Manager) setColorTempModeReal(mode int32) {
 	}
 	switch mode {
 	case ColorTemperatureModeAuto: // 自动模式调节色温 启动服务
-		m.redshiftRunner.start()
+		m.colorTempRunner.start()
 		m.stopCustomColorTempMode()
 	case ColorTemperatureModeManual, ColorTemperatureModeNone:
 		// manual 手动调节色温
 		// none 恢复正常色温
-		m.redshiftRunner.stop()
+		m.colorTempRunner.stop()
 		m.stopCustomColorTempMode()
 	case ColorTemperatureModeCustom:
-		m.redshiftRunner.stop()
+		m.colorTempRunner.stop()
 		m.listenCustomColorTempTime()
 	}
 	// 对于自动模式，也要先把色温设置为正常。
@@ -83,159 +77,155 @@ func (m *Manager) setColorTempModeReal(mode int32) {
 }
 
 const (
-	redshiftStateRunning = iota + 1
-	redshiftStateStopped
+	colorTempRunnerStateRunning = iota + 1
+	colorTempRunnerStateStopped
 )
 
-type zoneInfo struct {
-	country   string
-	latitude  float64
-	longitude float64
-	distance  float64
-}
+// 自动色温模式下重新计算色温的间隔
+const colorTempUpdateInterval = time.Minute
 
-type redshiftRunner struct {
+// colorTempRunner 在自动色温模式下，根据时区的经纬度计算日出日落时间，定期更新色温。
+type colorTempRunner struct {
 	mu                 sync.Mutex
 	state              int
 	timer              *time.Timer
-	process            *os.Process
+	auto               core.AutoColorTemp
 	value              int
 	cb                 func(value int)
 	sysService         *dbusutil.Service
 	geoAgentRegistered bool
 
-	zoneInfoMap map[string]*zoneInfo
+	// 时区名称到经纬度的映射
+	zonePositions map[string]core.GeoPosition
 }
 
-func convertPos(pos string, digits int32) float64 {
-	if len(pos) < 4 || digits > 9 {
-		return 0.0
-	}
-
-	integer := pos[:digits+1]
-	fraction := pos[digits+1:]
-	t1, _ := strconv.ParseFloat(integer, 64)
-	t2, _ := strconv.ParseFloat(fraction, 64)
-	if t1 > 0.0 {
-		return t1 + t2/math.Pow(10.0, float64(len(fraction)))
-	} else {
-		return t1 - t2/math.Pow(10.0, float64(len(fraction)))
-	}
-}
-
-func newRedshiftRunner() *redshiftRunner {
+func newColorTempRunner() *colorTempRunner {
 	sysService, err := dbusutil.NewSystemService()
 	if err != nil {
 		logger.Warning("new sys service failed:", err)
 	}
-	zoneInfoMap := make(map[string]*zoneInfo)
-	contents, err := os.ReadFile(timeZoneFile)
+	zonePositions, err := core.LoadZonePositions()
 	if err != nil {
 		logger.Warning("Red timezone file failed:", err)
 	}
-	lines := bytes.Split(contents, []byte{'\n'})
-	for _, line := range lines {
-		if !bytes.HasPrefix(line, []byte{'#'}) {
-			parts := bytes.Split(line, []byte{'\t'})
-			if len(parts) >= 3 {
-				coordinates := string(parts[1])
-				index := strings.Index(coordinates[3:], "+")
-				if index == -1 {
-					index = strings.Index(coordinates[3:], "-")
-				}
-				if index > -1 {
-					latitude := convertPos(coordinates[:index+3], 2)
-					longitude := convertPos(coordinates[index+3:], 3)
-					zone_info := &zoneInfo{
-						country:   string(parts[0]),
-						latitude:  latitude,
-						longitude: longitude,
-					}
-					zoneInfoMap[string(parts[2])] = zone_info
-				}
-			}
-		}
+	return &colorTempRunner{
+		sysService:    sysService,
+		zonePositions: zonePositions,
+		auto: core.AutoColorTemp{
+			Day:        core.DefaultDayTemperature,
+			Night:      core.DefaultNightTemperature,
+			Transition: core.DefaultTemperatureTransition,
+		},
 	}
-	return &redshiftRunner{
-		sysService:  sysService,
-		zoneInfoMap: zoneInfoMap,
+}
+
+// getGeoPosition 返回当前时区的经纬度，找不到时返回 nil
+func (r *colorTempRunner) getGeoPosition() *core.GeoPosition {
+	pos, ok := r.zonePositions[_timeZone]
+	if !ok {
+		return nil
 	}
+	return &pos
 }
 
-func (r *redshiftRunner) start() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	logger.Debugf("redshiftRunner.start")
+// getTimeZoneNow 返回当前时区的当前时间
+func getTimeZoneNow() time.Time {
+	now := time.Now()
+	loc, err := time.LoadLocation(_timeZone)
+	if err != nil {
+		return now
+	}
+	return now.In(loc)
+}
 
-	if r.state == redshiftStateRunning {
-		return
+// loadAutoColorTempConfig 读取白天和夜晚的色温以及过渡时间
+func loadAutoColorTempConfig() core.AutoColorTemp {
+	auto := core.AutoColorTemp{
+		Day:        core.DefaultDayTemperature,
+		Night:      core.DefaultNightTemperature,
+		Transition: core.DefaultTemperatureTransition,
 	}
-	r.state = redshiftStateRunning
-	var colorConf string
+
+	colorConf := defaultAutoColorTemperatureConf
 	val, err := getGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyAutoColorTemperature)
 	if err != nil {
-		colorConf = defaultAutoColorTemperatureConf
 		logger.Warning(err)
+	} else if str, ok := val.(string); ok {
+		colorConf = str
+	}
+	day, night, err := core.ParseAutoColorTemp(colorConf)
+	if err == nil && isValidColorTempValue(int32(day)) && isValidColorTempValue(int32(night)) {
+		auto.Day = day
+		auto.Night = night
 	} else {
-		colorConf = val.(string)
-	}
-	latitude := r.zoneInfoMap[_timeZone].latitude
-	longitude := r.zoneInfoMap[_timeZone].longitude
-	geographicalPosition := strconv.FormatFloat(latitude, 'f', -1, 64) + ":" + strconv.FormatFloat(longitude, 'f', -1, 64)
-	logger.Info("Get geographicalPosition:", geographicalPosition)
-	cmd := exec.Command("redshift", "-m", "dummy", "-t", colorConf, "-r")
-	if geographicalPosition != "" {
-		cmd.Args = append(cmd.Args, "-l", geographicalPosition)
-	}
-	cmd.Env = append(os.Environ(), "LC_ALL=C")
-	var errBuf bytes.Buffer
-	cmd.Stderr = &errBuf
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		logger.Warning("get stdout pipe failed:", err)
-		r.state = redshiftStateStopped
-		return
+		logger.Warning("invalid auto color temperature config:", colorConf)
 	}
-	err = cmd.Start()
+
+	val, err = getGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyColorTempTransition)
 	if err != nil {
-		logger.Warning("start redshift failed:", err)
-		r.state = redshiftStateStopped
-		return
+		logger.Warning(err)
+		return auto
 	}
+	var minutes int64
+	switch vv := val.(type) {
+	case float64:
+		minutes = int64(vv)
+	case int64:
+		minutes = vv
+	default:
+		logger.Warning("type is wrong!")
+		return auto
+	}
+	if minutes >= 0 {
+		auto.Transition = time.Duration(minutes) * time.Minute
+	}
+	return auto
+}
+
+func (r *colorTempRunner) start() {
+	auto := loadAutoColorTempConfig()
 
-	r.process = cmd.Process
+	r.mu.Lock()
+	logger.Debugf("colorTempRunner.start")
+	if r.state == colorTempRunnerStateRunning {
+		r.mu.Unlock()
+		return
+	}
+	r.state = colorTempRunnerStateRunning
+	r.auto = auto
 	r.value = 0
+	if r.timer == nil {
+		r.timer = time.AfterFunc(colorTempUpdateInterval, r.handleTimer)
+	} else {
+		r.timer.Reset(colorTempUpdateInterval)
+	}
+	r.mu.Unlock()
 
-	go func() {
-		logger.Debugf("redshift is running, pid: %v", r.process.Pid)
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			line := scanner.Bytes()
-			logger.Debugf("line: %s", line)
-			temp, ok := getTemperatureWithLine(line)
-			if ok {
-				logger.Debug("temp:", temp)
-				r.updateValue(temp)
-			}
-		}
-		err := scanner.Err()
-		if err != nil {
-			logger.Warning("scanner err:", err)
-		}
+	r.update()
+}
 
-		err = cmd.Wait()
-		if err != nil {
-			logger.Debugf("redshift cmd wait err: %v, errBuf: %s", err, errBuf.Bytes())
-		}
-		logger.Debug("redshift stopped")
+func (r *colorTempRunner) handleTimer() {
+	r.update()
 
-		r.mu.Lock()
-		r.state = redshiftStateStopped
-		r.process = nil
+	r.mu.Lock()
+	if r.state == colorTempRunnerStateRunning {
+		r.timer.Reset(colorTempUpdateInterval)
+	}
+	r.mu.Unlock()
+}
+
+// update 根据当前时间重新计算色温
+func (r *colorTempRunner) update() {
+	r.mu.Lock()
+	if r.state != colorTempRunnerStateRunning {
 		r.mu.Unlock()
-	}()
+		return
+	}
+	temp := r.auto.Temperature(getTimeZoneNow(), r.getGeoPosition())
+	r.mu.Unlock()
 
-	return
+	logger.Debug("temp:", temp)
+	r.updateValue(temp)
 }
 
 func (m *Manager) listenTimezone() {
@@ -275,37 +265,31 @@ func (m *Manager) listenTimezone() {
 				timezone, _ := v.Value().(string)
 				logger.Info("Timezone change to", timezone)
 				_timeZone = timezone
-				if m.redshiftRunner.state == redshiftStateRunning {
-					logger.Info("Redshift is Running")
-					m.redshiftRunner.stop()
-					time.AfterFunc(50*time.Millisecond, func() {
-						m.redshiftRunner.start()
-					})
-				}
+				// 时区改变后经纬度也改变了，重新计算色温
+				m.colorTempRunner.update()
+				m.triggerBrightnessSchedule()
 			}
 		}
 	}
 }
 
-func (r *redshiftRunner) stop() {
+func (r *colorTempRunner) stop() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.state == redshiftStateStopped {
+	if r.state == colorTempRunnerStateStopped {
 		return
 	}
 
-	logger.Debug("redshiftRunner.stop")
-	if r.process != nil {
-		err := r.process.Signal(os.Interrupt)
-		if err != nil {
-			logger.Warning("send signal interrupt to redshift process failed:", err)
-		}
+	logger.Debug("colorTempRunner.stop")
+	r.state = colorTempRunnerStateStopped
+	if r.timer != nil {
+		r.timer.Stop()
 	}
 	r.value = 0
 }
 
-func (r *redshiftRunner) updateValue(value int) {
+func (r *colorTempRunner) updateValue(value int) {
 	r.mu.Lock()
 	if r.value == value {
 		// no change
@@ -320,22 +304,12 @@ func (r *redshiftRunner) updateValue(value int) {
 	}
 }
 
-func (r *redshiftRunner) getValue() int {
+func (r *colorTempRunner) getValue() int {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	return r.value
 }
 
-func getTemperatureWithLine(line []byte) (int, bool) {
-	const prefix = "Temperature: "
-	if bytes.HasPrefix(line, []byte(prefix)) {
-		numStr := string(line[len(prefix):])
-		num, err := strconv.Atoi(numStr)
-		return num, err == nil
-	}
-	return 0, false
-}
-
 // dbus 上导出的方法
 func (m *Manager) setColorTempValue(value int32) error {
 	if m.ColorTemperatureMode != ColorTemperatureModeManual {
@@ -393,7 +367,7 @@ func (m *Manager) getColorTemperatureValue() int {
 	case ColorTemperatureModeManual:
 		return int(manual)
 	case ColorTemperatureModeAuto:
-		return m.redshiftRunner.getValue()
+		return m.colorTempRunner.getValue()
 	case ColorTemperatureModeCustom:
 		value := defaultTemperature
 		if m.customColorTempFlag {
@@ -486,7 +460,7 @@ func (a *geoClueAgent) GetExportedMethods() dbusutil.ExportedMethods {
 	}
 }
 
-func (r *redshiftRunner) registerGeoClueAgent() error {
+func (r *colorTempRunner) registerGeoClueAgent() error {
 	if r.geoAgentRegistered {
 		return nil
 	}
@@ -149,11 +149,11 @@ func loadOldConfig(m *Manager) (*ConfigV6, error) {
 }
 
 func (m *Manager) saveBuiltinMonitorConfig(name string) (err error) {
-	m.sysConfig.mu.Lock()
+	m.sysConfig.Mu.Lock()
 
 	m.sysConfig.Config.Cache.BuiltinMonitor = name
 	err = m.saveSysConfigNoLock("builtin monitor")
 
-	m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Unlock()
 	return
 }
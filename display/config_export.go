@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 导出和导入当前这组显示器的显示配置，配置中的显示器用 EDID 信息标识，
+// 可以把一台机器上设置好的布局导入到连接了相同型号显示器的其他机器上。
+
+// getUuidEdidHash 从 v1 版本的 uuid 中取出 EDID 的 md5
+func getUuidEdidHash(uuid string) string {
+	parts := strings.Split(uuid, "|")
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+func toLocalMonitors(monitors Monitors) []core.LocalMonitor {
+	result := make([]core.LocalMonitor, len(monitors))
+	for i, monitor := range monitors {
+		monitor.PropsMu.RLock()
+		result[i] = core.LocalMonitor{
+			UUID: monitor.uuid,
+			Name: monitor.Name,
+			Identity: core.MonitorIdentity{
+				Manufacturer: monitor.Manufacturer,
+				Model:        monitor.Model,
+				EdidHash:     getUuidEdidHash(monitor.uuid),
+				Connector:    monitor.Name,
+			},
+		}
+		monitor.PropsMu.RUnlock()
+	}
+	return result
+}
+
+func (m *Manager) exportConfig() (string, error) {
+	monitors := m.getConnectedMonitors()
+	if len(monitors) == 0 {
+		return "", errors.New("no monitor connected")
+	}
+	monitorsId := monitors.getMonitorsId()
+	m.updateConfigUuid(monitors)
+
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	m.PropsMu.RUnlock()
+
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	portableCfg, err := core.NewPortableConfig(displayMode, toLocalMonitors(monitors), screenCfg)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(portableCfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// importConfig 把导入的配置保存为当前这组显示器的屏幕配置，并应用其中的显示模式。
+func (m *Manager) importConfig(data string) error {
+	portableCfg, err := core.ParsePortableConfig([]byte(data))
+	if err != nil {
+		return err
+	}
+
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	if len(monitors) == 0 {
+		return errors.New("no monitor connected")
+	}
+	monitorsId := monitors.getMonitorsId()
+
+	screenCfg, err := portableCfg.ToScreenConfig(toLocalMonitors(monitors))
+	if err != nil {
+		return err
+	}
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	err = m.saveSysConfig("import config")
+	if err != nil {
+		return err
+	}
+
+	m.PropsMu.RLock()
+	oldMode := m.DisplayMode
+	m.PropsMu.RUnlock()
+
+	mode := oldMode
+	var options applyOptions
+	if len(monitors) > 1 && core.IsValidDisplayMode(portableCfg.DisplayMode) {
+		mode = portableCfg.DisplayMode
+		if mode != oldMode {
+			options = getSwitchModeOptions(mode, "")
+		}
+	}
+	err = m.switchModeAux(mode, oldMode, monitorsId, monitorMap, false, options)
+	if err != nil {
+		return err
+	}
+	m.markClean()
+	m.updatePropProfiles(nil)
+	return nil
+}
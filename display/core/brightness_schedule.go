@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 亮度计划，按一天中的时间点设置亮度，时间点可以是固定时间，也可以相对日出日落时间。
+//
+// 配置为 JSON 数组，例如:
+//
+//	[{"Time":"07:30","Level":0.8},{"Time":"sunset-30","Level":0.5},{"Time":"23:00","Level":0.3}]
+//
+// Time 为 "HH:MM"，或者 "sunrise"，"sunset"，后面可以带以分钟为单位的偏移。
+
+type BrightnessSchedulePoint struct {
+	Time  string
+	Level float64
+}
+
+type BrightnessSchedule []BrightnessSchedulePoint
+
+// ParseBrightnessSchedule 解析亮度计划，空字符串表示没有亮度计划
+func ParseBrightnessSchedule(data string) (BrightnessSchedule, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, nil
+	}
+	var schedule BrightnessSchedule
+	err := json.Unmarshal([]byte(data), &schedule)
+	if err != nil {
+		return nil, err
+	}
+	for _, point := range schedule {
+		err = ValidateScheduleTime(point.Time)
+		if err != nil {
+			return nil, err
+		}
+		if !IsValidBrightness(point.Level) {
+			return nil, fmt.Errorf("invalid brightness level %v at %q", point.Level, point.Time)
+		}
+	}
+	return schedule, nil
+}
+
+func (s BrightnessSchedule) times() []string {
+	times := make([]string, len(s))
+	for idx, point := range s {
+		times[idx] = point.Time
+	}
+	return times
+}
+
+// Target 返回 now 时亮度计划的目标亮度，以及开始调整的时间，即最近一个已经过去的时间点。
+func (s BrightnessSchedule) Target(now time.Time, pos *GeoPosition) (level float64, since time.Time, err error) {
+	index, since, _, err := FindScheduleTime(s.times(), now, pos)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return s[index].Level, since, nil
+}
+
+// Next 返回 now 之后的下一个时间点
+func (s BrightnessSchedule) Next(now time.Time, pos *GeoPosition) (time.Time, error) {
+	_, _, next, err := FindScheduleTime(s.times(), now, pos)
+	return next, err
+}
+
+// RampBrightness 从亮度 current 向 target 平滑调整，rampEnd 时达到 target，返回 now 时应设置的亮度。
+// 每次调整按剩余时间的比例前进 step，所以从任何亮度开始都能平滑地到达目标亮度。
+func RampBrightness(current, target float64, now, rampEnd time.Time, step time.Duration) float64 {
+	remaining := rampEnd.Sub(now)
+	if remaining <= step {
+		return target
+	}
+	return current + (target-current)*float64(step)/float64(remaining)
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBrightnessSchedule(t *testing.T) {
+	schedule, err := ParseBrightnessSchedule("")
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	schedule, err = ParseBrightnessSchedule(`[{"Time":"07:30","Level":0.8},{"Time":"sunset-30","Level":0.5},{"Time":"sunrise","Level":0.6}]`)
+	require.NoError(t, err)
+	assert.Len(t, schedule, 3)
+
+	_, err = ParseBrightnessSchedule(`[{"Time":"25:00","Level":0.8}]`)
+	assert.Error(t, err)
+	_, err = ParseBrightnessSchedule(`[{"Time":"sunsetx","Level":0.8}]`)
+	assert.Error(t, err)
+	_, err = ParseBrightnessSchedule(`[{"Time":"07:00","Level":1.5}]`)
+	assert.Error(t, err)
+}
+
+func TestBrightnessSchedule_Target(t *testing.T) {
+	schedule := BrightnessSchedule{
+		{Time: "07:00", Level: 0.8},
+		{Time: "sunset", Level: 0.5},
+		{Time: "23:00", Level: 0.3},
+	}
+	loc := time.UTC
+	at := func(hour, min int) time.Time {
+		return time.Date(2023, time.March, 1, hour, min, 0, 0, loc)
+	}
+
+	// 没有位置时日落为 18:00
+	level, since, err := schedule.Target(at(12, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, level)
+	assert.Equal(t, at(7, 0), since)
+
+	level, since, err = schedule.Target(at(18, 30), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, level)
+	assert.Equal(t, at(18, 0), since)
+
+	// 凌晨使用前一天最后的时间点
+	level, since, err = schedule.Target(at(2, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.3, level)
+	assert.Equal(t, at(23, 0).AddDate(0, 0, -1), since)
+
+	next, err := schedule.Next(at(23, 30), nil)
+	require.NoError(t, err)
+	assert.Equal(t, at(7, 0).AddDate(0, 0, 1), next)
+
+	_, _, err = BrightnessSchedule{}.Target(at(12, 0), nil)
+	assert.Error(t, err)
+}
+
+func TestRampBrightness(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	step := 30 * time.Second
+	assert.Equal(t, 0.5, RampBrightness(1, 0.5, now, now.Add(step), step))
+	assert.InDelta(t, 0.75, RampBrightness(1, 0.5, now, now.Add(2*step), step), 1e-9)
+	assert.Equal(t, 0.5, RampBrightness(1, 0.5, now, now.Add(-time.Minute), step))
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 自动色温，白天使用 Day 色温，夜晚使用 Night 色温，在日出日落前后 Transition 时间内平滑过渡。
+
+const (
+	DefaultDayTemperature        = 6500
+	DefaultNightTemperature      = 3500
+	DefaultTemperatureTransition = time.Hour
+)
+
+type AutoColorTemp struct {
+	Day        int
+	Night      int
+	Transition time.Duration
+}
+
+// ParseAutoColorTemp 解析 "白天色温:夜晚色温" 格式的配置，比如 "6500:3500"
+func ParseAutoColorTemp(conf string) (day, night int, err error) {
+	parts := strings.Split(conf, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid auto color temperature %q", conf)
+	}
+	day, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid auto color temperature %q", conf)
+	}
+	night, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid auto color temperature %q", conf)
+	}
+	return day, night, nil
+}
+
+func lerpTemperature(from, to int, progress float64) int {
+	return int(math.Round(float64(from) + float64(to-from)*progress))
+}
+
+// Temperature 返回 now 时的色温，pos 为 nil 时使用默认的日出日落时间
+func (c *AutoColorTemp) Temperature(now time.Time, pos *GeoPosition) int {
+	sunrise, sunset, ok := getSunTimesOfDay(now, pos)
+	if !ok && pos != nil {
+		// 极昼或者极夜
+		if SolarElevation(getMidnight(now).Add(12*time.Hour), *pos) > 0 {
+			return c.Day
+		}
+		return c.Night
+	}
+
+	half := c.Transition / 2
+	progress := func(start time.Time) float64 {
+		return float64(now.Sub(start)) / float64(c.Transition)
+	}
+	switch {
+	case now.Before(sunrise.Add(-half)):
+		return c.Night
+	case now.Before(sunrise.Add(half)):
+		return lerpTemperature(c.Night, c.Day, progress(sunrise.Add(-half)))
+	case now.Before(sunset.Add(-half)):
+		return c.Day
+	case now.Before(sunset.Add(half)):
+		return lerpTemperature(c.Day, c.Night, progress(sunset.Add(-half)))
+	default:
+		return c.Night
+	}
+}
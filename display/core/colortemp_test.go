@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAutoColorTemp(t *testing.T) {
+	day, night, err := ParseAutoColorTemp("6500:3500")
+	require.NoError(t, err)
+	assert.Equal(t, 6500, day)
+	assert.Equal(t, 3500, night)
+
+	_, _, err = ParseAutoColorTemp("6500")
+	assert.Error(t, err)
+	_, _, err = ParseAutoColorTemp("a:b")
+	assert.Error(t, err)
+}
+
+func TestAutoColorTemp_Temperature(t *testing.T) {
+	c := &AutoColorTemp{Day: 6500, Night: 3500, Transition: time.Hour}
+	at := func(hour, min int) time.Time {
+		return time.Date(2023, time.March, 1, hour, min, 0, 0, time.UTC)
+	}
+	// 没有位置时日出 6:00，日落 18:00
+	assert.Equal(t, 3500, c.Temperature(at(3, 0), nil))
+	assert.Equal(t, 5000, c.Temperature(at(6, 0), nil))
+	assert.Equal(t, 6500, c.Temperature(at(12, 0), nil))
+	assert.Equal(t, 5750, c.Temperature(at(17, 45), nil))
+	assert.Equal(t, 3500, c.Temperature(at(18, 30), nil))
+	assert.Equal(t, 3500, c.Temperature(at(23, 0), nil))
+
+	// 过渡时间为 0 时直接切换
+	c.Transition = 0
+	assert.Equal(t, 6500, c.Temperature(at(6, 0), nil))
+	assert.Equal(t, 3500, c.Temperature(at(5, 59), nil))
+
+	// 极昼
+	summer := time.Date(2023, time.June, 21, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 6500, c.Temperature(summer, &GeoPosition{Latitude: 78, Longitude: 15}))
+	// 极夜
+	winter := time.Date(2023, time.December, 21, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3500, c.Temperature(winter, &GeoPosition{Latitude: 78, Longitude: 15}))
+}
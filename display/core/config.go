@@ -0,0 +1,487 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SysConfigVersion = "1.0"
+
+type SysRootConfig struct {
+	Mu       sync.Mutex `json:"-"`
+	Version  string
+	Config   SysConfig
+	UpdateAt string
+}
+
+func (c *SysRootConfig) CopyFrom(newSysConfig *SysRootConfig) {
+	c.Mu.Lock()
+
+	c.Version = newSysConfig.Version
+	c.Config = newSysConfig.Config
+	c.UpdateAt = newSysConfig.UpdateAt
+
+	c.Mu.Unlock()
+}
+
+func (c *SysRootConfig) Fix() {
+	cfg := &c.Config
+	// 默认显示模式为复制模式
+	if cfg.DisplayMode == DisplayModeUnknown || cfg.DisplayMode == DisplayModeCustom {
+		cfg.DisplayMode = DisplayModeMirror
+	}
+	for _, screenConfig := range cfg.Screens {
+		screenConfig.Fix()
+	}
+}
+
+// SysConfig v1
+type SysConfig struct {
+	DisplayMode  byte
+	Screens      map[string]*SysScreenConfig
+	ScaleFactors map[string]float64 // 缩放比例
+	FillModes    map[string]string  // key 是特殊的 fillMode Key
+	Cache        SysCache
+}
+
+type SysCache struct {
+	BuiltinMonitor string
+	ConnectTime    map[string]time.Time
+}
+
+// UuidUpdater 根据配置中的 uuid 查找显示器当前的 uuid，找不到显示器时 ok 为 false。
+type UuidUpdater func(uuid string) (newUuid string, ok bool)
+
+type SysScreenConfig struct {
+	Mirror      *SysMonitorModeConfig            `json:",omitempty"`
+	Extend      *SysMonitorModeConfig            `json:",omitempty"`
+	Single      *SysMonitorModeConfig            `json:",omitempty"`
+	OnlyOneMap  map[string]*SysMonitorModeConfig `json:",omitempty"`
+	OnlyOneUuid string                           `json:",omitempty"`
+	// Profiles 用户命名保存的布局，键是布局名称
+	Profiles       map[string]*SysProfileConfig `json:",omitempty"`
+	CurrentProfile string                       `json:",omitempty"`
+}
+
+// SysProfileConfig 用户命名保存的布局，记录保存时的显示模式和各显示器配置
+type SysProfileConfig struct {
+	DisplayMode byte
+	Monitors    SysMonitorConfigs
+}
+
+func (c *SysScreenConfig) UpdateUuid(update UuidUpdater) (result *SysScreenConfig, overallChanged bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	result = &SysScreenConfig{}
+
+	if c.Mirror != nil {
+		changed := false
+		result.Mirror, changed = c.Mirror.UpdateUuid(update)
+		overallChanged = overallChanged || changed
+	}
+	if c.Extend != nil {
+		changed := false
+		result.Extend, changed = c.Extend.UpdateUuid(update)
+		overallChanged = overallChanged || changed
+	}
+	if c.Single != nil {
+		changed := false
+		result.Single, changed = c.Single.UpdateUuid(update)
+		overallChanged = overallChanged || changed
+	}
+	if len(c.OnlyOneMap) > 0 {
+		result.OnlyOneMap = make(map[string]*SysMonitorModeConfig, len(c.OnlyOneMap))
+		for uuid, config := range c.OnlyOneMap {
+			changed := false
+			uuid, changed = updateUuid(uuid, update)
+			overallChanged = overallChanged || changed
+
+			result.OnlyOneMap[uuid], changed = config.UpdateUuid(update)
+			overallChanged = overallChanged || changed
+		}
+	}
+
+	if len(c.Profiles) > 0 {
+		result.Profiles = make(map[string]*SysProfileConfig, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			changed := false
+			result.Profiles[name], changed = profile.UpdateUuid(update)
+			overallChanged = overallChanged || changed
+		}
+	}
+	result.CurrentProfile = c.CurrentProfile
+
+	changed := false
+	result.OnlyOneUuid, changed = updateUuid(c.OnlyOneUuid, update)
+	overallChanged = overallChanged || changed
+
+	return result, overallChanged
+}
+
+func updateUuid(uuid string, update UuidUpdater) (newUuid string, changed bool) {
+	newUuid, ok := update(uuid)
+	if !ok || newUuid == uuid {
+		// 放弃修改
+		return uuid, false
+	}
+	return newUuid, true
+}
+
+// UpdateUuid 更新配置中的所有 uuid 的版本
+func (c *SysMonitorModeConfig) UpdateUuid(update UuidUpdater) (*SysMonitorModeConfig, bool) {
+	if c == nil {
+		return nil, false
+	}
+	monitorConfigs := c.Monitors
+	hasChanged := false
+	outMonitorCfgs := make(SysMonitorConfigs, 0, len(monitorConfigs))
+	for _, monitorCfg := range monitorConfigs {
+		uuid, ok := update(monitorCfg.UUID)
+		if !ok {
+			continue
+		}
+		if uuid != monitorCfg.UUID {
+			outMonitorCfg := *monitorCfg
+			// 更新 uuid
+			outMonitorCfg.UUID = uuid
+			hasChanged = true
+			outMonitorCfgs = append(outMonitorCfgs, &outMonitorCfg)
+		} else {
+			outMonitorCfgs = append(outMonitorCfgs, monitorCfg)
+		}
+	}
+	result := SysMonitorModeConfig{}
+	if hasChanged && len(outMonitorCfgs) == len(monitorConfigs) {
+		result.Monitors = outMonitorCfgs
+	} else {
+		// 放弃更新 uuid
+		result.Monitors = monitorConfigs
+	}
+	return &result, hasChanged
+}
+
+func (c *SysScreenConfig) Clone() *SysScreenConfig {
+	if c == nil {
+		return nil
+	}
+	result := &SysScreenConfig{
+		Mirror:         c.Mirror.Clone(),
+		Extend:         c.Extend.Clone(),
+		Single:         c.Single.Clone(),
+		OnlyOneUuid:    c.OnlyOneUuid,
+		CurrentProfile: c.CurrentProfile,
+	}
+	if len(c.OnlyOneMap) > 0 {
+		result.OnlyOneMap = make(map[string]*SysMonitorModeConfig, len(c.OnlyOneMap))
+		for uuid, config := range c.OnlyOneMap {
+			result.OnlyOneMap[uuid] = config.Clone()
+		}
+	}
+	if len(c.Profiles) > 0 {
+		result.Profiles = make(map[string]*SysProfileConfig, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			result.Profiles[name] = profile.Clone()
+		}
+	}
+	return result
+}
+
+func (c *SysScreenConfig) Fix() {
+	if c.Mirror != nil {
+		c.Mirror.Fix()
+	}
+	if c.Extend != nil {
+		c.Extend.Fix()
+	}
+	if c.Single != nil {
+		c.Single.Fix()
+	}
+	for uuid, config := range c.OnlyOneMap {
+		if config != nil {
+			config.Fix()
+		} else {
+			delete(c.OnlyOneMap, uuid)
+		}
+	}
+	for name, profile := range c.Profiles {
+		if profile != nil {
+			profile.Fix()
+		} else {
+			delete(c.Profiles, name)
+		}
+	}
+	if c.Profiles[c.CurrentProfile] == nil {
+		c.CurrentProfile = ""
+	}
+}
+
+func (c *SysProfileConfig) Fix() {
+	if !IsValidDisplayMode(c.DisplayMode) {
+		c.DisplayMode = DisplayModeExtend
+	}
+	for _, monitor := range c.Monitors {
+		monitor.Fix()
+	}
+}
+
+func (c *SysProfileConfig) Clone() *SysProfileConfig {
+	if c == nil {
+		return nil
+	}
+	return &SysProfileConfig{
+		DisplayMode: c.DisplayMode,
+		Monitors:    c.Monitors.Clone(),
+	}
+}
+
+// UpdateUuid 更新布局中显示器的 uuid 版本
+func (c *SysProfileConfig) UpdateUuid(update UuidUpdater) (*SysProfileConfig, bool) {
+	if c == nil {
+		return nil, false
+	}
+	modeCfg, changed := (&SysMonitorModeConfig{Monitors: c.Monitors}).UpdateUuid(update)
+	return &SysProfileConfig{
+		DisplayMode: c.DisplayMode,
+		Monitors:    modeCfg.Monitors,
+	}, changed
+}
+
+type SysMonitorModeConfig struct {
+	Monitors SysMonitorConfigs
+}
+
+func (c *SysMonitorModeConfig) Fix() {
+	for _, monitor := range c.Monitors {
+		monitor.Fix()
+	}
+}
+
+func (c *SysMonitorModeConfig) Clone() *SysMonitorModeConfig {
+	if c == nil {
+		return nil
+	}
+	return &SysMonitorModeConfig{
+		Monitors: c.Monitors.Clone(),
+	}
+}
+
+type SysMonitorConfig struct {
+	UUID        string
+	Name        string
+	Enabled     bool
+	X           int16
+	Y           int16
+	Width       uint16
+	Height      uint16
+	Rotation    uint16
+	Reflect     uint16
+	RefreshRate float64
+	Brightness  float64
+	Primary     bool
+	// 缩放比例，为 0 表示由后端决定，目前只有 Wayland 使用
+	Scale float64 `json:",omitempty"`
+}
+
+func (c *SysMonitorConfig) Fix() {
+	// c.Enable 为 false，但 c.Primary 为 true 的，错误情况
+	if !c.Enabled && c.Primary {
+		c.Primary = false
+	}
+	if !IsValidBrightness(c.Brightness) {
+		c.Brightness = 1
+	}
+}
+
+type SysMonitorConfigs []*SysMonitorConfig
+
+func (cfgs SysMonitorConfigs) Clone() SysMonitorConfigs {
+	if cfgs == nil {
+		return nil
+	}
+	result := make(SysMonitorConfigs, len(cfgs))
+	for i, config := range cfgs {
+		configCp := *config
+		result[i] = &configCp
+	}
+	return result
+}
+
+func (s *SysScreenConfig) GetSingleMonitorConfigs() SysMonitorConfigs {
+	if s == nil || s.Single == nil {
+		return nil
+	}
+	return s.Single.Monitors
+}
+
+func (s *SysScreenConfig) GetMonitorConfigs(mode uint8, uuid string) SysMonitorConfigs {
+	if s == nil {
+		return nil
+	}
+	switch mode {
+	case DisplayModeMirror:
+		if s.Mirror == nil {
+			return nil
+		}
+		return s.Mirror.Monitors
+
+	case DisplayModeExtend:
+		if s.Extend == nil {
+			return nil
+		}
+		return s.Extend.Monitors
+
+	case DisplayModeOnlyOne:
+		if uuid == "" {
+			return nil
+		}
+		config := s.OnlyOneMap[uuid]
+		if config != nil {
+			return config.Monitors
+		}
+	}
+
+	return nil
+}
+
+func (s *SysScreenConfig) SetSingleMonitorConfigs(configs SysMonitorConfigs) {
+	if s.Single == nil {
+		s.Single = &SysMonitorModeConfig{}
+	}
+	s.Single.Monitors = configs
+}
+
+func (s *SysScreenConfig) SetMonitorConfigs(mode uint8, uuid string, configs SysMonitorConfigs) {
+	switch mode {
+	case DisplayModeMirror:
+		if s.Mirror == nil {
+			s.Mirror = &SysMonitorModeConfig{}
+		}
+		s.Mirror.Monitors = configs
+
+	case DisplayModeExtend:
+		if s.Extend == nil {
+			s.Extend = &SysMonitorModeConfig{}
+		}
+		s.Extend.Monitors = configs
+
+	case DisplayModeOnlyOne:
+		s.SetMonitorConfigsOnlyOne(uuid, configs)
+	}
+}
+
+func (s *SysScreenConfig) SetMonitorConfigsOnlyOne(uuid string, configs SysMonitorConfigs) {
+	if uuid == "" {
+		return
+	}
+	if s.OnlyOneMap == nil {
+		s.OnlyOneMap = make(map[string]*SysMonitorModeConfig)
+	}
+
+	if s.OnlyOneMap[uuid] == nil {
+		s.OnlyOneMap[uuid] = &SysMonitorModeConfig{}
+	}
+
+	if len(configs) > 1 {
+		// 去除非使能的 monitor
+		var tmpCfg *SysMonitorConfig
+		for _, config := range configs {
+			if config.Enabled {
+				tmpCfg = config
+				break
+			}
+		}
+		if tmpCfg != nil {
+			configs = SysMonitorConfigs{tmpCfg}
+		}
+	}
+
+	s.OnlyOneMap[uuid].Monitors = configs
+}
+
+func (cfgs SysMonitorConfigs) GetByUuid(uuid string) *SysMonitorConfig {
+	for _, mc := range cfgs {
+		if uuid == mc.UUID {
+			return mc
+		}
+	}
+	return nil
+}
+
+func (cfgs SysMonitorConfigs) SetPrimary(uuid string) {
+	for _, mc := range cfgs {
+		if mc.UUID == uuid {
+			mc.Primary = true
+		} else {
+			mc.Primary = false
+		}
+	}
+}
+
+// OnlyBrNotEq cfgs 和 otherCfgs 之间是否仅亮度不同
+// 前置条件：cfgs 和 otherCfgs 不相同
+func (cfgs SysMonitorConfigs) OnlyBrNotEq(otherCfgs SysMonitorConfigs) bool {
+	if len(cfgs) != len(otherCfgs) {
+		return false
+	}
+	// 除了亮度设置为 0， 其他字段都复制
+	partCpCfgs := func(cfgs SysMonitorConfigs) SysMonitorConfigs {
+		copyCfgs := make(SysMonitorConfigs, len(cfgs))
+		for i, cfg := range cfgs {
+			cpCfg := &SysMonitorConfig{}
+			*cpCfg = *cfg
+			cpCfg.Brightness = 0
+			copyCfgs[i] = cpCfg
+		}
+		return copyCfgs
+	}
+
+	c1 := partCpCfgs(cfgs)
+	c2 := partCpCfgs(otherCfgs)
+	// 把亮度都安全的设置为0, 如果 c1 和 c2 是相同的，则可以说明是仅亮度不同。
+	if reflect.DeepEqual(c1, c2) {
+		return true
+	}
+	return false
+}
+
+// GetRealDisplayMode 根据已启用的显示器的位置判断实际的显示模式
+func (cfgs SysMonitorConfigs) GetRealDisplayMode() uint8 {
+	mode := DisplayModeUnknown
+	positions := make(map[[2]int16]struct{})
+	for _, cfg := range cfgs {
+		if !cfg.Enabled {
+			continue
+		}
+
+		pos := [2]int16{cfg.X, cfg.Y}
+		// 左上角座标相同，是复制
+		if _, ok := positions[pos]; ok {
+			mode = DisplayModeMirror
+		}
+		positions[pos] = struct{}{}
+	}
+
+	if mode == DisplayModeUnknown && len(positions) != 0 {
+		if len(positions) == 1 {
+			mode = DisplayModeOnlyOne
+		} else {
+			mode = DisplayModeExtend
+		}
+	}
+	return mode
+}
+
+func (cfgs SysMonitorConfigs) Sort() {
+	sort.Slice(cfgs, func(i, j int) bool {
+		return strings.Compare(cfgs[i].UUID, cfgs[j].UUID) < 0
+	})
+}
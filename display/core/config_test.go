@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysScreenConfig_UpdateUuid(t *testing.T) {
+	screenCfg := &SysScreenConfig{
+		Extend: &SysMonitorModeConfig{
+			Monitors: SysMonitorConfigs{
+				{UUID: "old-a", Enabled: true},
+				{UUID: "b|v1", Enabled: true},
+			},
+		},
+		OnlyOneUuid: "old-a",
+	}
+	update := func(uuid string) (string, bool) {
+		if uuid == "old-a" {
+			return "a|v1", true
+		}
+		return uuid, uuid == "b|v1"
+	}
+
+	result, changed := screenCfg.UpdateUuid(update)
+	assert.True(t, changed)
+	assert.Equal(t, "a|v1", result.OnlyOneUuid)
+	assert.NotNil(t, result.Extend.Monitors.GetByUuid("a|v1"))
+	assert.NotNil(t, result.Extend.Monitors.GetByUuid("b|v1"))
+	// 原配置不变
+	assert.NotNil(t, screenCfg.Extend.Monitors.GetByUuid("old-a"))
+
+	// 有显示器找不到时放弃更新
+	result, changed = screenCfg.UpdateUuid(func(uuid string) (string, bool) {
+		if uuid == "old-a" {
+			return "a|v1", true
+		}
+		return uuid, false
+	})
+	assert.True(t, changed)
+	assert.Equal(t, screenCfg.Extend.Monitors, result.Extend.Monitors)
+}
+
+func TestSysMonitorConfigs_GetRealDisplayMode(t *testing.T) {
+	assert.Equal(t, DisplayModeUnknown, SysMonitorConfigs{}.GetRealDisplayMode())
+	assert.Equal(t, DisplayModeOnlyOne, SysMonitorConfigs{
+		{Enabled: true},
+		{Enabled: false, X: 1920},
+	}.GetRealDisplayMode())
+	assert.Equal(t, DisplayModeMirror, SysMonitorConfigs{
+		{Enabled: true},
+		{Enabled: true},
+	}.GetRealDisplayMode())
+	assert.Equal(t, DisplayModeExtend, SysMonitorConfigs{
+		{Enabled: true},
+		{Enabled: true, X: 1920},
+	}.GetRealDisplayMode())
+}
+
+func TestSysRootConfig_Fix(t *testing.T) {
+	rootCfg := &SysRootConfig{
+		Config: SysConfig{
+			DisplayMode: DisplayModeCustom,
+			Screens: map[string]*SysScreenConfig{
+				"a": {
+					Single: &SysMonitorModeConfig{
+						Monitors: SysMonitorConfigs{{UUID: "a", Primary: true}},
+					},
+					Profiles: map[string]*SysProfileConfig{
+						"Desk": {DisplayMode: DisplayModeCustom},
+					},
+					CurrentProfile: "NotExist",
+				},
+			},
+		},
+	}
+	rootCfg.Fix()
+	assert.Equal(t, DisplayModeMirror, rootCfg.Config.DisplayMode)
+	screenCfg := rootCfg.Config.Screens["a"]
+	monitorCfg := screenCfg.GetSingleMonitorConfigs()[0]
+	assert.False(t, monitorCfg.Primary)
+	assert.Equal(t, float64(1), monitorCfg.Brightness)
+	assert.Equal(t, DisplayModeExtend, screenCfg.GetProfile("Desk").DisplayMode)
+	assert.Empty(t, screenCfg.CurrentProfile)
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package core 是 X11 和 Wayland 两个显示服务共用的部分，包括显示模式、配置格式、
+// 亮度和分辨率等与后端无关的逻辑，不依赖 X11 和 KWin。
+package core
+
+const (
+	DisplayModeCustom uint8 = iota
+	DisplayModeMirror
+	DisplayModeExtend
+	DisplayModeOnlyOne
+	DisplayModeUnknown
+)
+
+// IsValidDisplayMode 是否是可以保存在配置中的显示模式
+func IsValidDisplayMode(mode uint8) bool {
+	return mode == DisplayModeMirror || mode == DisplayModeExtend || mode == DisplayModeOnlyOne
+}
+
+func IsValidBrightness(value float64) bool {
+	// 不含 0
+	return value > 0 && value <= 1
+}
+
+// ClampBrightness 把亮度限制在 [min, 1] 之间
+func ClampBrightness(value, min float64) float64 {
+	if value > 1 {
+		return 1
+	}
+	if value < min {
+		return min
+	}
+	return value
+}
+
+type Size struct {
+	Width  uint16
+	Height uint16
+}
+
+// GetMaxAreaSize 返回面积最大的尺寸
+func GetMaxAreaSize(sizes []Size) Size {
+	if len(sizes) == 0 {
+		return Size{}
+	}
+	maxS := sizes[0]
+	for _, s := range sizes[1:] {
+		if (int(maxS.Width) * int(maxS.Height)) < (int(s.Width) * int(s.Height)) {
+			maxS = s
+		}
+	}
+	return maxS
+}
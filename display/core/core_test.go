@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetMaxAreaSize(t *testing.T) {
+	size := GetMaxAreaSize([]Size{
+		{1024, 768},
+		{640, 480},
+		{1280, 720},
+		{800, 600},
+	})
+	assert.Equal(t, Size{1280, 720}, size)
+	size = GetMaxAreaSize(nil)
+	assert.Equal(t, Size{}, size)
+	size = GetMaxAreaSize([]Size{
+		{1024, 768},
+	})
+	assert.Equal(t, Size{1024, 768}, size)
+}
+
+func Test_IsValidBrightness(t *testing.T) {
+	assert.True(t, IsValidBrightness(1))
+	assert.True(t, IsValidBrightness(0.5))
+	assert.False(t, IsValidBrightness(0))
+	assert.False(t, IsValidBrightness(1.1))
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// 按 VESA CVT 1.1 标准计算显示模式的时序，结果和 cvt 工具以及 X 的 xf86CVTMode 一致，
+// 用于 EDID 缺失或者不完整的显示器添加自定义模式。不支持隔行扫描和边框。
+
+const (
+	cvtHGranularity = 8
+	cvtMinVPorch    = 3
+	cvtMinVBPorch   = 6
+	// 单位是 kHz
+	cvtClockStep = 250
+
+	// 普通消隐
+	cvtMinVSyncBP       = 550.0
+	cvtHSyncPercentage  = 8
+	cvtCPrime           = (40-20)*128/256 + 20
+	cvtMPrime           = 600 * 128 / 256
+	cvtMinHBlankPercent = 20
+
+	// 减少消隐
+	cvtRBMinVBlank = 460.0
+	cvtRBHSync     = 32
+	cvtRBHBlank    = 160
+	cvtRBVFPorch   = 3
+)
+
+// CVTMode 显示模式的时序
+type CVTMode struct {
+	Name string
+	// 单位是 Hz
+	DotClock   uint32
+	HDisplay   uint16
+	HSyncStart uint16
+	HSyncEnd   uint16
+	HTotal     uint16
+	VDisplay   uint16
+	VSyncStart uint16
+	VSyncEnd   uint16
+	VTotal     uint16
+	// 减少消隐时水平同步是正极性，垂直同步是负极性，普通消隐相反
+	ReducedBlanking bool
+}
+
+// Rate 实际的刷新率
+func (m *CVTMode) Rate() float64 {
+	return float64(m.DotClock) / (float64(m.HTotal) * float64(m.VTotal))
+}
+
+// getCVTVSync 根据宽高比确定垂直同步的行数
+func getCVTVSync(width, height int) int {
+	switch {
+	case height%3 == 0 && height*4/3 == width:
+		return 4
+	case height%9 == 0 && height*16/9 == width:
+		return 5
+	case height%10 == 0 && height*16/10 == width:
+		return 6
+	case height%4 == 0 && height*5/4 == width,
+		height%9 == 0 && height*15/9 == width:
+		return 7
+	}
+	return 10
+}
+
+// CalcCVTMode 计算宽 width、高 height、刷新率 rate 的 CVT 模式，rate 为 0 时使用 60Hz，
+// reducedBlanking 为 true 时使用减少消隐，用于不支持 CRT 时序的数字接口以降低像素时钟。
+func CalcCVTMode(width, height uint16, rate float64, reducedBlanking bool) (*CVTMode, error) {
+	if rate == 0 {
+		rate = 60
+	}
+	if math.IsNaN(rate) || rate < 0 || rate > 500 {
+		return nil, fmt.Errorf("invalid rate %v", rate)
+	}
+	hDisplay := int(width) - int(width)%cvtHGranularity
+	vDisplay := int(height)
+	if hDisplay == 0 || vDisplay == 0 {
+		return nil, errors.New("invalid size")
+	}
+	vSync := getCVTVSync(hDisplay, vDisplay)
+
+	var hPeriod float64
+	var hTotal, hSyncStart, hSyncEnd, vTotal int
+	if !reducedBlanking {
+		// 估算行周期，单位是微秒
+		hPeriod = (1000000.0/rate - cvtMinVSyncBP) / float64(vDisplay+cvtMinVPorch)
+		vSyncAndBackPorch := int(cvtMinVSyncBP/hPeriod) + 1
+		if vSyncAndBackPorch < vSync+cvtMinVPorch {
+			vSyncAndBackPorch = vSync + cvtMinVPorch
+		}
+		vTotal = vDisplay + vSyncAndBackPorch + cvtMinVPorch
+
+		hBlankPercentage := cvtCPrime - cvtMPrime*hPeriod/1000.0
+		if hBlankPercentage < cvtMinHBlankPercent {
+			hBlankPercentage = cvtMinHBlankPercent
+		}
+		hBlank := int(float64(hDisplay) * hBlankPercentage / (100.0 - hBlankPercentage))
+		hBlank -= hBlank % (2 * cvtHGranularity)
+
+		hTotal = hDisplay + hBlank
+		hSyncEnd = hDisplay + hBlank/2
+		hSyncStart = hSyncEnd - hTotal*cvtHSyncPercentage/100
+		hSyncStart += cvtHGranularity - hSyncStart%cvtHGranularity
+	} else {
+		hPeriod = (1000000.0/rate - cvtRBMinVBlank) / float64(vDisplay)
+		vbiLines := int(cvtRBMinVBlank/hPeriod + 1)
+		if vbiLines < cvtRBVFPorch+vSync+cvtMinVBPorch {
+			vbiLines = cvtRBVFPorch + vSync + cvtMinVBPorch
+		}
+		vTotal = vDisplay + vbiLines
+
+		hTotal = hDisplay + cvtRBHBlank
+		hSyncEnd = hDisplay + cvtRBHBlank/2
+		hSyncStart = hSyncEnd - cvtRBHSync
+	}
+	if hPeriod <= 0 {
+		return nil, fmt.Errorf("invalid rate %v", rate)
+	}
+	if hTotal > 0xffff || vTotal > 0xffff {
+		return nil, errors.New("size is too large")
+	}
+
+	// 像素时钟，单位是 kHz，按 cvtClockStep 取整
+	clock := int(float64(hTotal) * 1000.0 / hPeriod)
+	clock -= clock % cvtClockStep
+	if float64(clock)*1000 > math.MaxUint32 {
+		return nil, fmt.Errorf("pixel clock %d kHz is too high", clock)
+	}
+
+	vSyncStart := vDisplay + cvtMinVPorch
+	if reducedBlanking {
+		vSyncStart = vDisplay + cvtRBVFPorch
+	}
+	mode := &CVTMode{
+		DotClock:        uint32(clock) * 1000,
+		HDisplay:        uint16(hDisplay),
+		HSyncStart:      uint16(hSyncStart),
+		HSyncEnd:        uint16(hSyncEnd),
+		HTotal:          uint16(hTotal),
+		VDisplay:        uint16(vDisplay),
+		VSyncStart:      uint16(vSyncStart),
+		VSyncEnd:        uint16(vSyncStart + vSync),
+		VTotal:          uint16(vTotal),
+		ReducedBlanking: reducedBlanking,
+	}
+	mode.Name = fmt.Sprintf("%dx%d_%.2f", hDisplay, vDisplay, rate)
+	if reducedBlanking {
+		mode.Name += "R"
+	}
+	return mode, nil
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcCVTMode(t *testing.T) {
+	// 期望值来自 cvt 工具的输出
+	mode, err := CalcCVTMode(1920, 1080, 60, false)
+	require.NoError(t, err)
+	assert.Equal(t, &CVTMode{
+		Name:       "1920x1080_60.00",
+		DotClock:   173000000,
+		HDisplay:   1920,
+		HSyncStart: 2048,
+		HSyncEnd:   2248,
+		HTotal:     2576,
+		VDisplay:   1080,
+		VSyncStart: 1083,
+		VSyncEnd:   1088,
+		VTotal:     1120,
+	}, mode)
+	assert.InDelta(t, 59.96, mode.Rate(), 0.01)
+
+	mode, err = CalcCVTMode(1920, 1080, 60, true)
+	require.NoError(t, err)
+	assert.Equal(t, &CVTMode{
+		Name:            "1920x1080_60.00R",
+		DotClock:        138500000,
+		HDisplay:        1920,
+		HSyncStart:      1968,
+		HSyncEnd:        2000,
+		HTotal:          2080,
+		VDisplay:        1080,
+		VSyncStart:      1083,
+		VSyncEnd:        1088,
+		VTotal:          1111,
+		ReducedBlanking: true,
+	}, mode)
+
+	mode, err = CalcCVTMode(1024, 768, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "1024x768_60.00", mode.Name)
+	assert.Equal(t, uint32(63500000), mode.DotClock)
+	assert.Equal(t, []uint16{1024, 1072, 1176, 1328, 768, 771, 775, 798},
+		[]uint16{mode.HDisplay, mode.HSyncStart, mode.HSyncEnd, mode.HTotal,
+			mode.VDisplay, mode.VSyncStart, mode.VSyncEnd, mode.VTotal})
+
+	// 宽度按 8 对齐
+	mode, err = CalcCVTMode(1366, 768, 60, false)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1360), mode.HDisplay)
+
+	_, err = CalcCVTMode(0, 768, 60, false)
+	assert.Error(t, err)
+	_, err = CalcCVTMode(1920, 1080, -1, false)
+	assert.Error(t, err)
+	_, err = CalcCVTMode(1920, 1080, math.NaN(), false)
+	assert.Error(t, err)
+	// 像素时钟超过 uint32 的范围
+	_, err = CalcCVTMode(8192, 8192, 240, false)
+	assert.Error(t, err)
+}
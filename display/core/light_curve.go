@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 自适应亮度，根据环境光传感器的照度（lux）计算亮度。
+//
+// 照度到亮度的映射曲线为 JSON 数组，例如:
+//
+//	[{"Lux":0,"Brightness":0.2},{"Lux":100,"Brightness":0.5},{"Lux":10000,"Brightness":1}]
+//
+// 点之间按照度的对数插值，因为人眼对亮度的感知大致是对数的。用户手动调节亮度后，
+// 用当前照度和调节后的亮度修改曲线，曲线始终保持单调不减。
+
+type LightCurvePoint struct {
+	Lux        float64
+	Brightness float64
+}
+
+type LightCurve []LightCurvePoint
+
+// DefaultLightCurve 没有学习过的默认曲线
+var DefaultLightCurve = LightCurve{
+	{Lux: 0, Brightness: 0.2},
+	{Lux: 10, Brightness: 0.3},
+	{Lux: 100, Brightness: 0.5},
+	{Lux: 1000, Brightness: 0.8},
+	{Lux: 10000, Brightness: 1},
+}
+
+const (
+	// 学习时，对数照度距离小于此值的旧点被新点替换
+	lightCurveMergeDistance = 0.25
+	// 亮度为 0 不是有效的亮度
+	lightCurveMinBrightness = 0.01
+)
+
+var errLightCurveEmpty = errors.New("light curve is empty")
+
+// ParseLightCurve 解析照度亮度曲线，空字符串表示使用默认曲线
+func ParseLightCurve(data string) (LightCurve, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return DefaultLightCurve.clone(), nil
+	}
+	var curve LightCurve
+	err := json.Unmarshal([]byte(data), &curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(curve) == 0 {
+		return nil, errLightCurveEmpty
+	}
+	for _, point := range curve {
+		if point.Lux < 0 || math.IsNaN(point.Lux) || math.IsInf(point.Lux, 0) {
+			return nil, fmt.Errorf("invalid lux %v", point.Lux)
+		}
+		if !IsValidBrightness(point.Brightness) {
+			return nil, fmt.Errorf("invalid brightness %v at lux %v", point.Brightness, point.Lux)
+		}
+	}
+	sort.SliceStable(curve, func(i, j int) bool {
+		return curve[i].Lux < curve[j].Lux
+	})
+	return curve, nil
+}
+
+func (c LightCurve) clone() LightCurve {
+	result := make(LightCurve, len(c))
+	copy(result, c)
+	return result
+}
+
+func (c LightCurve) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func luxToLog(lux float64) float64 {
+	if lux < 0 {
+		lux = 0
+	}
+	return math.Log10(lux + 1)
+}
+
+// Brightness 返回照度 lux 对应的亮度，超出曲线范围时使用两端的亮度
+func (c LightCurve) Brightness(lux float64) float64 {
+	if len(c) == 0 {
+		return 1
+	}
+	x := luxToLog(lux)
+	if x <= luxToLog(c[0].Lux) {
+		return c[0].Brightness
+	}
+	for i := 1; i < len(c); i++ {
+		x1 := luxToLog(c[i].Lux)
+		if x > x1 {
+			continue
+		}
+		x0 := luxToLog(c[i-1].Lux)
+		if x1 == x0 {
+			return c[i].Brightness
+		}
+		b0, b1 := c[i-1].Brightness, c[i].Brightness
+		return b0 + (b1-b0)*(x-x0)/(x1-x0)
+	}
+	return c[len(c)-1].Brightness
+}
+
+// Adjust 用户在照度 lux 下把亮度调节为 brightness，返回学习后的新曲线。
+// 附近的点被替换，照度更低的点亮度不高于 brightness，照度更高的点亮度不低于 brightness。
+func (c LightCurve) Adjust(lux, brightness float64) LightCurve {
+	if lux < 0 {
+		lux = 0
+	}
+	brightness = ClampBrightness(brightness, lightCurveMinBrightness)
+	x := luxToLog(lux)
+
+	result := make(LightCurve, 0, len(c)+1)
+	inserted := false
+	for _, point := range c {
+		px := luxToLog(point.Lux)
+		if math.Abs(px-x) < lightCurveMergeDistance {
+			continue
+		}
+		if px > x && !inserted {
+			result = append(result, LightCurvePoint{Lux: lux, Brightness: brightness})
+			inserted = true
+		}
+		if px < x && point.Brightness > brightness {
+			point.Brightness = brightness
+		} else if px > x && point.Brightness < brightness {
+			point.Brightness = brightness
+		}
+		result = append(result, point)
+	}
+	if !inserted {
+		result = append(result, LightCurvePoint{Lux: lux, Brightness: brightness})
+	}
+	return result
+}
+
+// LightSmoother 对照度做指数平滑，避免亮度随照度抖动。照度变高时跟得快一些，变低时慢一些，
+// 这样从暗处到亮处时能很快看清屏幕，偶尔被遮挡时亮度也不会马上变暗。
+type LightSmoother struct {
+	Brighten time.Duration
+	Darken   time.Duration
+
+	value float64 // 对数照度
+	last  time.Time
+	valid bool
+}
+
+// Update 输入 now 时的照度，返回平滑后的照度
+func (s *LightSmoother) Update(lux float64, now time.Time) float64 {
+	x := luxToLog(lux)
+	if !s.valid {
+		s.value = x
+		s.last = now
+		s.valid = true
+		return lux
+	}
+
+	dt := now.Sub(s.last)
+	s.last = now
+	tau := s.Darken
+	if x > s.value {
+		tau = s.Brighten
+	}
+	if dt <= 0 {
+		return s.Value()
+	}
+	if tau <= 0 {
+		s.value = x
+	} else {
+		s.value += (x - s.value) * (1 - math.Exp(-float64(dt)/float64(tau)))
+	}
+	return s.Value()
+}
+
+// Value 返回当前平滑后的照度
+func (s *LightSmoother) Value() float64 {
+	return math.Pow(10, s.value) - 1
+}
+
+// Settled 平滑后的照度是否已经接近 lux
+func (s *LightSmoother) Settled(lux float64) bool {
+	return s.valid && math.Abs(luxToLog(lux)-s.value) < 0.01
+}
+
+func (s *LightSmoother) Reset() {
+	s.valid = false
+}
+
+// StepBrightness 从 current 向 target 调整，每次最多改变 maxDelta
+func StepBrightness(current, target, maxDelta float64) float64 {
+	if math.Abs(target-current) <= maxDelta {
+		return target
+	}
+	if target > current {
+		return current + maxDelta
+	}
+	return current - maxDelta
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLightCurve(t *testing.T) {
+	curve, err := ParseLightCurve("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLightCurve, curve)
+
+	curve, err = ParseLightCurve(`[{"Lux":1000,"Brightness":0.9},{"Lux":0,"Brightness":0.1}]`)
+	require.NoError(t, err)
+	assert.Equal(t, LightCurve{{0, 0.1}, {1000, 0.9}}, curve)
+
+	_, err = ParseLightCurve(`[]`)
+	assert.Error(t, err)
+	_, err = ParseLightCurve(`[{"Lux":-1,"Brightness":0.5}]`)
+	assert.Error(t, err)
+	_, err = ParseLightCurve(`[{"Lux":10,"Brightness":0}]`)
+	assert.Error(t, err)
+}
+
+func TestLightCurve_Brightness(t *testing.T) {
+	curve := LightCurve{{0, 0.2}, {99, 0.6}, {9999, 1}}
+	assert.Equal(t, 0.2, curve.Brightness(0))
+	assert.Equal(t, 0.6, curve.Brightness(99))
+	// 对数插值，log10(999+1) 在 log10(99+1) 和 log10(9999+1) 中间
+	assert.InDelta(t, 0.8, curve.Brightness(999), 1e-9)
+	assert.Equal(t, 1.0, curve.Brightness(100000))
+}
+
+func TestLightCurve_Adjust(t *testing.T) {
+	curve := DefaultLightCurve.Adjust(100, 0.9)
+	assert.InDelta(t, 0.9, curve.Brightness(100), 1e-9)
+	// 保持单调不减
+	for i := 1; i < len(curve); i++ {
+		assert.True(t, curve[i].Lux > curve[i-1].Lux)
+		assert.True(t, curve[i].Brightness >= curve[i-1].Brightness)
+	}
+	// 离得远的点不受影响
+	assert.Equal(t, 0.2, curve.Brightness(0))
+	assert.Equal(t, 1.0, curve.Brightness(10000))
+	// 默认曲线没有被修改
+	assert.Equal(t, 0.5, DefaultLightCurve.Brightness(100))
+
+	curve = DefaultLightCurve.Adjust(20, 0.05)
+	assert.InDelta(t, 0.05, curve.Brightness(20), 1e-9)
+	assert.Equal(t, 0.05, curve.Brightness(0))
+
+	curve = DefaultLightCurve.Adjust(50000, 0)
+	assert.Equal(t, lightCurveMinBrightness, curve.Brightness(50000))
+	_, err := ParseLightCurve(curve.String())
+	assert.NoError(t, err)
+}
+
+func TestLightSmoother(t *testing.T) {
+	s := LightSmoother{Brighten: time.Second, Darken: 10 * time.Second}
+	now := time.Now()
+	assert.Equal(t, 100.0, s.Update(100, now))
+	assert.True(t, s.Settled(100))
+
+	// 变亮时比变暗时跟得快
+	up := s.Update(10000, now.Add(time.Second))
+	assert.True(t, up > 100 && up < 10000)
+	s.Reset()
+	s.Update(10000, now)
+	down := s.Update(100, now.Add(time.Second))
+	assert.True(t, down > 100 && down < 10000)
+	assert.True(t, luxToLog(10000)-luxToLog(down) < luxToLog(up)-luxToLog(100))
+
+	for i := 2; i < 200; i++ {
+		s.Update(100, now.Add(time.Duration(i)*time.Second))
+	}
+	assert.True(t, s.Settled(100))
+}
+
+func TestStepBrightness(t *testing.T) {
+	assert.Equal(t, 0.5, StepBrightness(0.45, 0.5, 0.1))
+	assert.InDelta(t, 0.4, StepBrightness(0.3, 0.8, 0.1), 1e-9)
+	assert.InDelta(t, 0.7, StepBrightness(0.8, 0.3, 0.1), 1e-9)
+}
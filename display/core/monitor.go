@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RotationNormal 不旋转，值同 randr.RotationRotate0
+const RotationNormal uint16 = 1
+
+// Mode 生成配置时需要的显示模式信息
+type Mode struct {
+	Width  uint16
+	Height uint16
+	Rate   float64
+}
+
+// Monitor 选择主屏和生成配置时需要的显示器信息，由两个显示服务各自的 Monitor 转换得到
+type Monitor struct {
+	ID   uint32
+	UUID string
+	// 旧版本算法生成的 uuid，可以为空
+	UUIDV0  string
+	Name    string
+	Builtin bool
+
+	BestMode Mode
+	Modes    []Mode
+}
+
+func (m *Monitor) toBasicSysConfig() *SysMonitorConfig {
+	return &SysMonitorConfig{
+		UUID: m.UUID,
+		Name: m.Name,
+	}
+}
+
+// GetFirstModeBySize 返回第一个尺寸是 width x height 的模式，找不到时返回 false
+func GetFirstModeBySize(modes []Mode, width, height uint16) (Mode, bool) {
+	for _, mode := range modes {
+		if mode.Width == width && mode.Height == height {
+			return mode, true
+		}
+	}
+	return Mode{}, false
+}
+
+// GetCommonSizes 返回所有显示器都支持的尺寸，最佳模式都相同时只返回最佳模式的尺寸
+func GetCommonSizes(monitors []Monitor) []Size {
+	if len(monitors) == 0 {
+		return nil
+	}
+	var notUseBestMode bool
+	count := make(map[Size]int)
+	bestMode := monitors[0].BestMode
+	for _, monitor := range monitors {
+		if bestMode != monitor.BestMode {
+			notUseBestMode = true
+		}
+		sizes := make(map[Size]struct{})
+		for _, mode := range monitor.Modes {
+			sizes[Size{mode.Width, mode.Height}] = struct{}{}
+		}
+		for size := range sizes {
+			count[size]++
+		}
+	}
+
+	if !notUseBestMode {
+		return []Size{{bestMode.Width, bestMode.Height}}
+	}
+	var commonSizes []Size
+	for size, value := range count {
+		if value == len(monitors) {
+			commonSizes = append(commonSizes, size)
+		}
+	}
+	return commonSizes
+}
+
+const (
+	priorityEDP = iota
+	priorityDP
+	priorityHDMI
+	priorityDVI
+	priorityVGA
+	priorityOther
+)
+
+var monitorTypePriority = map[string]int{
+	"edp":  priorityEDP,
+	"dp":   priorityDP,
+	"hdmi": priorityHDMI,
+	"dvi":  priorityDVI,
+	"vga":  priorityVGA,
+}
+
+// GetPortType 根据显示器名称判断出端口类型，比如 vga，hdmi，edp 等。
+func GetPortType(name string) string {
+	i := strings.IndexRune(name, '-')
+	if i != -1 {
+		name = name[0:i]
+	}
+	return strings.ToLower(name)
+}
+
+func GetPortPriority(name string) int {
+	p, ok := monitorTypePriority[GetPortType(name)]
+	if ok {
+		return p
+	}
+	return priorityOther
+}
+
+// GetPrimaryMonitor 选择默认的主屏，内置显示器优先，其次按端口类型的优先级，
+// 优先级相同时按最后连接时间从早到晚，再按 ID 从小到大。connectTime 可以为 nil。
+func GetPrimaryMonitor(monitors []Monitor, connectTime func(name string) time.Time) (Monitor, bool) {
+	if len(monitors) == 0 {
+		return Monitor{}, false
+	}
+	for _, monitor := range monitors {
+		if monitor.Builtin {
+			return monitor, true
+		}
+	}
+
+	sorted := make([]Monitor, len(monitors))
+	copy(sorted, monitors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		mi := sorted[i]
+		mj := sorted[j]
+		pi := GetPortPriority(mi.Name)
+		pj := GetPortPriority(mj.Name)
+		if pi != pj {
+			return pi < pj
+		}
+		if connectTime != nil {
+			ti := connectTime(mi.Name)
+			tj := connectTime(mj.Name)
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+		}
+		return mi.ID < mj.ID
+	})
+	return sorted[0], true
+}
+
+// BuildMirrorConfigs 生成复制模式的配置，所有显示器使用共同支持的最大尺寸
+func BuildMirrorConfigs(monitors []Monitor, primary Monitor) (SysMonitorConfigs, error) {
+	commonSizes := GetCommonSizes(monitors)
+	if len(commonSizes) == 0 {
+		return nil, errors.New("not found common size")
+	}
+	maxSize := GetMaxAreaSize(commonSizes)
+	var configs SysMonitorConfigs
+	for _, monitor := range monitors {
+		cfg := monitor.toBasicSysConfig()
+		cfg.Enabled = true
+		cfg.Primary = monitor.ID == primary.ID
+		mode, _ := GetFirstModeBySize(monitor.Modes, maxSize.Width, maxSize.Height)
+		cfg.Width = mode.Width
+		cfg.Height = mode.Height
+		cfg.RefreshRate = mode.Rate
+		cfg.Rotation = RotationNormal
+		cfg.Brightness = 1
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// BuildExtendConfigs 生成扩展模式的配置，主屏在最左边，其余的按 ID 从左到右排列，都使用最佳模式
+func BuildExtendConfigs(monitors []Monitor, primary Monitor) SysMonitorConfigs {
+	sorted := make([]Monitor, len(monitors))
+	copy(sorted, monitors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ID == primary.ID {
+			return sorted[j].ID != primary.ID
+		} else if sorted[j].ID == primary.ID {
+			return false
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	var configs SysMonitorConfigs
+	var xOffset int
+	for _, monitor := range sorted {
+		cfg := monitor.toBasicSysConfig()
+		cfg.Enabled = true
+		cfg.Primary = monitor.ID == primary.ID
+		// 不用考虑旋转，默认不旋转
+		cfg.Width = monitor.BestMode.Width
+		cfg.Height = monitor.BestMode.Height
+		cfg.RefreshRate = monitor.BestMode.Rate
+		if xOffset > math.MaxInt16 {
+			xOffset = math.MaxInt16
+		}
+		cfg.X = int16(xOffset)
+		cfg.Rotation = RotationNormal
+		cfg.Brightness = 1
+		xOffset += int(cfg.Width)
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+// BuildOnlyOneConfigs 生成单屏模式的配置，只包含 uuid 对应的显示器，找不到时返回 nil
+func BuildOnlyOneConfigs(monitors []Monitor, uuid string) SysMonitorConfigs {
+	for _, monitor := range monitors {
+		if monitor.UUID != uuid {
+			continue
+		}
+		cfg := monitor.toBasicSysConfig()
+		cfg.Enabled = true
+		cfg.Primary = true
+		cfg.Width = monitor.BestMode.Width
+		cfg.Height = monitor.BestMode.Height
+		cfg.RefreshRate = monitor.BestMode.Rate
+		cfg.Rotation = RotationNormal
+		cfg.Brightness = 1
+		return SysMonitorConfigs{cfg}
+	}
+	return nil
+}
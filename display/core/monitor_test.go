@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mode1080 = Mode{1920, 1080, 60}
+	mode768  = Mode{1024, 768, 60}
+	mode1440 = Mode{2560, 1440, 60}
+)
+
+func Test_GetCommonSizes(t *testing.T) {
+	monitors := []Monitor{
+		{ID: 1, BestMode: mode1080, Modes: []Mode{mode1080, mode768}},
+		{ID: 2, BestMode: mode1080, Modes: []Mode{mode1080}},
+	}
+	// 最佳模式相同
+	assert.Equal(t, []Size{{1920, 1080}}, GetCommonSizes(monitors))
+
+	monitors[1] = Monitor{ID: 2, BestMode: mode1440, Modes: []Mode{mode1440, mode768, mode768}}
+	assert.Equal(t, []Size{{1024, 768}}, GetCommonSizes(monitors))
+	assert.Nil(t, GetCommonSizes(nil))
+}
+
+func Test_GetPrimaryMonitor(t *testing.T) {
+	_, ok := GetPrimaryMonitor(nil, nil)
+	assert.False(t, ok)
+
+	monitors := []Monitor{
+		{ID: 3, Name: "VGA-1"},
+		{ID: 2, Name: "HDMI-2"},
+		{ID: 1, Name: "HDMI-1"},
+	}
+	primary, ok := GetPrimaryMonitor(monitors, nil)
+	require.True(t, ok)
+	assert.Equal(t, "HDMI-1", primary.Name)
+
+	// 优先级相同时，最早连接的优先
+	now := time.Now()
+	connectTime := map[string]time.Time{"HDMI-1": now, "HDMI-2": now.Add(-time.Minute)}
+	primary, _ = GetPrimaryMonitor(monitors, func(name string) time.Time {
+		return connectTime[name]
+	})
+	assert.Equal(t, "HDMI-2", primary.Name)
+
+	// 内置显示器优先
+	monitors = append(monitors, Monitor{ID: 4, Name: "LVDS-1", Builtin: true})
+	primary, _ = GetPrimaryMonitor(monitors, nil)
+	assert.Equal(t, "LVDS-1", primary.Name)
+	// 没有改变参数的顺序
+	assert.Equal(t, uint32(3), monitors[0].ID)
+
+	assert.Equal(t, "edp", GetPortType("eDP-1"))
+	assert.Equal(t, priorityOther, GetPortPriority("Virtual-1"))
+}
+
+func Test_BuildModeConfigs(t *testing.T) {
+	monitors := []Monitor{
+		{ID: 1, UUID: "uuid1", Name: "HDMI-1", BestMode: mode1080, Modes: []Mode{mode1080, mode768}},
+		{ID: 2, UUID: "uuid2", Name: "eDP-1", BestMode: mode1440, Modes: []Mode{mode1440, mode768}},
+	}
+
+	configs, err := BuildMirrorConfigs(monitors, monitors[1])
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	for _, cfg := range configs {
+		assert.True(t, cfg.Enabled)
+		assert.Equal(t, uint16(1024), cfg.Width)
+		assert.Equal(t, int16(0), cfg.X)
+		assert.Equal(t, RotationNormal, cfg.Rotation)
+	}
+	assert.False(t, configs[0].Primary)
+	assert.True(t, configs[1].Primary)
+
+	_, err = BuildMirrorConfigs([]Monitor{
+		{ID: 1, BestMode: mode1080, Modes: []Mode{mode1080}},
+		{ID: 2, BestMode: mode768, Modes: []Mode{mode768}},
+	}, monitors[0])
+	assert.Error(t, err)
+
+	// 主屏在最左边
+	configs = BuildExtendConfigs(monitors, monitors[1])
+	require.Len(t, configs, 2)
+	assert.Equal(t, "uuid2", configs[0].UUID)
+	assert.True(t, configs[0].Primary)
+	assert.Equal(t, int16(0), configs[0].X)
+	assert.Equal(t, uint16(2560), configs[0].Width)
+	assert.Equal(t, "uuid1", configs[1].UUID)
+	assert.Equal(t, int16(2560), configs[1].X)
+	assert.Equal(t, uint16(1920), configs[1].Width)
+
+	configs = BuildOnlyOneConfigs(monitors, "uuid1")
+	require.Len(t, configs, 1)
+	assert.True(t, configs[0].Enabled)
+	assert.True(t, configs[0].Primary)
+	assert.Equal(t, uint16(1080), configs[0].Height)
+	assert.Nil(t, BuildOnlyOneConfigs(monitors, "gone"))
+}
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 可移植的显示配置，用于在型号相同的机器之间复制显示布局。
+// 导出时配置中的显示器 uuid 被替换为 "monitor<N>"，N 是显示器在 Monitors 中的下标，
+// 导入时根据 EDID 信息把 Monitors 中的显示器和本机已连接的显示器对应起来，不依赖接口名称。
+
+const PortableConfigVersion = "1.0"
+
+const portableUuidPrefix = "monitor"
+
+// MonitorIdentity 根据 EDID 标识一个显示器
+type MonitorIdentity struct {
+	Manufacturer string
+	Model        string
+	// EdidHash 是 EDID 前 128 字节的 md5，同一台显示器才会相同
+	EdidHash string `json:",omitempty"`
+	// Connector 接口名称，只用于区分型号相同的显示器
+	Connector string `json:",omitempty"`
+}
+
+// LocalMonitor 本机已连接的显示器
+type LocalMonitor struct {
+	UUID     string
+	Name     string
+	Identity MonitorIdentity
+}
+
+type PortableConfig struct {
+	Version     string
+	DisplayMode byte
+	Monitors    []MonitorIdentity
+	Screen      *SysScreenConfig
+}
+
+func portableUuid(idx int) string {
+	return portableUuidPrefix + strconv.Itoa(idx)
+}
+
+func parsePortableUuid(uuid string) (int, bool) {
+	if !strings.HasPrefix(uuid, portableUuidPrefix) {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(uuid[len(portableUuidPrefix):])
+	if err != nil || idx < 0 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// NewPortableConfig 把 monitors 这组显示器的屏幕配置转换为可移植的配置
+func NewPortableConfig(displayMode byte, monitors []LocalMonitor, screenCfg *SysScreenConfig) (*PortableConfig, error) {
+	if len(monitors) == 0 {
+		return nil, errors.New("no monitor")
+	}
+	if screenCfg == nil {
+		return nil, errors.New("screen config is nil")
+	}
+	identities := make([]MonitorIdentity, len(monitors))
+	for i, monitor := range monitors {
+		identities[i] = monitor.Identity
+	}
+	screen, err := screenCfg.mapUuid(func(uuid string) (string, string, bool) {
+		for i, monitor := range monitors {
+			if monitor.UUID == uuid {
+				return portableUuid(i), "", true
+			}
+		}
+		return "", "", false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &PortableConfig{
+		Version:     PortableConfigVersion,
+		DisplayMode: displayMode,
+		Monitors:    identities,
+		Screen:      screen,
+	}, nil
+}
+
+// ParsePortableConfig 解析并检查可移植的配置
+func ParsePortableConfig(data []byte) (*PortableConfig, error) {
+	var c PortableConfig
+	err := json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != PortableConfigVersion {
+		return nil, fmt.Errorf("unsupported config version %q", c.Version)
+	}
+	if len(c.Monitors) == 0 || c.Screen == nil {
+		return nil, errors.New("invalid config: no monitor or screen config")
+	}
+	return &c, nil
+}
+
+// ToScreenConfig 把可移植的配置转换为本机 monitors 这组显示器的屏幕配置
+func (c *PortableConfig) ToScreenConfig(monitors []LocalMonitor) (*SysScreenConfig, error) {
+	matched, err := matchMonitors(c.Monitors, monitors)
+	if err != nil {
+		return nil, err
+	}
+	screenCfg, err := c.Screen.mapUuid(func(uuid string) (string, string, bool) {
+		idx, ok := parsePortableUuid(uuid)
+		if !ok || idx >= len(matched) {
+			return "", "", false
+		}
+		monitor := monitors[matched[idx]]
+		return monitor.UUID, monitor.Name, true
+	})
+	if err != nil {
+		return nil, err
+	}
+	screenCfg.Fix()
+	return screenCfg, nil
+}
+
+// matchMonitors 为每个 identities 中的显示器找到 monitors 中对应的显示器，返回其下标。
+// 优先匹配 EDID 完全相同的显示器，其次是厂商、型号和接口都相同的，最后是厂商和型号相同的。
+func matchMonitors(identities []MonitorIdentity, monitors []LocalMonitor) ([]int, error) {
+	if len(identities) != len(monitors) {
+		return nil, fmt.Errorf("config is for %d monitors, but %d connected", len(identities), len(monitors))
+	}
+
+	result := make([]int, len(identities))
+	for i := range result {
+		result[i] = -1
+	}
+	used := make([]bool, len(monitors))
+	matchers := []func(a, b *MonitorIdentity) bool{
+		func(a, b *MonitorIdentity) bool {
+			return a.EdidHash != "" && a.EdidHash == b.EdidHash
+		},
+		func(a, b *MonitorIdentity) bool {
+			return a.Manufacturer == b.Manufacturer && a.Model == b.Model && a.Connector == b.Connector
+		},
+		func(a, b *MonitorIdentity) bool {
+			return a.Manufacturer == b.Manufacturer && a.Model == b.Model
+		},
+	}
+	for _, match := range matchers {
+		for i := range identities {
+			if result[i] != -1 {
+				continue
+			}
+			for j := range monitors {
+				if !used[j] && match(&identities[i], &monitors[j].Identity) {
+					result[i] = j
+					used[j] = true
+					break
+				}
+			}
+		}
+	}
+
+	for i, j := range result {
+		if j == -1 {
+			return nil, fmt.Errorf("no connected monitor matches %s %s",
+				identities[i].Manufacturer, identities[i].Model)
+		}
+	}
+	return result, nil
+}
+
+// mapUuid 复制配置并替换其中所有显示器的 uuid，name 不为空时也替换显示器的名称
+func (c *SysScreenConfig) mapUuid(fn func(uuid string) (newUuid, name string, ok bool)) (*SysScreenConfig, error) {
+	result := c.Clone()
+	mapConfigs := func(configs SysMonitorConfigs) error {
+		for _, config := range configs {
+			uuid, name, ok := fn(config.UUID)
+			if !ok {
+				return fmt.Errorf("unknown monitor %q", config.UUID)
+			}
+			config.UUID = uuid
+			if name != "" {
+				config.Name = name
+			}
+		}
+		return nil
+	}
+
+	for _, modeCfg := range []*SysMonitorModeConfig{result.Mirror, result.Extend, result.Single} {
+		if modeCfg == nil {
+			continue
+		}
+		err := mapConfigs(modeCfg.Monitors)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(result.OnlyOneMap) > 0 {
+		onlyOneMap := make(map[string]*SysMonitorModeConfig, len(result.OnlyOneMap))
+		for uuid, modeCfg := range result.OnlyOneMap {
+			newUuid, _, ok := fn(uuid)
+			if !ok {
+				return nil, fmt.Errorf("unknown monitor %q", uuid)
+			}
+			if modeCfg != nil {
+				err := mapConfigs(modeCfg.Monitors)
+				if err != nil {
+					return nil, err
+				}
+			}
+			onlyOneMap[newUuid] = modeCfg
+		}
+		result.OnlyOneMap = onlyOneMap
+	}
+	for _, profile := range result.Profiles {
+		if profile == nil {
+			continue
+		}
+		err := mapConfigs(profile.Monitors)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if result.OnlyOneUuid != "" {
+		uuid, _, ok := fn(result.OnlyOneUuid)
+		if !ok {
+			return nil, fmt.Errorf("unknown monitor %q", result.OnlyOneUuid)
+		}
+		result.OnlyOneUuid = uuid
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortableConfig(t *testing.T) {
+	src := []LocalMonitor{
+		{UUID: "eDP-1|aaa|v1", Name: "eDP-1",
+			Identity: MonitorIdentity{Manufacturer: "BOE", Model: "NV140", EdidHash: "aaa", Connector: "eDP-1"}},
+		{UUID: "HDMI-1|bbb|v1", Name: "HDMI-1",
+			Identity: MonitorIdentity{Manufacturer: "DEL", Model: "U2720Q", EdidHash: "bbb", Connector: "HDMI-1"}},
+	}
+	screenCfg := &SysScreenConfig{
+		Extend: &SysMonitorModeConfig{
+			Monitors: SysMonitorConfigs{
+				{UUID: "eDP-1|aaa|v1", Name: "eDP-1", Enabled: true, Width: 1920, Height: 1080, Primary: true},
+				{UUID: "HDMI-1|bbb|v1", Name: "HDMI-1", Enabled: true, X: 1920, Width: 3840, Height: 2160},
+			},
+		},
+		OnlyOneMap: map[string]*SysMonitorModeConfig{
+			"HDMI-1|bbb|v1": {Monitors: SysMonitorConfigs{{UUID: "HDMI-1|bbb|v1", Enabled: true}}},
+		},
+		OnlyOneUuid: "HDMI-1|bbb|v1",
+	}
+
+	portableCfg, err := NewPortableConfig(DisplayModeExtend, src, screenCfg)
+	require.NoError(t, err)
+	assert.Equal(t, "monitor1", portableCfg.Screen.OnlyOneUuid)
+	assert.NotNil(t, portableCfg.Screen.Extend.Monitors.GetByUuid("monitor0"))
+	// 原配置不变
+	assert.NotNil(t, screenCfg.Extend.Monitors.GetByUuid("eDP-1|aaa|v1"))
+
+	data, err := json.Marshal(portableCfg)
+	require.NoError(t, err)
+	portableCfg, err = ParsePortableConfig(data)
+	require.NoError(t, err)
+	assert.Equal(t, DisplayModeExtend, portableCfg.DisplayMode)
+
+	// 另一台机器，显示器型号相同，但 EDID 和接口不同
+	dst := []LocalMonitor{
+		{UUID: "DP-2|ddd|v1", Name: "DP-2",
+			Identity: MonitorIdentity{Manufacturer: "DEL", Model: "U2720Q", EdidHash: "ddd", Connector: "DP-2"}},
+		{UUID: "eDP-1|ccc|v1", Name: "eDP-1",
+			Identity: MonitorIdentity{Manufacturer: "BOE", Model: "NV140", EdidHash: "ccc", Connector: "eDP-1"}},
+	}
+	result, err := portableCfg.ToScreenConfig(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "DP-2|ddd|v1", result.OnlyOneUuid)
+	assert.NotNil(t, result.OnlyOneMap["DP-2|ddd|v1"])
+	cfg := result.Extend.Monitors.GetByUuid("DP-2|ddd|v1")
+	require.NotNil(t, cfg)
+	assert.Equal(t, "DP-2", cfg.Name)
+	assert.Equal(t, uint16(3840), cfg.Width)
+	assert.Equal(t, float64(1), cfg.Brightness)
+	assert.True(t, result.Extend.Monitors.GetByUuid("eDP-1|ccc|v1").Primary)
+
+	// 显示器型号不同
+	dst[0].Identity.Model = "P2419H"
+	_, err = portableCfg.ToScreenConfig(dst)
+	assert.Error(t, err)
+
+	// 显示器数量不同
+	_, err = portableCfg.ToScreenConfig(dst[:1])
+	assert.Error(t, err)
+}
+
+func TestMatchMonitors(t *testing.T) {
+	identities := []MonitorIdentity{
+		{Manufacturer: "DEL", Model: "U2720Q", Connector: "DP-1"},
+		{Manufacturer: "DEL", Model: "U2720Q", Connector: "DP-2"},
+	}
+	monitors := []LocalMonitor{
+		{Identity: MonitorIdentity{Manufacturer: "DEL", Model: "U2720Q", Connector: "DP-2"}},
+		{Identity: MonitorIdentity{Manufacturer: "DEL", Model: "U2720Q", Connector: "DP-1"}},
+	}
+	// 型号相同时按接口区分
+	result, err := matchMonitors(identities, monitors)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, result)
+
+	// EDID 相同时优先
+	identities[0].EdidHash = "x"
+	monitors[0].Identity.EdidHash = "x"
+	result, err = matchMonitors(identities, monitors)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, result)
+}
+
+func TestParsePortableConfig(t *testing.T) {
+	_, err := ParsePortableConfig([]byte(`{"Version":"9.0"}`))
+	assert.Error(t, err)
+	_, err = ParsePortableConfig([]byte(`{"Version":"1.0"}`))
+	assert.Error(t, err)
+	_, err = ParsePortableConfig([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 命名布局（profile）保存在 SysScreenConfig.Profiles 中，同一组显示器可以保存多个布局。
+
+type ProfileNotFoundError struct {
+	Name string
+}
+
+func (err ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("not found profile %q", err.Name)
+}
+
+func (c *SysScreenConfig) GetProfile(name string) *SysProfileConfig {
+	if c == nil {
+		return nil
+	}
+	return c.Profiles[name]
+}
+
+func (c *SysScreenConfig) SetProfile(name string, profile *SysProfileConfig) {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*SysProfileConfig)
+	}
+	c.Profiles[name] = profile
+}
+
+func (c *SysScreenConfig) RenameProfile(name, newName string) error {
+	profile := c.GetProfile(name)
+	if profile == nil {
+		return ProfileNotFoundError{Name: name}
+	}
+	if name == newName {
+		return nil
+	}
+	if c.GetProfile(newName) != nil {
+		return fmt.Errorf("same name profile %q already exists", newName)
+	}
+	delete(c.Profiles, name)
+	c.Profiles[newName] = profile
+	if c.CurrentProfile == name {
+		c.CurrentProfile = newName
+	}
+	return nil
+}
+
+func (c *SysScreenConfig) DeleteProfile(name string) error {
+	if c.GetProfile(name) == nil {
+		return ProfileNotFoundError{Name: name}
+	}
+	delete(c.Profiles, name)
+	if len(c.Profiles) == 0 {
+		c.Profiles = nil
+	}
+	if c.CurrentProfile == name {
+		c.CurrentProfile = ""
+	}
+	return nil
+}
+
+// GetProfileNames 返回排好序的布局名称列表
+func (c *SysScreenConfig) GetProfileNames() []string {
+	if c == nil || len(c.Profiles) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 一天中的时间点，用于亮度计划和浅色深色外观的切换。
+// 格式为 "HH:MM"，或者 "sunrise"，"sunset"，后面可以带以分钟为单位的偏移，例如 "sunset-30"。
+
+const (
+	timeSunrise = "sunrise"
+	timeSunset  = "sunset"
+)
+
+var errScheduleEmpty = errors.New("schedule is empty")
+
+// parseScheduleTime 解析时间点，anchor 为 sunrise、sunset 或者空，minutes 是相对 anchor 或者零点的分钟数
+func parseScheduleTime(str string) (anchor string, minutes int, err error) {
+	for _, a := range []string{timeSunrise, timeSunset} {
+		if !strings.HasPrefix(str, a) {
+			continue
+		}
+		offset := str[len(a):]
+		if offset == "" {
+			return a, 0, nil
+		}
+		if offset[0] != '+' && offset[0] != '-' {
+			break
+		}
+		minutes, err = strconv.Atoi(offset)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid time %q", str)
+		}
+		return a, minutes, nil
+	}
+
+	t, err := time.Parse("15:04", str)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid time %q", str)
+	}
+	return "", t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateScheduleTime 检查时间点的格式
+func ValidateScheduleTime(str string) error {
+	_, _, err := parseScheduleTime(str)
+	return err
+}
+
+type scheduleEvent struct {
+	time  time.Time
+	index int
+}
+
+// scheduleEventsOfDay 计算 day 那天每个时间点的具体时间，忽略格式错误的时间点
+func scheduleEventsOfDay(times []string, day time.Time, pos *GeoPosition) []scheduleEvent {
+	midnight := getMidnight(day)
+	sunrise, sunset, _ := getSunTimesOfDay(day, pos)
+
+	events := make([]scheduleEvent, 0, len(times))
+	for idx, str := range times {
+		anchor, minutes, err := parseScheduleTime(str)
+		if err != nil {
+			continue
+		}
+		t := midnight
+		switch anchor {
+		case timeSunrise:
+			t = sunrise
+		case timeSunset:
+			t = sunset
+		}
+		events = append(events, scheduleEvent{
+			time:  t.Add(time.Duration(minutes) * time.Minute),
+			index: idx,
+		})
+	}
+	return events
+}
+
+// FindScheduleTime 返回 now 时生效的时间点，即最近一个已经过去的时间点在 times 中的下标和具体时间，
+// 以及 now 之后的下一个时间点。pos 为 nil 时使用默认的日出日落时间。
+func FindScheduleTime(times []string, now time.Time, pos *GeoPosition) (index int, since, next time.Time, err error) {
+	// 前后各多算一天，凌晨使用前一天最后的时间点
+	var events []scheduleEvent
+	for _, offset := range []int{-1, 0, 1} {
+		events = append(events, scheduleEventsOfDay(times, now.AddDate(0, 0, offset), pos)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Before(events[j].time)
+	})
+
+	for _, ev := range events {
+		if ev.time.After(now) {
+			next = ev.time
+			break
+		}
+		index = ev.index
+		since = ev.time
+	}
+	if since.IsZero() || next.IsZero() {
+		return 0, time.Time{}, time.Time{}, errScheduleEmpty
+	}
+	return index, since, next, nil
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindScheduleTime(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2022, 6, day, hour, min, 0, 0, time.UTC)
+	}
+
+	// 没有位置时日出日落是 06:00 和 18:00，格式错误的时间点被忽略
+	times := []string{"sunrise", "abc", "sunset+30"}
+	index, since, next, err := FindScheduleTime(times, at(1, 12, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+	assert.Equal(t, at(1, 6, 0), since)
+	assert.Equal(t, at(1, 18, 30), next)
+
+	index, since, next, err = FindScheduleTime(times, at(1, 3, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, at(0, 18, 30), since)
+	assert.Equal(t, at(1, 6, 0), next)
+
+	_, _, _, err = FindScheduleTime([]string{"abc"}, at(1, 12, 0), nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, ValidateScheduleTime("07:30"))
+	assert.NoError(t, ValidateScheduleTime("sunset-30"))
+	assert.Error(t, ValidateScheduleTime("sunsetx"))
+}
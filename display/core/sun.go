@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// 太阳位置的计算，使用 NOAA 的近似算法，精度在几分钟以内，足够用于调节亮度和色温。
+
+const (
+	// 拿不到位置或者极昼极夜时使用的日出日落时间
+	defaultSunriseMinutes = 6 * 60
+	defaultSunsetMinutes  = 18 * 60
+
+	rad = math.Pi / 180
+)
+
+// GeoPosition 地理位置，单位是度，北纬和东经为正
+type GeoPosition struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// solarParams 返回 t 时的时差（分钟）和太阳赤纬（弧度）
+func solarParams(t time.Time) (eqTime, decl float64) {
+	t = t.UTC()
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	gamma := 2 * math.Pi / 365 * (float64(t.YearDay()-1) + (hour-12)/24)
+	eqTime = 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	decl = 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+	return
+}
+
+// SolarElevation 返回 t 时太阳的高度角，单位是度
+func SolarElevation(t time.Time, pos GeoPosition) float64 {
+	eqTime, decl := solarParams(t)
+	utc := t.UTC()
+	minutes := float64(utc.Hour()*60+utc.Minute()) + float64(utc.Second())/60
+	// 真太阳时
+	trueSolarTime := minutes + eqTime + 4*pos.Longitude
+	ha := (trueSolarTime/4 - 180) * rad
+	lat := pos.Latitude * rad
+	cosZenith := math.Sin(lat)*math.Sin(decl) + math.Cos(lat)*math.Cos(decl)*math.Cos(ha)
+	cosZenith = math.Max(-1, math.Min(1, cosZenith))
+	return 90 - math.Acos(cosZenith)/rad
+}
+
+// SunTimes 计算 date 那天的日出日落时间，极昼极夜时 ok 为 false。
+func SunTimes(date time.Time, pos GeoPosition) (sunrise, sunset time.Time, ok bool) {
+	eqTime, decl := solarParams(date)
+	lat := pos.Latitude * rad
+	cosHa := math.Cos(90.833*rad)/(math.Cos(lat)*math.Cos(decl)) - math.Tan(lat)*math.Tan(decl)
+	if cosHa < -1 || cosHa > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	ha := math.Acos(cosHa) / rad
+
+	year, month, day := date.Date()
+	base := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	toTime := func(minutes float64) time.Time {
+		return base.Add(time.Duration(minutes * float64(time.Minute))).In(date.Location())
+	}
+	sunrise = toTime(720 - 4*(pos.Longitude+ha) - eqTime)
+	sunset = toTime(720 - 4*(pos.Longitude-ha) - eqTime)
+	return sunrise, sunset, true
+}
+
+func getMidnight(day time.Time) time.Time {
+	year, month, date := day.Date()
+	return time.Date(year, month, date, 0, 0, 0, 0, day.Location())
+}
+
+// getSunTimesOfDay 返回 day 那天的日出日落时间，pos 为 nil 或者极昼极夜时返回默认的时间，ok 为 false
+func getSunTimesOfDay(day time.Time, pos *GeoPosition) (sunrise, sunset time.Time, ok bool) {
+	midnight := getMidnight(day)
+	if pos != nil {
+		sunrise, sunset, ok = SunTimes(midnight.Add(12*time.Hour), *pos)
+		if ok {
+			return
+		}
+	}
+	return midnight.Add(defaultSunriseMinutes * time.Minute),
+		midnight.Add(defaultSunsetMinutes * time.Minute), false
+}
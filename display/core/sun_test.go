@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSunTimes(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 北京夏至，日出约 4:46，日落约 19:46
+	date := time.Date(2023, time.June, 21, 12, 0, 0, 0, loc)
+	sunrise, sunset, ok := SunTimes(date, GeoPosition{Latitude: 39.9, Longitude: 116.4})
+	require.True(t, ok)
+	assert.InDelta(t, 4*60+46, sunrise.Hour()*60+sunrise.Minute(), 5)
+	assert.InDelta(t, 19*60+46, sunset.Hour()*60+sunset.Minute(), 5)
+
+	// 北极圈内的极昼
+	_, _, ok = SunTimes(date, GeoPosition{Latitude: 78, Longitude: 15})
+	assert.False(t, ok)
+}
+
+func TestSolarElevation(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	pos := GeoPosition{Latitude: 39.9, Longitude: 116.4}
+	// 北京夏至正午，高度角约 73.5 度
+	noon := time.Date(2023, time.June, 21, 12, 14, 0, 0, loc)
+	assert.InDelta(t, 73.5, SolarElevation(noon, pos), 1)
+	// 午夜在地平线以下
+	midnight := time.Date(2023, time.June, 21, 0, 0, 0, 0, loc)
+	assert.Less(t, SolarElevation(midnight, pos), 0.0)
+	// 日出时接近地平线
+	sunrise, _, ok := SunTimes(noon, pos)
+	require.True(t, ok)
+	assert.InDelta(t, -0.833, SolarElevation(sunrise, pos), 0.5)
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"encoding/json"
+)
+
+// TouchscreenMapValue 触摸屏映射到的显示器，保存在 map-output 中，键是触摸屏的 uuid
+type TouchscreenMapValue struct {
+	OutputName string
+	Auto       bool
+	// 显示器的 uuid，旧配置中没有
+	MonitorUuid string `json:",omitempty"`
+}
+
+// ParseTouchscreenMap 解析 map-output 的值，
+// 也支持 Wayland 显示服务以前保存的触摸屏到接口名的映射。
+func ParseTouchscreenMap(value string) (map[string]TouchscreenMapValue, error) {
+	result := make(map[string]TouchscreenMapValue)
+	if value == "" {
+		return result, nil
+	}
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal([]byte(value), &raw)
+	if err != nil {
+		return result, err
+	}
+	for touch, data := range raw {
+		var v TouchscreenMapValue
+		err = json.Unmarshal(data, &v)
+		if err != nil {
+			var outputName string
+			if json.Unmarshal(data, &outputName) != nil {
+				return make(map[string]TouchscreenMapValue), err
+			}
+			v = TouchscreenMapValue{OutputName: outputName}
+		}
+		result[touch] = v
+	}
+	return result, nil
+}
+
+// GetTouchscreenMapMonitor 返回映射配置中的显示器，优先用 uuid 查找，旧配置中只有接口名
+func GetTouchscreenMapMonitor(v TouchscreenMapValue, monitors []Monitor) (Monitor, bool) {
+	for _, monitor := range monitors {
+		if v.MonitorUuid != "" {
+			if monitor.UUID == v.MonitorUuid || (monitor.UUIDV0 != "" && monitor.UUIDV0 == v.MonitorUuid) {
+				return monitor, true
+			}
+		} else if monitor.Name == v.OutputName {
+			return monitor, true
+		}
+	}
+	return Monitor{}, false
+}
+
+// GetTouchMap 返回已连接的触摸屏到显示器接口名的映射
+func GetTouchMap(touchscreenMap map[string]TouchscreenMapValue, touchscreens []string, monitors []Monitor) map[string]string {
+	touchMap := make(map[string]string)
+	for _, touch := range touchscreens {
+		v, ok := touchscreenMap[touch]
+		if !ok {
+			continue
+		}
+		if monitor, ok := GetTouchscreenMapMonitor(v, monitors); ok {
+			touchMap[touch] = monitor.Name
+		}
+	}
+	return touchMap
+}
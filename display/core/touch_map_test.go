@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseTouchscreenMap(t *testing.T) {
+	m, err := ParseTouchscreenMap(`{"touch1":{"OutputName":"DP-1","Auto":true,"MonitorUuid":"uuid1"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TouchscreenMapValue{
+		"touch1": {OutputName: "DP-1", Auto: true, MonitorUuid: "uuid1"},
+	}, m)
+
+	// Wayland 显示服务以前保存的格式
+	m, err = ParseTouchscreenMap(`{"touch2":"HDMI-1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]TouchscreenMapValue{
+		"touch2": {OutputName: "HDMI-1"},
+	}, m)
+
+	m, err = ParseTouchscreenMap("")
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+
+	_, err = ParseTouchscreenMap(`{"touch3":1}`)
+	assert.Error(t, err)
+}
+
+func Test_GetTouchMap(t *testing.T) {
+	monitors := []Monitor{
+		{ID: 1, Name: "DP-2", UUID: "touch-monitor"},
+		{ID: 2, Name: "HDMI-1", UUID: "other", UUIDV0: "other-v0"},
+	}
+	touchscreenMap := map[string]TouchscreenMapValue{
+		// 显示器从 DP-1 换到了 DP-2
+		"touch1": {OutputName: "DP-1", MonitorUuid: "touch-monitor"},
+		// 旧配置只有接口名
+		"touch2": {OutputName: "HDMI-1"},
+		"touch3": {OutputName: "HDMI-1", MonitorUuid: "gone"},
+		"touch4": {OutputName: "DP-1", MonitorUuid: "other-v0"},
+	}
+	assert.Equal(t, map[string]string{
+		"touch1": "DP-2",
+		"touch2": "HDMI-1",
+		"touch4": "HDMI-1",
+	}, GetTouchMap(touchscreenMap, []string{"touch1", "touch2", "touch3", "touch4", "touch5"}, monitors))
+}
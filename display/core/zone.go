@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ZoneTabFile 包含每个时区的经纬度
+const ZoneTabFile = "/usr/share/zoneinfo/zone1970.tab"
+
+func convertPos(pos string, digits int32) float64 {
+	if len(pos) < 4 || digits > 9 {
+		return 0.0
+	}
+
+	integer := pos[:digits+1]
+	fraction := pos[digits+1:]
+	t1, _ := strconv.ParseFloat(integer, 64)
+	t2, _ := strconv.ParseFloat(fraction, 64)
+	if t1 > 0.0 {
+		return t1 + t2/math.Pow(10.0, float64(len(fraction)))
+	} else {
+		return t1 - t2/math.Pow(10.0, float64(len(fraction)))
+	}
+}
+
+// ParseZoneTab 解析 zone1970.tab 的内容，返回时区名称到经纬度的映射
+func ParseZoneTab(contents []byte) map[string]GeoPosition {
+	positions := make(map[string]GeoPosition)
+	lines := bytes.Split(contents, []byte{'\n'})
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte{'#'}) {
+			continue
+		}
+		parts := bytes.Split(line, []byte{'\t'})
+		if len(parts) < 3 || len(parts[1]) < 4 {
+			continue
+		}
+		coordinates := string(parts[1])
+		index := strings.Index(coordinates[3:], "+")
+		if index == -1 {
+			index = strings.Index(coordinates[3:], "-")
+		}
+		if index > -1 {
+			positions[string(parts[2])] = GeoPosition{
+				Latitude:  convertPos(coordinates[:index+3], 2),
+				Longitude: convertPos(coordinates[index+3:], 3),
+			}
+		}
+	}
+	return positions
+}
+
+// LoadZonePositions 读取 ZoneTabFile 中每个时区的经纬度
+func LoadZonePositions() (map[string]GeoPosition, error) {
+	contents, err := os.ReadFile(ZoneTabFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseZoneTab(contents), nil
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseZoneTab(t *testing.T) {
+	positions := ParseZoneTab([]byte("# comment\n" +
+		"CN\t+3114+12128\tAsia/Shanghai\tBeijing Time\n" +
+		"US\t+404251-0740023\tAmerica/New_York\tEastern (most areas)\n" +
+		"invalid\n"))
+	assert.Len(t, positions, 2)
+	assert.InDelta(t, 31.14, positions["Asia/Shanghai"].Latitude, 1e-9)
+	assert.InDelta(t, 121.28, positions["Asia/Shanghai"].Longitude, 1e-9)
+	assert.InDelta(t, 40.4251, positions["America/New_York"].Latitude, 1e-9)
+	assert.InDelta(t, -74.0023, positions["America/New_York"].Longitude, 1e-9)
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 自定义模式，用于 EDID 缺失或者不完整的显示器，比如一些 KVM 和采集卡只能得到 1024x768。
+// 模式按 CVT 标准计算时序后通过 RandR 创建并添加到 output 上，
+// 按显示器的 uuid 保存在用户配置的 Monitors 中，显示器重新连接后自动添加。
+
+// CustomMode 用户添加的自定义模式
+type CustomMode struct {
+	Width           uint16
+	Height          uint16
+	Rate            float64
+	ReducedBlanking bool
+}
+
+// addUniqueCustomMode 把 mode 加到 modes 中，已经存在时不重复添加
+func addUniqueCustomMode(modes []CustomMode, mode CustomMode) []CustomMode {
+	for _, m := range modes {
+		if m == mode {
+			return modes
+		}
+	}
+	return append(modes, mode)
+}
+
+// restoreCustomModes 把保存的自定义模式添加到显示器上
+func (m *Manager) restoreCustomModes(monitor *Monitor) {
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	name := monitor.Name
+	monitor.PropsMu.RUnlock()
+
+	for _, customMode := range m.getUserMonitorConfig(uuid).CustomModes {
+		mode, err := core.CalcCVTMode(customMode.Width, customMode.Height, customMode.Rate, customMode.ReducedBlanking)
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		err = m.mm.addCustomMode(monitor.ID, mode)
+		if err != nil {
+			logger.Warningf("failed to add custom mode %v to %v: %v", mode.Name, name, err)
+		}
+	}
+}
+
+func (m *Manager) restoreMonitorsCustomModes() {
+	for _, monitor := range m.getConnectedMonitors() {
+		m.restoreCustomModes(monitor)
+	}
+}
+
+// dbus 上导出的方法
+func (m *Manager) addMonitorCustomMode(monitor *Monitor, customMode CustomMode) error {
+	mode, err := core.CalcCVTMode(customMode.Width, customMode.Height, customMode.Rate, customMode.ReducedBlanking)
+	if err != nil {
+		return err
+	}
+	err = m.mm.addCustomMode(monitor.ID, mode)
+	if err != nil {
+		return err
+	}
+
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	monitor.PropsMu.RUnlock()
+	return m.updateUserMonitorConfig(uuid, func(cfg *UserMonitorConfig) {
+		cfg.CustomModes = addUniqueCustomMode(cfg.CustomModes, customMode)
+	})
+}
@@ -76,8 +76,8 @@ func (h *scaleFactorsHelper) SetChangedCb(fn func(factors map[string]float64) er
 
 func (m *Manager) setScaleFactors(factors map[string]float64) error {
 	logger.Debug("setScaleFactors", factors)
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
 
 	if reflect.DeepEqual(m.sysConfig.Config.ScaleFactors, factors) {
 		return nil
@@ -128,6 +128,10 @@ func Start(service *dbusutil.Service) error {
 				err = m.setColorTempMode(mode)
 				return dbusutil.ToError(err)
 			})
+			err = so.SetWriteCallback(m, "RotationLocked", m.writeRotationLocked)
+			if err != nil {
+				logger.Warning(err)
+			}
 		}
 
 		err = service.RequestName(dbusServiceName)
@@ -146,10 +150,13 @@ func StartPart2() error {
 	m := _dpy
 	m.initSysDisplay()
 	m.initTouchscreens()
+	m.initTablets()
 
 	if !_greeterMode {
 		controlRedshift("disable")
 		m.applyColorTempConfig(m.DisplayMode)
+		m.startBrightnessSchedule()
+		m.startAdaptiveBrightness()
 	}
 
 	return nil
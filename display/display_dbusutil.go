@@ -92,6 +92,15 @@ func (v *Manager) emitPropChangedTouchMap(value map[string]string) error {
 	return v.service.EmitPropertyChanged(v, "TouchMap", value)
 }
 
+func (v *Manager) setPropTablets(value []Tablet) {
+	v.Tablets = value
+	v.emitPropChangedTablets(value)
+}
+
+func (v *Manager) emitPropChangedTablets(value []Tablet) error {
+	return v.service.EmitPropertyChanged(v, "Tablets", value)
+}
+
 func (v *Manager) setPropCurrentCustomId(value string) (changed bool) {
 	if v.CurrentCustomId != value {
 		v.CurrentCustomId = value
@@ -235,6 +244,67 @@ func (v *Manager) emitPropChangedCustomColorTempTimePeriod(value string) error {
 	return v.service.EmitPropertyChanged(v, "CustomColorTempTimePeriod", value)
 }
 
+func (v *Manager) setPropBrightnessSchedule(value string) (changed bool) {
+	if v.BrightnessSchedule != value {
+		v.BrightnessSchedule = value
+		v.emitPropChangedBrightnessSchedule(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedBrightnessSchedule(value string) error {
+	return v.service.EmitPropertyChanged(v, "BrightnessSchedule", value)
+}
+
+func (v *Manager) setPropAdaptiveBrightness(value bool) (changed bool) {
+	if v.AdaptiveBrightness != value {
+		v.AdaptiveBrightness = value
+		v.emitPropChangedAdaptiveBrightness(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedAdaptiveBrightness(value bool) error {
+	return v.service.EmitPropertyChanged(v, "AdaptiveBrightness", value)
+}
+
+func (v *Manager) setPropRotationLocked(value bool) (changed bool) {
+	if v.RotationLocked != value {
+		v.RotationLocked = value
+		v.emitPropChangedRotationLocked(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedRotationLocked(value bool) error {
+	return v.service.EmitPropertyChanged(v, "RotationLocked", value)
+}
+
+func (v *Manager) setPropAllowedOrientations(value []string) {
+	v.AllowedOrientations = value
+	v.emitPropChangedAllowedOrientations(value)
+}
+
+func (v *Manager) emitPropChangedAllowedOrientations(value []string) error {
+	return v.service.EmitPropertyChanged(v, "AllowedOrientations", value)
+}
+
+func (v *Manager) setPropPosture(value string) (changed bool) {
+	if v.Posture != value {
+		v.Posture = value
+		v.emitPropChangedPosture(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedPosture(value string) error {
+	return v.service.EmitPropertyChanged(v, "Posture", value)
+}
+
 func (v *Monitor) setPropID(value uint32) (changed bool) {
 	if v.ID != value {
 		v.ID = value
@@ -521,6 +591,45 @@ func (v *Monitor) emitPropChangedCurrentRotateMode(value uint8) error {
 	return v.service.EmitPropertyChanged(v, "CurrentRotateMode", value)
 }
 
+func (v *Monitor) setPropColorTemperatureEnabled(value bool) (changed bool) {
+	if v.ColorTemperatureEnabled != value {
+		v.ColorTemperatureEnabled = value
+		v.emitPropChangedColorTemperatureEnabled(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedColorTemperatureEnabled(value bool) error {
+	return v.service.EmitPropertyChanged(v, "ColorTemperatureEnabled", value)
+}
+
+func (v *Monitor) setPropColorTemperatureOffset(value int32) (changed bool) {
+	if v.ColorTemperatureOffset != value {
+		v.ColorTemperatureOffset = value
+		v.emitPropChangedColorTemperatureOffset(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedColorTemperatureOffset(value int32) error {
+	return v.service.EmitPropertyChanged(v, "ColorTemperatureOffset", value)
+}
+
+func (v *Monitor) setPropColorProfile(value string) (changed bool) {
+	if v.ColorProfile != value {
+		v.ColorProfile = value
+		v.emitPropChangedColorProfile(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedColorProfile(value string) error {
+	return v.service.EmitPropertyChanged(v, "ColorProfile", value)
+}
+
 func (v *Monitor) setPropCurrentMode(value ModeInfo) (changed bool) {
 	if v.CurrentMode != value {
 		v.CurrentMode = value
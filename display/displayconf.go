@@ -5,59 +5,57 @@
 package display
 
 import (
-	"reflect"
-	"sort"
 	"strings"
-	"sync"
-	"time"
+
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 const (
-	sysConfigVersion  = "1.0"
+	sysConfigVersion  = core.SysConfigVersion
 	userConfigVersion = "1.0"
 )
 
-type SysRootConfig struct {
-	mu       sync.Mutex
-	Version  string
-	Config   SysConfig
-	UpdateAt string
-}
-
-func (c *SysRootConfig) copyFrom(newSysConfig *SysRootConfig) {
-	c.mu.Lock()
-
-	c.Version = newSysConfig.Version
-	c.Config = newSysConfig.Config
-	c.UpdateAt = newSysConfig.UpdateAt
-
-	c.mu.Unlock()
-}
-
-// SysConfig v1
-type SysConfig struct {
-	DisplayMode  byte
-	Screens      map[string]*SysScreenConfig
-	ScaleFactors map[string]float64 // 缩放比例
-	FillModes    map[string]string  // key 是特殊的 fillMode Key
-	Cache        SysCache
-}
-
-type SysCache struct {
-	BuiltinMonitor string
-	ConnectTime    map[string]time.Time
-}
+// 系统级配置的格式在 core 包中定义，和 Wayland 的显示服务共用。
+type (
+	SysRootConfig        = core.SysRootConfig
+	SysConfig            = core.SysConfig
+	SysCache             = core.SysCache
+	SysScreenConfig      = core.SysScreenConfig
+	SysProfileConfig     = core.SysProfileConfig
+	SysMonitorModeConfig = core.SysMonitorModeConfig
+	SysMonitorConfig     = core.SysMonitorConfig
+	SysMonitorConfigs    = core.SysMonitorConfigs
+)
 
 // UserConfig v1
 type UserConfig struct {
 	Version string
 	Screens map[string]UserScreenConfig
+	// 键是显示器的 uuid
+	Monitors map[string]*UserMonitorConfig `json:",omitempty"`
+}
+
+// UserMonitorConfig 用户对单个显示器的设置，不随显示模式改变
+type UserMonitorConfig struct {
+	ColorTemperatureEnabled bool
+	ColorTemperatureOffset  int32
+	// ICC 配置文件的路径
+	ColorProfile string `json:",omitempty"`
+	// 自定义模式，显示器连接时自动添加
+	CustomModes []CustomMode `json:",omitempty"`
 }
 
 func (cfg *UserConfig) fix() {
 	for _, screenConfig := range cfg.Screens {
 		screenConfig.fix()
 	}
+	for uuid, monitorConfig := range cfg.Monitors {
+		if monitorConfig == nil {
+			delete(cfg.Monitors, uuid)
+			continue
+		}
+		monitorConfig.fix()
+	}
 }
 
 type UserScreenConfig map[string]*UserMonitorModeConfig
@@ -134,13 +132,13 @@ func (m *Manager) setUserScreenConfig(monitorsId monitorsId, screenCfg UserScree
 // getSysScreenConfig 根据 monitorsId 参数返回不同的屏幕配置，不同 monitorsId 则屏幕配置不同。
 // monitorsId 代表了已连接了哪些显示器。
 func (m *Manager) getSysScreenConfig(monitorsId monitorsId) *SysScreenConfig {
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
 
 	screens := m.sysConfig.Config.Screens
 	screenCfg := screens[monitorsId.v1]
 	if screenCfg != nil {
-		return screenCfg.clone()
+		return screenCfg.Clone()
 	}
 
 	return &SysScreenConfig{}
@@ -203,10 +201,10 @@ func (cfg *UserConfig) updateUuid(monitors Monitors) (changed bool) {
 }
 
 func (m *Manager) updateSysConfigUuid(monitors Monitors) {
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
 
-	needSave := m.sysConfig.Config.updateUuid(monitors)
+	needSave := updateSysConfigUuid(&m.sysConfig.Config, monitors)
 	if needSave {
 		err := m.saveSysConfigNoLock("update uuid")
 		if err != nil {
@@ -216,18 +214,18 @@ func (m *Manager) updateSysConfigUuid(monitors Monitors) {
 }
 
 func (m *Manager) setSysScreenConfig(monitorsId monitorsId, screenCfg *SysScreenConfig) {
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
 
 	screens := m.sysConfig.Config.Screens
 	if screens == nil {
 		screens = make(map[string]*SysScreenConfig)
 		m.sysConfig.Config.Screens = screens
 	}
-	screens[monitorsId.v1] = screenCfg.clone()
+	screens[monitorsId.v1] = screenCfg.Clone()
 }
 
-func (cfg *SysConfig) getMonitorConfigs(monitorsId monitorsId, displayMode byte, single bool) SysMonitorConfigs {
+func getSysMonitorConfigs(cfg *SysConfig, monitorsId monitorsId, displayMode byte, single bool) SysMonitorConfigs {
 	if cfg == nil {
 		return nil
 	}
@@ -238,12 +236,12 @@ func (cfg *SysConfig) getMonitorConfigs(monitorsId monitorsId, displayMode byte,
 	}
 
 	if single {
-		return sc.getSingleMonitorConfigs()
+		return sc.GetSingleMonitorConfigs()
 	}
-	return sc.getMonitorConfigs(displayMode, sc.OnlyOneUuid)
+	return sc.GetMonitorConfigs(displayMode, sc.OnlyOneUuid)
 }
 
-func (cfg *SysConfig) updateUuid(monitors Monitors) (changed bool) {
+func updateSysConfigUuid(cfg *SysConfig, monitors Monitors) (changed bool) {
 	// 更新 screens 中的 uuid
 	screens := cfg.Screens
 	// screensAdditional 待插入 screens 的键值
@@ -264,7 +262,7 @@ func (cfg *SysConfig) updateUuid(monitors Monitors) (changed bool) {
 		if len(monitorUuids) == len(partMonitors) {
 			newMonitorsId := partMonitors.getMonitorsId()
 			screenCfgChanged := false
-			screenCfg, screenCfgChanged = screenCfg.updateUuid(monitors)
+			screenCfg, screenCfgChanged = screenCfg.UpdateUuid(monitors.uuidUpdater())
 			if newMonitorsId.v1 != monitorsId || screenCfgChanged {
 				if screensAdditional == nil {
 					screensAdditional = make(map[string]*SysScreenConfig)
@@ -376,59 +374,10 @@ func (usc UserScreenConfig) updateUuid(monitors Monitors) (outCfg UserScreenConf
 
 // SysScreenConfig 系统级屏幕配置
 // NOTE: Single 可以看作是特殊的显示模式，和 Mirror,Extend 等模式共用 SysMonitorModeConfig 结构可以保持设计上的统一，不必在乎里面有 Monitors
-type SysScreenConfig struct {
-	Mirror      *SysMonitorModeConfig            `json:",omitempty"`
-	Extend      *SysMonitorModeConfig            `json:",omitempty"`
-	Single      *SysMonitorModeConfig            `json:",omitempty"`
-	OnlyOneMap  map[string]*SysMonitorModeConfig `json:",omitempty"`
-	OnlyOneUuid string                           `json:",omitempty"`
-}
-
 func isCurrentVersionUuid(uuid string) bool {
 	return strings.HasSuffix(uuid, "|v1")
 }
 
-func (c *SysScreenConfig) updateUuid(monitors Monitors) (result *SysScreenConfig, overallChanged bool) {
-	if c == nil {
-		return nil, false
-	}
-
-	result = &SysScreenConfig{}
-
-	if c.Mirror != nil {
-		changed := false
-		result.Mirror, changed = c.Mirror.updateUuid(monitors)
-		overallChanged = overallChanged || changed
-	}
-	if c.Extend != nil {
-		changed := false
-		result.Extend, changed = c.Extend.updateUuid(monitors)
-		overallChanged = overallChanged || changed
-	}
-	if c.Single != nil {
-		changed := false
-		result.Single, changed = c.Single.updateUuid(monitors)
-		overallChanged = overallChanged || changed
-	}
-	if len(c.OnlyOneMap) > 0 {
-		result.OnlyOneMap = make(map[string]*SysMonitorModeConfig, len(c.OnlyOneMap))
-		for uuid, config := range c.OnlyOneMap {
-			changed := false
-			uuid, changed = updateUuid(uuid, monitors)
-			overallChanged = overallChanged || changed
-
-			result.OnlyOneMap[uuid], changed = config.updateUuid(monitors)
-			overallChanged = overallChanged || changed
-		}
-	}
-
-	changed := false
-	result.OnlyOneUuid, changed = updateUuid(c.OnlyOneUuid, monitors)
-	overallChanged = overallChanged || changed
-
-	return result, overallChanged
-}
-
 func updateUuid(uuid string, monitors Monitors) (newUuid string, change bool) {
 	if isCurrentVersionUuid(uuid) {
 		return uuid, false
@@ -441,121 +390,22 @@ func updateUuid(uuid string, monitors Monitors) (newUuid string, change bool) {
 	return uuid, false
 }
 
-// 更新配置中的所有 uuid 的版本
-func (c *SysMonitorModeConfig) updateUuid(monitors Monitors) (*SysMonitorModeConfig, bool) {
-	if c == nil {
-		return nil, false
-	}
-	monitorConfigs := c.Monitors
-	hasChanged := false
-	outMonitorCfgs := make(SysMonitorConfigs, 0, len(monitorConfigs))
-	for _, monitorCfg := range monitorConfigs {
-		if !isCurrentVersionUuid(monitorCfg.UUID) {
-			monitor := monitors.GetByUuid(monitorCfg.UUID)
-			if monitor != nil {
-				outMonitorCfg := *monitorCfg
-				// 更新 uuid
-				outMonitorCfg.UUID = monitor.uuid
-				hasChanged = true
-				outMonitorCfgs = append(outMonitorCfgs, &outMonitorCfg)
-			}
-		} else {
-			outMonitorCfgs = append(outMonitorCfgs, monitorCfg)
-		}
-	}
-	result := SysMonitorModeConfig{}
-	if hasChanged && len(outMonitorCfgs) == len(monitorConfigs) {
-		result.Monitors = outMonitorCfgs
-	} else {
-		// 放弃更新 uuid
-		result.Monitors = monitorConfigs
-	}
-	return &result, hasChanged
-}
-
-func (c *SysScreenConfig) clone() *SysScreenConfig {
-	if c == nil {
-		return nil
-	}
-	result := &SysScreenConfig{
-		Mirror:      c.Mirror.clone(),
-		Extend:      c.Extend.clone(),
-		Single:      c.Single.clone(),
-		OnlyOneUuid: c.OnlyOneUuid,
-	}
-	if len(c.OnlyOneMap) > 0 {
-		result.OnlyOneMap = make(map[string]*SysMonitorModeConfig, len(c.OnlyOneMap))
-		for uuid, config := range c.OnlyOneMap {
-			result.OnlyOneMap[uuid] = config.clone()
+// uuidUpdater 返回用于把配置中旧版本的 uuid 更新为当前版本的函数
+func (monitors Monitors) uuidUpdater() core.UuidUpdater {
+	return func(uuid string) (string, bool) {
+		if isCurrentVersionUuid(uuid) {
+			return uuid, true
 		}
-	}
-	return result
-}
-
-func (c *SysScreenConfig) fix() {
-	if c.Mirror != nil {
-		c.Mirror.fix()
-	}
-	if c.Extend != nil {
-		c.Extend.fix()
-	}
-	if c.Single != nil {
-		c.Single.fix()
-	}
-	for uuid, config := range c.OnlyOneMap {
-		if config != nil {
-			config.fix()
-		} else {
-			delete(c.OnlyOneMap, uuid)
+		monitor := monitors.GetByUuid(uuid)
+		if monitor == nil {
+			return uuid, false
 		}
+		return monitor.uuid, true
 	}
 }
 
-type SysMonitorModeConfig struct {
-	Monitors SysMonitorConfigs
-}
-
-func (c *SysMonitorModeConfig) fix() {
-	for _, monitor := range c.Monitors {
-		monitor.fix()
-	}
-}
-
-func (c *SysMonitorModeConfig) clone() *SysMonitorModeConfig {
-	if c == nil {
-		return nil
-	}
-	return &SysMonitorModeConfig{
-		Monitors: c.Monitors.clone(),
-	}
-}
-
-type SysMonitorConfig struct {
-	UUID        string
-	Name        string
-	Enabled     bool
-	X           int16
-	Y           int16
-	Width       uint16
-	Height      uint16
-	Rotation    uint16
-	Reflect     uint16
-	RefreshRate float64
-	Brightness  float64
-	Primary     bool
-}
-
-func (c *SysMonitorConfig) fix() {
-	// c.Enable 为 false，但 c.Primary 为 true 的，错误情况
-	if !c.Enabled && c.Primary {
-		c.Primary = false
-	}
-	if !isValidBrightness(c.Brightness) {
-		c.Brightness = 1
-	}
-}
-
-func (c *SysMonitorConfig) modify(changes monitorChanges) {
+// modifySysMonitorConfig 把显示器属性的修改应用到配置中
+func modifySysMonitorConfig(c *SysMonitorConfig, changes monitorChanges) {
 	for name, value := range changes {
 		var ok bool
 		switch name {
@@ -585,166 +435,3 @@ func (c *SysMonitorConfig) modify(changes monitorChanges) {
 		}
 	}
 }
-
-func isValidBrightness(value float64) bool {
-	// 不含 0
-	return value > 0 && value <= 1
-}
-
-type SysMonitorConfigs []*SysMonitorConfig
-
-func (cfgs SysMonitorConfigs) clone() SysMonitorConfigs {
-	if cfgs == nil {
-		return nil
-	}
-	result := make(SysMonitorConfigs, len(cfgs))
-	for i, config := range cfgs {
-		configCp := *config
-		result[i] = &configCp
-	}
-	return result
-}
-
-func (s *SysScreenConfig) getSingleMonitorConfigs() SysMonitorConfigs {
-	if s == nil || s.Single == nil {
-		return nil
-	}
-	return s.Single.Monitors
-}
-
-func (s *SysScreenConfig) getMonitorConfigs(mode uint8, uuid string) SysMonitorConfigs {
-	if s == nil {
-		return nil
-	}
-	switch mode {
-	case DisplayModeMirror:
-		if s.Mirror == nil {
-			return nil
-		}
-		return s.Mirror.Monitors
-
-	case DisplayModeExtend:
-		if s.Extend == nil {
-			return nil
-		}
-		return s.Extend.Monitors
-
-	case DisplayModeOnlyOne:
-		if uuid == "" {
-			return nil
-		}
-		config := s.OnlyOneMap[uuid]
-		if config != nil {
-			return config.Monitors
-		}
-	}
-
-	return nil
-}
-
-func (s *SysScreenConfig) setSingleMonitorConfigs(configs SysMonitorConfigs) {
-	if s.Single == nil {
-		s.Single = &SysMonitorModeConfig{}
-	}
-	s.Single.Monitors = configs
-}
-
-func (s *SysScreenConfig) setMonitorConfigs(mode uint8, uuid string, configs SysMonitorConfigs) {
-	switch mode {
-	case DisplayModeMirror:
-		if s.Mirror == nil {
-			s.Mirror = &SysMonitorModeConfig{}
-		}
-		s.Mirror.Monitors = configs
-
-	case DisplayModeExtend:
-		if s.Extend == nil {
-			s.Extend = &SysMonitorModeConfig{}
-		}
-		s.Extend.Monitors = configs
-
-	case DisplayModeOnlyOne:
-		s.setMonitorConfigsOnlyOne(uuid, configs)
-	}
-}
-
-func (s *SysScreenConfig) setMonitorConfigsOnlyOne(uuid string, configs SysMonitorConfigs) {
-	if uuid == "" {
-		return
-	}
-	if s.OnlyOneMap == nil {
-		s.OnlyOneMap = make(map[string]*SysMonitorModeConfig)
-	}
-
-	if s.OnlyOneMap[uuid] == nil {
-		s.OnlyOneMap[uuid] = &SysMonitorModeConfig{}
-	}
-
-	if len(configs) > 1 {
-		// 去除非使能的 monitor
-		var tmpCfg *SysMonitorConfig
-		for _, config := range configs {
-			if config.Enabled {
-				tmpCfg = config
-				break
-			}
-		}
-		if tmpCfg != nil {
-			configs = SysMonitorConfigs{tmpCfg}
-		}
-	}
-
-	s.OnlyOneMap[uuid].Monitors = configs
-}
-
-func (cfgs SysMonitorConfigs) getByUuid(uuid string) *SysMonitorConfig {
-	for _, mc := range cfgs {
-		if uuid == mc.UUID {
-			return mc
-		}
-	}
-	return nil
-}
-
-func (cfgs SysMonitorConfigs) setPrimary(uuid string) {
-	for _, mc := range cfgs {
-		if mc.UUID == uuid {
-			mc.Primary = true
-		} else {
-			mc.Primary = false
-		}
-	}
-}
-
-// cfgs 和 otherCfgs 之间是否仅亮度不同
-// 前置条件：cfgs 和 otherCfgs 不相同
-func (cfgs SysMonitorConfigs) onlyBrNotEq(otherCfgs SysMonitorConfigs) bool {
-	if len(cfgs) != len(otherCfgs) {
-		return false
-	}
-	// 除了亮度设置为 0， 其他字段都复制
-	partCpCfgs := func(cfgs SysMonitorConfigs) SysMonitorConfigs {
-		copyCfgs := make(SysMonitorConfigs, len(cfgs))
-		for i, cfg := range cfgs {
-			cpCfg := &SysMonitorConfig{}
-			*cpCfg = *cfg
-			cpCfg.Brightness = 0
-			copyCfgs[i] = cpCfg
-		}
-		return copyCfgs
-	}
-
-	c1 := partCpCfgs(cfgs)
-	c2 := partCpCfgs(otherCfgs)
-	// 把亮度都安全的设置为0, 如果 c1 和 c2 是相同的，则可以说明是仅亮度不同。
-	if reflect.DeepEqual(c1, c2) {
-		return true
-	}
-	return false
-}
-
-func (cfgs SysMonitorConfigs) sort() {
-	sort.Slice(cfgs, func(i, j int) bool {
-		return strings.Compare(cfgs[i].UUID, cfgs[j].UUID) < 0
-	})
-}
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// 显示事件记录，用于排查插拔、唤醒后布局错误等问题。
+
+const defaultEventHistorySize = 200
+
+const (
+	eventMonitorConnected    = "connected"
+	eventMonitorDisconnected = "disconnected"
+	eventMonitorsIdChanged   = "monitors-id-changed"
+	eventConfigApplied       = "config-applied"
+	eventApplyFailed         = "apply-failed"
+	eventPrimaryChanged      = "primary-changed"
+)
+
+type displayEvent struct {
+	Time        time.Time
+	Type        string
+	Name        string            `json:",omitempty"`
+	UUID        string            `json:",omitempty"`
+	MonitorsId  string            `json:",omitempty"`
+	DisplayMode byte              `json:",omitempty"`
+	Configs     SysMonitorConfigs `json:",omitempty"`
+	Detail      string            `json:",omitempty"`
+}
+
+// displayEventHistory 固定大小的环形缓冲区，写满后覆盖最旧的事件。
+type displayEventHistory struct {
+	mu     sync.Mutex
+	size   int
+	events []displayEvent
+	next   int
+	full   bool
+}
+
+func (h *displayEventHistory) add(ev displayEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.events == nil {
+		size := h.size
+		if size <= 0 {
+			size = defaultEventHistorySize
+		}
+		h.events = make([]displayEvent, size)
+	}
+	h.events[h.next] = ev
+	h.next++
+	if h.next == len(h.events) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// list 按时间先后返回所有事件
+func (h *displayEventHistory) list() []displayEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var result []displayEvent
+	if h.full {
+		result = append(result, h.events[h.next:]...)
+	}
+	result = append(result, h.events[:h.next]...)
+	return result
+}
+
+func (m *Manager) addEvent(ev displayEvent) {
+	logger.Debugf("display event %s %s", ev.Type, ev.Name)
+	m.eventHistory.add(ev)
+}
+
+func (m *Manager) getEventHistory() (string, error) {
+	data, err := json.Marshal(m.eventHistory.list())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type monitorDumpState struct {
+	ID              uint32
+	Name            string
+	UUID            string
+	Connected       bool
+	Manufacturer    string
+	Model           string
+	MmWidth         uint32
+	MmHeight        uint32
+	Enabled         bool
+	X               int16
+	Y               int16
+	Width           uint16
+	Height          uint16
+	Rotation        uint16
+	Reflect         uint16
+	RefreshRate     float64
+	Brightness      float64
+	CurrentMode     ModeInfo
+	CurrentFillMode string
+	Modes           []ModeInfo
+	Changes         monitorChanges `json:",omitempty"`
+}
+
+type displayDumpState struct {
+	Time            time.Time
+	MonitorsId      string
+	DisplayMode     byte
+	Primary         string
+	PrimaryRect     x.Rectangle
+	ScreenWidth     uint16
+	ScreenHeight    uint16
+	HasChanged      bool
+	CurrentCustomId string
+	BuiltinMonitor  string
+	Monitors        []monitorDumpState
+	SysConfig       json.RawMessage
+	Events          []displayEvent
+}
+
+// dumpState 收集当前显示状态的快照，包括各个显示器的状态、系统级配置和事件记录。
+func (m *Manager) dumpState() (string, error) {
+	state := displayDumpState{
+		Time: time.Now(),
+	}
+
+	m.monitorsIdMu.Lock()
+	state.MonitorsId = m.monitorsId.v1
+	m.monitorsIdMu.Unlock()
+
+	m.PropsMu.RLock()
+	state.DisplayMode = m.DisplayMode
+	state.Primary = m.Primary
+	state.PrimaryRect = m.PrimaryRect
+	state.ScreenWidth = m.ScreenWidth
+	state.ScreenHeight = m.ScreenHeight
+	state.HasChanged = m.HasChanged
+	state.CurrentCustomId = m.CurrentCustomId
+	m.PropsMu.RUnlock()
+
+	builtinMonitor := m.getBuiltinMonitor()
+	if builtinMonitor != nil {
+		state.BuiltinMonitor = builtinMonitor.Name
+	}
+
+	monitors := m.getConnectedMonitors()
+	sort.Slice(monitors, func(i, j int) bool {
+		return monitors[i].ID < monitors[j].ID
+	})
+	for _, monitor := range monitors {
+		monitor.PropsMu.RLock()
+		state.Monitors = append(state.Monitors, monitorDumpState{
+			ID:              monitor.ID,
+			Name:            monitor.Name,
+			UUID:            monitor.uuid,
+			Connected:       monitor.Connected,
+			Manufacturer:    monitor.Manufacturer,
+			Model:           monitor.Model,
+			MmWidth:         monitor.MmWidth,
+			MmHeight:        monitor.MmHeight,
+			Enabled:         monitor.Enabled,
+			X:               monitor.X,
+			Y:               monitor.Y,
+			Width:           monitor.Width,
+			Height:          monitor.Height,
+			Rotation:        monitor.Rotation,
+			Reflect:         monitor.Reflect,
+			RefreshRate:     monitor.RefreshRate,
+			Brightness:      monitor.Brightness,
+			CurrentMode:     monitor.CurrentMode,
+			CurrentFillMode: monitor.CurrentFillMode,
+			Modes:           monitor.Modes,
+			Changes:         monitor.changes.clone(),
+		})
+		monitor.PropsMu.RUnlock()
+	}
+
+	m.sysConfig.Mu.Lock()
+	state.SysConfig = json.RawMessage(jsonMarshal(&m.sysConfig))
+	m.sysConfig.Mu.Unlock()
+
+	state.Events = m.eventHistory.list()
+
+	data, err := json.Marshal(&state)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_displayEventHistory(t *testing.T) {
+	h := displayEventHistory{size: 3}
+	assert.Empty(t, h.list())
+
+	for i := 0; i < 2; i++ {
+		h.add(displayEvent{Type: eventMonitorConnected, Name: fmt.Sprint(i)})
+	}
+	events := h.list()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "0", events[0].Name)
+	assert.False(t, events[0].Time.IsZero())
+
+	// 写满后覆盖最旧的事件
+	for i := 2; i < 5; i++ {
+		h.add(displayEvent{Type: eventMonitorConnected, Name: fmt.Sprint(i)})
+	}
+	events = h.list()
+	assert.Len(t, events, 3)
+	for i, ev := range events {
+		assert.Equal(t, fmt.Sprint(i+2), ev.Name)
+	}
+}
+
+func (s *FakeDisplayTestSuite) getEventTypes() []string {
+	var result []string
+	for _, ev := range s.m.eventHistory.list() {
+		result = append(result, ev.Type)
+	}
+	return result
+}
+
+func (s *FakeDisplayTestSuite) TestEventHistory() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeMirror)
+	s.Contains(s.getEventTypes(), eventConfigApplied)
+	s.Contains(s.getEventTypes(), eventPrimaryChanged)
+
+	s.mm.failNextApply(errors.New("fake apply failed"))
+	s.Error(s.m.switchMode(DisplayModeExtend, ""))
+	events := s.m.eventHistory.list()
+	last := events[len(events)-1]
+	s.Equal(eventApplyFailed, last.Type)
+	s.Equal(DisplayModeExtend, last.DisplayMode)
+	s.Contains(last.Detail, "fake apply failed")
+
+	s.Require().NoError(s.mm.hotplug("HDMI-1", false))
+	s.Eventually(func() bool {
+		types := s.getEventTypes()
+		return types[len(types)-1] == eventConfigApplied
+	}, 3*time.Second, 50*time.Millisecond)
+	types := s.getEventTypes()
+	s.Contains(types, eventMonitorDisconnected)
+	s.Contains(types, eventMonitorsIdChanged)
+
+	history, err := s.m.getEventHistory()
+	s.Require().NoError(err)
+	s.Contains(history, `"Type":"disconnected","Name":"HDMI-1"`)
+
+	state, err := s.m.dumpState()
+	s.Require().NoError(err)
+	s.Contains(state, `"Name":"eDP-1"`)
+	s.Contains(state, `"SysConfig":{`)
+	s.Contains(state, `"Events":[`)
+}
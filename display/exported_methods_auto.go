@@ -9,8 +9,19 @@ import (
 func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 	return dbusutil.ExportedMethods{
 		{
-			Name: "ApplyChanges",
-			Fn:   v.ApplyChanges,
+			Name:    "ApplyChanges",
+			Fn:      v.ApplyChanges,
+			OutArgs: []string{"id"},
+		},
+		{
+			Name:   "ApplyProfile",
+			Fn:     v.ApplyProfile,
+			InArgs: []string{"name"},
+		},
+		{
+			Name:   "AssociateTablet",
+			Fn:     v.AssociateTablet,
+			InArgs: []string{"outputName", "uuid", "keepAspect"},
 		},
 		{
 			Name:   "AssociateTouch",
@@ -38,11 +49,36 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.ChangeBrightness,
 			InArgs: []string{"raised"},
 		},
+		{
+			Name:   "ClearTouchscreenMap",
+			Fn:     v.ClearTouchscreenMap,
+			InArgs: []string{"touchUUID"},
+		},
+		{
+			Name:   "ConfirmChanges",
+			Fn:     v.ConfirmChanges,
+			InArgs: []string{"id"},
+		},
 		{
 			Name:   "DeleteCustomMode",
 			Fn:     v.DeleteCustomMode,
 			InArgs: []string{"name"},
 		},
+		{
+			Name:   "DeleteProfile",
+			Fn:     v.DeleteProfile,
+			InArgs: []string{"name"},
+		},
+		{
+			Name:    "DumpState",
+			Fn:      v.DumpState,
+			OutArgs: []string{"state"},
+		},
+		{
+			Name:    "ExportConfig",
+			Fn:      v.ExportConfig,
+			OutArgs: []string{"config"},
+		},
 		{
 			Name:    "GetBrightness",
 			Fn:      v.GetBrightness,
@@ -53,11 +89,21 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.GetBuiltinMonitor,
 			OutArgs: []string{"outArg0", "outArg1"},
 		},
+		{
+			Name:    "GetEventHistory",
+			Fn:      v.GetEventHistory,
+			OutArgs: []string{"history"},
+		},
 		{
 			Name:    "GetRealDisplayMode",
 			Fn:      v.GetRealDisplayMode,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:   "ImportConfig",
+			Fn:     v.ImportConfig,
+			InArgs: []string{"config"},
+		},
 		{
 			Name:    "ListOutputNames",
 			Fn:      v.ListOutputNames,
@@ -68,6 +114,16 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.ListOutputsCommonModes,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "ListProfiles",
+			Fn:      v.ListProfiles,
+			OutArgs: []string{"outArg0"},
+		},
+		{
+			Name:    "ListTouchscreenMaps",
+			Fn:      v.ListTouchscreenMaps,
+			OutArgs: []string{"maps"},
+		},
 		{
 			Name:   "ModifyConfigName",
 			Fn:     v.ModifyConfigName,
@@ -77,10 +133,19 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Name: "RefreshBrightness",
 			Fn:   v.RefreshBrightness,
 		},
+		{
+			Name:   "RenameProfile",
+			Fn:     v.RenameProfile,
+			InArgs: []string{"name", "newName"},
+		},
 		{
 			Name: "Reset",
 			Fn:   v.Reset,
 		},
+		{
+			Name: "ResetAdaptiveBrightnessCurve",
+			Fn:   v.ResetAdaptiveBrightnessCurve,
+		},
 		{
 			Name: "ResetChanges",
 			Fn:   v.ResetChanges,
@@ -89,6 +154,21 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Name: "Save",
 			Fn:   v.Save,
 		},
+		{
+			Name:   "SaveProfile",
+			Fn:     v.SaveProfile,
+			InArgs: []string{"name"},
+		},
+		{
+			Name:   "SetAdaptiveBrightness",
+			Fn:     v.SetAdaptiveBrightness,
+			InArgs: []string{"enabled"},
+		},
+		{
+			Name:   "SetAllowedOrientations",
+			Fn:     v.SetAllowedOrientations,
+			InArgs: []string{"orientations"},
+		},
 		{
 			Name:   "SetAndSaveBrightness",
 			Fn:     v.SetAndSaveBrightness,
@@ -99,6 +179,11 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetBrightness,
 			InArgs: []string{"outputName", "value"},
 		},
+		{
+			Name:   "SetBrightnessSchedule",
+			Fn:     v.SetBrightnessSchedule,
+			InArgs: []string{"schedule"},
+		},
 		{
 			Name:   "SetColorTemperature",
 			Fn:     v.SetColorTemperature,
@@ -114,11 +199,21 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetMethodAdjustCCT,
 			InArgs: []string{"adjustMethod"},
 		},
+		{
+			Name:   "SetPosture",
+			Fn:     v.SetPosture,
+			InArgs: []string{"posture"},
+		},
 		{
 			Name:   "SetPrimary",
 			Fn:     v.SetPrimary,
 			InArgs: []string{"outputName"},
 		},
+		{
+			Name:   "SetTabletArea",
+			Fn:     v.SetTabletArea,
+			InArgs: []string{"uuid", "area"},
+		},
 		{
 			Name:    "SupportSetColorTemperature",
 			Fn:      v.SupportSetColorTemperature,
@@ -133,11 +228,31 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 }
 func (v *Monitor) GetExportedMethods() dbusutil.ExportedMethods {
 	return dbusutil.ExportedMethods{
+		{
+			Name:   "AddCustomMode",
+			Fn:     v.AddCustomMode,
+			InArgs: []string{"width", "height", "rate", "reducedBlanking"},
+		},
 		{
 			Name:   "Enable",
 			Fn:     v.Enable,
 			InArgs: []string{"enabled"},
 		},
+		{
+			Name:   "SetColorProfile",
+			Fn:     v.SetColorProfile,
+			InArgs: []string{"filename"},
+		},
+		{
+			Name:   "SetColorTemperatureEnabled",
+			Fn:     v.SetColorTemperatureEnabled,
+			InArgs: []string{"enabled"},
+		},
+		{
+			Name:   "SetColorTemperatureOffset",
+			Fn:     v.SetColorTemperatureOffset,
+			InArgs: []string{"offset"},
+		},
 		{
 			Name:   "SetMode",
 			Fn:     v.SetMode,
@@ -0,0 +1,570 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// fakeMonitorManager 是完全在内存中的显示器后端，不依赖 X 或者 KWin，用于无头环境和测试。
+// 它模拟 output、EDID、crtc、热插拔和应用失败，行为尽量与 xMonitorManager 保持一致：
+// output 的集合是固定的，热插拔只改变连接状态，断开时 crtc 不会被自动禁用。
+
+const envFakeMonitors = "DEEPIN_DISPLAY_FAKE_MONITORS"
+
+const (
+	fakeOutputIdBase = 0x40
+	fakeCrtcIdBase   = 0x80
+	fakeModeIdBase   = 0x100
+)
+
+const fakeAllRotations = randr.RotationRotate0 | randr.RotationRotate90 | randr.RotationRotate180 |
+	randr.RotationRotate270 | randr.RotationReflectX | randr.RotationReflectY
+
+// 模拟显示器在首选模式之外支持的常见模式
+var fakeCommonSizes = []struct {
+	width, height uint16
+}{
+	{1920, 1080},
+	{1680, 1050},
+	{1600, 900},
+	{1280, 1024},
+	{1280, 720},
+	{1024, 768},
+	{800, 600},
+}
+
+// fakeOutputSpec 模拟 output 的描述，格式为 NAME[:WxH[@RATE]]，NAME 前加 "-" 表示未连接。
+type fakeOutputSpec struct {
+	name      string
+	connected bool
+	width     uint16
+	height    uint16
+	rate      float64
+}
+
+// parseFakeOutputSpecs 解析以逗号分隔的 fakeOutputSpec，
+// 比如 "eDP-1:1920x1080@60,HDMI-1:2560x1440,-VGA-1"。
+func parseFakeOutputSpecs(str string) ([]fakeOutputSpec, error) {
+	var result []fakeOutputSpec
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		spec := fakeOutputSpec{
+			connected: true,
+			width:     1920,
+			height:    1080,
+			rate:      60,
+		}
+		if strings.HasPrefix(item, "-") {
+			spec.connected = false
+			item = item[1:]
+		}
+		name, mode, hasMode := strings.Cut(item, ":")
+		if name == "" {
+			return nil, fmt.Errorf("invalid fake output %q: empty name", item)
+		}
+		spec.name = name
+		if hasMode {
+			size, rate, hasRate := strings.Cut(mode, "@")
+			w, h, ok := strings.Cut(size, "x")
+			if !ok {
+				return nil, fmt.Errorf("invalid fake output %q: bad size", item)
+			}
+			width, err := strconv.ParseUint(w, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fake output %q: %v", item, err)
+			}
+			height, err := strconv.ParseUint(h, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fake output %q: %v", item, err)
+			}
+			spec.width = uint16(width)
+			spec.height = uint16(height)
+			if hasRate {
+				spec.rate, err = strconv.ParseFloat(rate, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid fake output %q: %v", item, err)
+				}
+			}
+		}
+		result = append(result, spec)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no fake output")
+	}
+	return result, nil
+}
+
+type fakeOutput struct {
+	id        uint32
+	name      string
+	connected bool
+	edid      []byte
+	modes     []ModeInfo
+	preferred ModeInfo
+	mmWidth   uint32
+	mmHeight  uint32
+	crtc      randr.Crtc
+	fillMode  string
+}
+
+type fakeCrtc struct {
+	id       randr.Crtc
+	x, y     int16
+	mode     ModeInfo
+	rotation uint16
+	output   uint32
+}
+
+func (c *fakeCrtc) getRect() x.Rectangle {
+	rect := x.Rectangle{
+		X:      c.x,
+		Y:      c.y,
+		Width:  c.mode.Width,
+		Height: c.mode.Height,
+	}
+	swapWidthHeightWithRotation(c.rotation, &rect.Width, &rect.Height)
+	return rect
+}
+
+type fakeMonitorManager struct {
+	hooks      monitorManagerHooks
+	mu         sync.Mutex
+	outputs    []*fakeOutput
+	crtcs      []*fakeCrtc
+	nextModeId uint32
+	primary    uint32
+	// 下一次 apply 返回的错误
+	applyErr   error
+	applyCount int
+	brightness map[uint32]float64
+	cursorShow bool
+}
+
+// newFakeMonitorManager 创建模拟后端，numCrtcs 不大于 0 时 crtc 数量与 output 数量相同。
+func newFakeMonitorManager(specs []fakeOutputSpec, numCrtcs int) *fakeMonitorManager {
+	mm := &fakeMonitorManager{
+		nextModeId: fakeModeIdBase,
+		brightness: make(map[uint32]float64),
+		cursorShow: true,
+	}
+	if numCrtcs <= 0 {
+		numCrtcs = len(specs)
+	}
+	for i := 0; i < numCrtcs; i++ {
+		mm.crtcs = append(mm.crtcs, &fakeCrtc{
+			id:       randr.Crtc(fakeCrtcIdBase + i),
+			rotation: randr.RotationRotate0,
+		})
+	}
+	for i, spec := range specs {
+		output := &fakeOutput{
+			id:        uint32(fakeOutputIdBase + i),
+			name:      spec.name,
+			connected: spec.connected,
+			edid:      newFakeEdid(spec.name, spec.width, spec.height),
+			// 按 96 dpi 计算物理尺寸
+			mmWidth:  uint32(float64(spec.width) * 25.4 / 96),
+			mmHeight: uint32(float64(spec.height) * 25.4 / 96),
+		}
+		output.modes, output.preferred = mm.newModes(spec.width, spec.height, spec.rate)
+		mm.outputs = append(mm.outputs, output)
+	}
+	return mm
+}
+
+func (mm *fakeMonitorManager) newModes(width, height uint16, rate float64) (modes []ModeInfo, preferred ModeInfo) {
+	newMode := func(w, h uint16, r float64) ModeInfo {
+		mode := ModeInfo{
+			Id:     mm.nextModeId,
+			name:   fmt.Sprintf("%dx%d", w, h),
+			Width:  w,
+			Height: h,
+			Rate:   r,
+		}
+		mm.nextModeId++
+		return mode
+	}
+	preferred = newMode(width, height, rate)
+	modes = append(modes, preferred)
+	if rate != 60 {
+		modes = append(modes, newMode(width, height, 60))
+	}
+	for _, size := range fakeCommonSizes {
+		if size.width == width && size.height == height {
+			continue
+		}
+		if size.width > width || size.height > height {
+			continue
+		}
+		modes = append(modes, newMode(size.width, size.height, 60))
+	}
+	return
+}
+
+// newFakeEdid 生成一个 128 字节的 EDID，根据 name 和尺寸区分不同的显示器，厂商是 DDE。
+func newFakeEdid(name string, width, height uint16) []byte {
+	edid := make([]byte, 128)
+	copy(edid, []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00})
+	// 厂商 ID，每个字母 5 位
+	vendor := uint16('D'-'A'+1)<<10 | uint16('D'-'A'+1)<<5 | uint16('E'-'A'+1)
+	edid[8] = byte(vendor >> 8)
+	edid[9] = byte(vendor)
+	sum := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s %dx%d", name, width, height)))
+	// 产品代码和序列号
+	edid[10] = byte(sum)
+	edid[11] = byte(sum >> 8)
+	edid[12] = byte(sum >> 16)
+	edid[13] = byte(sum >> 24)
+	edid[18] = 1
+	edid[19] = 4
+	// 显示器名称描述符
+	copy(edid[90:95], []byte{0x00, 0x00, 0x00, 0xfc, 0x00})
+	model := []byte("Fake " + name)
+	if len(model) > 13 {
+		model = model[:13]
+	}
+	n := copy(edid[95:108], model)
+	if n < 13 {
+		edid[95+n] = '\n'
+		for i := 95 + n + 1; i < 108; i++ {
+			edid[i] = ' '
+		}
+	}
+	var checksum byte
+	for _, b := range edid[:127] {
+		checksum += b
+	}
+	edid[127] = -checksum
+	return edid
+}
+
+func (mm *fakeMonitorManager) setHooks(hooks monitorManagerHooks) {
+	mm.hooks = hooks
+}
+
+func (mm *fakeMonitorManager) getOutput(id uint32) *fakeOutput {
+	for _, output := range mm.outputs {
+		if output.id == id {
+			return output
+		}
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) getOutputByName(name string) *fakeOutput {
+	for _, output := range mm.outputs {
+		if output.name == name {
+			return output
+		}
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) getCrtc(id randr.Crtc) *fakeCrtc {
+	for _, crtc := range mm.crtcs {
+		if crtc.id == id {
+			return crtc
+		}
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) toMonitorInfo(output *fakeOutput) *MonitorInfo {
+	// NOTE: 不要加锁
+	monitor := &MonitorInfo{
+		crtc:            output.crtc,
+		ID:              output.id,
+		Name:            output.name,
+		Connected:       output.connected,
+		Modes:           output.modes,
+		PreferredMode:   output.preferred,
+		MmWidth:         output.mmWidth,
+		MmHeight:        output.mmHeight,
+		EDID:            output.edid,
+		UUID:            getOutputUuid(output.name, "", output.edid),
+		UuidV0:          getOutputUuidV0(output.name, output.edid),
+		CurrentFillMode: output.fillMode,
+		Rotations:       fakeAllRotations,
+	}
+	monitor.Manufacturer, monitor.Model = parseEdid(output.edid)
+
+	crtc := mm.getCrtc(output.crtc)
+	if crtc != nil {
+		monitor.X = crtc.x
+		monitor.Y = crtc.y
+		monitor.Rotation = crtc.rotation
+		monitor.Width, monitor.Height = crtc.mode.Width, crtc.mode.Height
+		swapWidthHeightWithRotation(crtc.rotation, &monitor.Width, &monitor.Height)
+		monitor.CurrentMode = crtc.mode
+	}
+
+	if monitor.Connected && monitor.Width != 0 && monitor.Height != 0 {
+		monitor.VirtualConnected = true
+		monitor.Enabled = true
+	}
+	return monitor
+}
+
+func (mm *fakeMonitorManager) getMonitors() []*MonitorInfo {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	monitors := make([]*MonitorInfo, len(mm.outputs))
+	for i, output := range mm.outputs {
+		monitors[i] = mm.toMonitorInfo(output)
+	}
+	return monitors
+}
+
+func (mm *fakeMonitorManager) getMonitor(id uint32) *MonitorInfo {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	output := mm.getOutput(id)
+	if output == nil {
+		return nil
+	}
+	return mm.toMonitorInfo(output)
+}
+
+// failNextApply 让下一次 apply 返回 err，模拟应用失败。
+func (mm *fakeMonitorManager) failNextApply(err error) {
+	mm.mu.Lock()
+	mm.applyErr = err
+	mm.mu.Unlock()
+}
+
+func (mm *fakeMonitorManager) getApplyCount() int {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.applyCount
+}
+
+func (mm *fakeMonitorManager) apply(monitorsId monitorsId, monitorMap map[uint32]*Monitor, prevScreenSize screenSize,
+	options applyOptions, fillModes map[string]string, primaryMonitorID uint32, displayMode byte) error {
+
+	logger.Debug("fake mm apply", monitorsId)
+	mm.mu.Lock()
+	mm.applyCount++
+	if mm.applyErr != nil {
+		err := mm.applyErr
+		mm.applyErr = nil
+		mm.mu.Unlock()
+		return err
+	}
+
+	// 先检查，失败时不改变任何状态
+	freeCrtcs := make(map[randr.Crtc]bool)
+	for _, crtc := range mm.crtcs {
+		if crtc.output == 0 {
+			freeCrtcs[crtc.id] = true
+			continue
+		}
+		monitor := monitorMap[crtc.output]
+		if monitor != nil && !monitor.Enabled {
+			freeCrtcs[crtc.id] = true
+		}
+	}
+	crtcOf := make(map[uint32]randr.Crtc)
+	for id, monitor := range monitorMap {
+		output := mm.getOutput(id)
+		if output == nil {
+			mm.mu.Unlock()
+			return fmt.Errorf("invalid output %d", id)
+		}
+		if !monitor.Enabled {
+			continue
+		}
+		if findMode(output.modes, monitor.CurrentMode.Id).isZero() {
+			mm.mu.Unlock()
+			return fmt.Errorf("invalid mode %d for output %s", monitor.CurrentMode.Id, output.name)
+		}
+		crtc := output.crtc
+		if crtc == 0 {
+			for _, c := range mm.crtcs {
+				if freeCrtcs[c.id] {
+					crtc = c.id
+					break
+				}
+			}
+			if crtc == 0 {
+				mm.mu.Unlock()
+				return errors.New("failed to find free crtc")
+			}
+		}
+		delete(freeCrtcs, crtc)
+		crtcOf[id] = crtc
+	}
+
+	for id, monitor := range monitorMap {
+		output := mm.getOutput(id)
+		crtcId, enabled := crtcOf[id]
+		if !enabled {
+			if c := mm.getCrtc(output.crtc); c != nil {
+				*c = fakeCrtc{id: c.id, rotation: randr.RotationRotate0}
+			}
+			output.crtc = 0
+			continue
+		}
+		crtc := mm.getCrtc(crtcId)
+		crtc.x = monitor.X
+		crtc.y = monitor.Y
+		crtc.mode = monitor.CurrentMode
+		crtc.rotation = monitor.Rotation | monitor.Reflect
+		crtc.output = id
+		output.crtc = crtcId
+
+		fillMode := fillModes[monitor.generateFillModeKey()]
+		if displayMode == DisplayModeMirror && monitorMap[primaryMonitorID] != nil {
+			fillMode = fillModes[monitorMap[primaryMonitorID].generateFillModeKey()]
+		}
+		output.fillMode = fillMode
+	}
+
+	var monitorInfos []*MonitorInfo
+	for id := range monitorMap {
+		monitorInfos = append(monitorInfos, mm.toMonitorInfo(mm.getOutput(id)))
+	}
+	mm.mu.Unlock()
+
+	if mm.hooks != nil {
+		for _, monitorInfo := range monitorInfos {
+			mm.hooks.handleMonitorChanged(monitorInfo)
+		}
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) setMonitorPrimary(monitorId uint32) error {
+	logger.Debug("fake mm.setMonitorPrimary", monitorId)
+	mm.mu.Lock()
+	mm.primary = monitorId
+	var pmi primaryMonitorInfo
+	output := mm.getOutput(monitorId)
+	if output != nil {
+		pmi.Name = output.name
+		if crtc := mm.getCrtc(output.crtc); crtc != nil {
+			pmi.Rect = crtc.getRect()
+		}
+	}
+	mm.mu.Unlock()
+
+	if mm.hooks != nil {
+		mm.hooks.handlePrimaryRectChanged(pmi)
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) setMonitorFillMode(monitor *Monitor, fillMode string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	output := mm.getOutput(monitor.ID)
+	if output == nil {
+		return fmt.Errorf("invalid output %d", monitor.ID)
+	}
+	output.fillMode = fillMode
+	return nil
+}
+
+func (mm *fakeMonitorManager) addCustomMode(monitorId uint32, mode *core.CVTMode) error {
+	mm.mu.Lock()
+	output := mm.getOutput(monitorId)
+	if output == nil {
+		mm.mu.Unlock()
+		return fmt.Errorf("invalid output %d", monitorId)
+	}
+	for _, modeInfo := range output.modes {
+		if modeInfo.name == mode.Name {
+			mm.mu.Unlock()
+			return nil
+		}
+	}
+	output.modes = append(output.modes, ModeInfo{
+		Id:     mm.nextModeId,
+		name:   mode.Name,
+		Width:  mode.HDisplay,
+		Height: mode.VDisplay,
+		Rate:   mode.Rate(),
+	})
+	mm.nextModeId++
+	monitorInfo := mm.toMonitorInfo(output)
+	mm.mu.Unlock()
+
+	if mm.hooks != nil {
+		mm.hooks.handleMonitorChanged(monitorInfo)
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) showCursor(show bool) error {
+	mm.mu.Lock()
+	mm.cursorShow = show
+	mm.mu.Unlock()
+	return nil
+}
+
+func (mm *fakeMonitorManager) setBrightness(target brightnessTarget, value float64, temperature int) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.getOutput(target.monitorId) == nil {
+		return fmt.Errorf("invalid output %d", target.monitorId)
+	}
+	mm.brightness[target.monitorId] = value
+	return nil
+}
+
+// getBrightnessSetter 模拟的显示器都可以用背光调节亮度
+func (mm *fakeMonitorManager) getBrightnessSetter(target brightnessTarget) string {
+	if target.setter == brightness.SetterAuto {
+		return brightness.SetterBacklight
+	}
+	return target.setter
+}
+
+func (mm *fakeMonitorManager) canSetBrightness(target brightnessTarget) bool {
+	return true
+}
+
+// hotplug 模拟热插拔 output name。
+func (mm *fakeMonitorManager) hotplug(name string, connected bool) error {
+	mm.mu.Lock()
+	output := mm.getOutputByName(name)
+	if output == nil {
+		mm.mu.Unlock()
+		return fmt.Errorf("not found output %q", name)
+	}
+	if output.connected == connected {
+		mm.mu.Unlock()
+		return nil
+	}
+	output.connected = connected
+	monitorInfo := mm.toMonitorInfo(output)
+	mm.mu.Unlock()
+
+	logger.Debug("fake mm hotplug", name, connected)
+	if mm.hooks != nil {
+		mm.hooks.handleMonitorChanged(monitorInfo)
+	}
+	return nil
+}
+
+func (mm *fakeMonitorManager) HandleEvent(ev interface{}) {
+}
+
+func (mm *fakeMonitorManager) HandleScreenChanged(e *randr.ScreenChangeNotifyEvent) (cfgTsChanged bool) {
+	return false
+}
@@ -0,0 +1,426 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	sysdisplay "github.com/linuxdeepin/go-dbus-factory/system/org.deepin.dde.display1"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func Test_parseFakeOutputSpecs(t *testing.T) {
+	specs, err := parseFakeOutputSpecs("eDP-1:1366x768@59.95, HDMI-1,-VGA-1:1024x768")
+	require.NoError(t, err)
+	assert.Equal(t, []fakeOutputSpec{
+		{name: "eDP-1", connected: true, width: 1366, height: 768, rate: 59.95},
+		{name: "HDMI-1", connected: true, width: 1920, height: 1080, rate: 60},
+		{name: "VGA-1", connected: false, width: 1024, height: 768, rate: 60},
+	}, specs)
+
+	for _, spec := range []string{"", ",", "HDMI-1:1920", "HDMI-1:axb", ":1920x1080", "HDMI-1:1920x1080@x"} {
+		_, err = parseFakeOutputSpecs(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func Test_newFakeEdid(t *testing.T) {
+	edid1 := newFakeEdid("HDMI-1", 1920, 1080)
+	edid2 := newFakeEdid("HDMI-2", 1920, 1080)
+	assert.Len(t, edid1, 128)
+	assert.NotEqual(t, edid1, edid2)
+
+	var sum byte
+	for _, b := range edid1 {
+		sum += b
+	}
+	assert.Equal(t, byte(0), sum)
+
+	manufacturer, model := parseEdid(edid1)
+	assert.Equal(t, "DDE", manufacturer)
+	assert.Equal(t, "Fake", model)
+	assert.NotEqual(t, getOutputUuid("HDMI-1", "", edid1), getOutputUuid("HDMI-1", "", edid2))
+}
+
+// discardConn 丢弃所有写入的数据，用于创建不连接总线的 dbus.Conn
+type discardConn struct{}
+
+func (discardConn) Read(p []byte) (int, error)  { return 0, errors.New("not readable") }
+func (discardConn) Write(p []byte) (int, error) { return len(p), nil }
+func (discardConn) Close() error                { return nil }
+
+// fakeSysDisplay 代替系统级 display 服务保存配置
+type fakeSysDisplay struct {
+	sysdisplay.Display
+	mu       sync.Mutex
+	cfgJson  string
+	setCount int
+}
+
+func (d *fakeSysDisplay) GetConfig(flags dbus.Flags) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cfgJson, nil
+}
+
+func (d *fakeSysDisplay) SetConfig(flags dbus.Flags, cfgJson string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cfgJson = cfgJson
+	d.setCount++
+	return nil
+}
+
+func (d *fakeSysDisplay) getSavedConfig() (*SysRootConfig, error) {
+	d.mu.Lock()
+	cfgJson := d.cfgJson
+	d.mu.Unlock()
+	var cfg SysRootConfig
+	err := jsonUnmarshal(cfgJson, &cfg)
+	return &cfg, err
+}
+
+type FakeDisplayTestSuite struct {
+	suite.Suite
+	m          *Manager
+	mm         *fakeMonitorManager
+	sysDisplay *fakeSysDisplay
+
+	oldUserConfigFile string
+	oldSpecs          []fakeOutputSpec
+}
+
+func (s *FakeDisplayTestSuite) SetupTest() {
+	s.oldUserConfigFile = userConfigFile
+	s.oldSpecs = _fakeOutputSpecs
+	userConfigFile = filepath.Join(s.T().TempDir(), "display-user.json")
+}
+
+func (s *FakeDisplayTestSuite) TearDownTest() {
+	if s.m != nil && s.m.delayApplyTimer != nil {
+		s.m.delayApplyTimer.Stop()
+	}
+	userConfigFile = s.oldUserConfigFile
+	_fakeOutputSpecs = s.oldSpecs
+	s.m = nil
+	s.mm = nil
+}
+
+// start 模拟 Manager.init，使用模拟后端启动显示管理。
+func (s *FakeDisplayTestSuite) start(spec string, numCrtcs int, displayMode byte) {
+	specs, err := parseFakeOutputSpecs(spec)
+	s.Require().NoError(err)
+	_fakeOutputSpecs = specs
+
+	conn, err := dbus.NewConn(discardConn{})
+	s.Require().NoError(err)
+
+	s.mm = newFakeMonitorManager(specs, numCrtcs)
+	s.sysDisplay = &fakeSysDisplay{}
+	m := &Manager{
+		service:         dbusutil.NewService(conn),
+		monitorMap:      make(map[uint32]*Monitor),
+		Brightness:      make(map[string]float64),
+		colorTempRunner: newColorTempRunner(),
+		sysDisplay:      s.sysDisplay,
+		mm:              s.mm,
+	}
+	s.m = m
+	m.mm.setHooks(m)
+	m.sysConfig.Fix()
+	m.sysConfig.Config.DisplayMode = displayMode
+
+	monitors := m.mm.getMonitors()
+	s.Require().NoError(m.recordMonitorsConnected(monitors))
+	for _, monitor := range monitors {
+		s.Require().NoError(m.addMonitor(monitor))
+	}
+	m.monitorsId = m.getMonitorsId()
+	m.updatePropMonitors()
+	m.DisplayMode = m.sysConfig.Config.DisplayMode
+	m.applyConfig(false, nil)
+}
+
+func (s *FakeDisplayTestSuite) getMonitor(name string) *Monitor {
+	m := s.m
+	m.monitorMapMu.Lock()
+	defer m.monitorMapMu.Unlock()
+	for _, monitor := range m.monitorMap {
+		if monitor.Name == name {
+			return monitor
+		}
+	}
+	s.FailNow("not found monitor " + name)
+	return nil
+}
+
+// getCrtc 获取 output name 当前使用的 crtc，未启用时为 nil
+func (s *FakeDisplayTestSuite) getCrtc(name string) *fakeCrtc {
+	s.mm.mu.Lock()
+	defer s.mm.mu.Unlock()
+	output := s.mm.getOutputByName(name)
+	s.Require().NotNil(output)
+	crtc := s.mm.getCrtc(output.crtc)
+	if crtc == nil {
+		return nil
+	}
+	crtcCp := *crtc
+	return &crtcCp
+}
+
+func (s *FakeDisplayTestSuite) TestInitMirror() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeMirror)
+
+	for _, name := range []string{"eDP-1", "HDMI-1"} {
+		crtc := s.getCrtc(name)
+		s.Require().NotNil(crtc, name)
+		s.Equal(int16(0), crtc.x)
+		s.Equal(int16(0), crtc.y)
+		s.Equal(uint16(1920), crtc.mode.Width)
+		s.Equal(uint16(1080), crtc.mode.Height)
+
+		monitor := s.getMonitor(name)
+		s.True(monitor.Enabled)
+		s.Equal(uint16(1920), monitor.Width)
+	}
+	s.Equal(DisplayModeMirror, s.m.DisplayMode)
+	s.Equal("eDP-1", s.m.Primary)
+	s.Equal(uint16(1920), s.m.ScreenWidth)
+	s.Equal(uint16(1080), s.m.ScreenHeight)
+}
+
+func (s *FakeDisplayTestSuite) TestSwitchModeExtend() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeMirror)
+
+	err := s.m.switchMode(DisplayModeExtend, "")
+	s.Require().NoError(err)
+
+	edp := s.getCrtc("eDP-1")
+	hdmi := s.getCrtc("HDMI-1")
+	s.Require().NotNil(edp)
+	s.Require().NotNil(hdmi)
+	s.Equal(int16(0), edp.x)
+	s.Equal(int16(1920), hdmi.x)
+	s.Equal(uint16(2560), hdmi.mode.Width)
+
+	s.Equal(DisplayModeExtend, s.m.DisplayMode)
+	s.Equal("eDP-1", s.m.Primary)
+	s.Equal(uint16(1920+2560), s.m.ScreenWidth)
+	s.Equal(uint16(1440), s.m.ScreenHeight)
+
+	cfg, err := s.sysDisplay.getSavedConfig()
+	s.Require().NoError(err)
+	s.Equal(DisplayModeExtend, cfg.Config.DisplayMode)
+	screenCfg := cfg.Config.Screens[s.m.getMonitorsId().v1]
+	s.Require().NotNil(screenCfg)
+	s.Len(screenCfg.GetMonitorConfigs(DisplayModeExtend, ""), 2)
+}
+
+func (s *FakeDisplayTestSuite) TestSwitchModeOnlyOne() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeExtend)
+
+	err := s.m.switchMode(DisplayModeOnlyOne, "HDMI-1")
+	s.Require().NoError(err)
+
+	s.Nil(s.getCrtc("eDP-1"))
+	hdmi := s.getCrtc("HDMI-1")
+	s.Require().NotNil(hdmi)
+	s.Equal(int16(0), hdmi.x)
+	s.False(s.getMonitor("eDP-1").Enabled)
+	s.True(s.getMonitor("HDMI-1").Enabled)
+	s.Equal("HDMI-1", s.m.Primary)
+	s.Equal(DisplayModeOnlyOne, s.m.DisplayMode)
+
+	err = s.m.switchMode(DisplayModeOnlyOne, "DP-9")
+	s.Equal(InvalidOutputNameError{Name: "DP-9"}, err)
+}
+
+func (s *FakeDisplayTestSuite) TestSetBrightness() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeExtend)
+
+	can, busErr := s.m.CanSetBrightness("HDMI-1")
+	s.Nil(busErr)
+	s.True(can)
+	_, busErr = s.m.CanSetBrightness("DP-9")
+	s.NotNil(busErr)
+
+	hdmi := s.getMonitor("HDMI-1")
+	s.Equal(brightness.SetterBacklight, s.m.getMonitorBrightnessSetter(hdmi))
+	err := s.m.setMonitorBrightness(hdmi, 0.6, 0)
+	s.Require().NoError(err)
+	s.mm.mu.Lock()
+	s.Equal(0.6, s.mm.brightness[hdmi.ID])
+	s.mm.mu.Unlock()
+}
+
+func (s *FakeDisplayTestSuite) TestSwitchModeApplyFailed() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeMirror)
+	applyCount := s.mm.getApplyCount()
+
+	s.mm.failNextApply(errors.New("fake apply failed"))
+	err := s.m.switchMode(DisplayModeExtend, "")
+	s.Error(err)
+	s.Equal(applyCount+1, s.mm.getApplyCount())
+	s.Equal(DisplayModeMirror, s.m.DisplayMode)
+	s.Equal(int16(0), s.getCrtc("HDMI-1").x)
+
+	// 失败只影响一次
+	err = s.m.switchMode(DisplayModeExtend, "")
+	s.NoError(err)
+	s.Equal(int16(1920), s.getCrtc("HDMI-1").x)
+}
+
+func (s *FakeDisplayTestSuite) TestNotEnoughCrtcs() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 1, DisplayModeOnlyOne)
+	s.NotNil(s.getCrtc("eDP-1"))
+	s.Nil(s.getCrtc("HDMI-1"))
+
+	err := s.m.switchMode(DisplayModeExtend, "")
+	s.EqualError(err, "failed to find free crtc")
+	s.NotNil(s.getCrtc("eDP-1"))
+	s.Nil(s.getCrtc("HDMI-1"))
+}
+
+func (s *FakeDisplayTestSuite) TestApplySysMonitorConfigs() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeExtend)
+	edp := s.getMonitor("eDP-1")
+	hdmi := s.getMonitor("HDMI-1")
+
+	configs := SysMonitorConfigs{
+		{UUID: hdmi.uuid, Name: hdmi.Name, Enabled: true, Primary: true,
+			Width: 1440, Height: 2560, RefreshRate: 60, Rotation: randr.RotationRotate90, Brightness: 0.5},
+		{UUID: edp.uuid, Name: edp.Name, Enabled: true, X: 1440,
+			Width: 1280, Height: 720, RefreshRate: 60, Rotation: randr.RotationRotate0, Brightness: 1},
+	}
+	monitorMap := s.m.cloneMonitorMap()
+	monitorsId := getConnectedMonitors(monitorMap).getMonitorsId()
+	err := s.m.applySysMonitorConfigs(DisplayModeExtend, monitorsId, monitorMap, configs, nil)
+	s.Require().NoError(err)
+
+	hdmiCrtc := s.getCrtc("HDMI-1")
+	s.Require().NotNil(hdmiCrtc)
+	s.Equal(uint16(randr.RotationRotate90), hdmiCrtc.rotation)
+	s.Equal(uint16(2560), hdmiCrtc.mode.Width)
+	s.Equal(uint16(1440), hdmi.Width)
+	s.Equal(uint16(2560), hdmi.Height)
+	s.Equal(uint16(1280), edp.Width)
+	s.Equal(int16(1440), edp.X)
+	s.Equal("HDMI-1", s.m.Primary)
+
+	s.Eventually(func() bool {
+		s.mm.mu.Lock()
+		defer s.mm.mu.Unlock()
+		return s.mm.brightness[hdmi.ID] == 0.5
+	}, time.Second, 10*time.Millisecond)
+
+	// 没有启用的显示器
+	configs = SysMonitorConfigs{{UUID: edp.uuid, Name: edp.Name}}
+	err = s.m.applySysMonitorConfigs(DisplayModeExtend, monitorsId, s.m.cloneMonitorMap(), configs, nil)
+	s.Error(err)
+}
+
+func (s *FakeDisplayTestSuite) TestHotplug() {
+	s.start("eDP-1:1920x1080,HDMI-1:2560x1440", 0, DisplayModeExtend)
+	twoMonitorsId := s.m.getMonitorsId()
+
+	s.Require().NoError(s.mm.hotplug("HDMI-1", false))
+	s.NotContains(s.m.sysConfig.Config.Cache.ConnectTime, "HDMI-1")
+	s.False(s.getMonitor("HDMI-1").realConnected)
+
+	// 延迟应用单屏配置
+	s.Eventually(func() bool {
+		return s.getCrtc("HDMI-1") == nil
+	}, 3*time.Second, 50*time.Millisecond)
+	s.NotEqual(twoMonitorsId, s.m.getMonitorsId())
+	s.Equal(int16(0), s.getCrtc("eDP-1").x)
+
+	s.Require().NoError(s.mm.hotplug("HDMI-1", true))
+	s.Contains(s.m.sysConfig.Config.Cache.ConnectTime, "HDMI-1")
+	s.Eventually(func() bool {
+		crtc := s.getCrtc("HDMI-1")
+		return crtc != nil && crtc.x == 1920
+	}, 3*time.Second, 50*time.Millisecond)
+	s.Equal(twoMonitorsId, s.m.getMonitorsId())
+}
+
+func (s *FakeDisplayTestSuite) TestHandleMonitorConnectedChanged() {
+	s.start("HDMI-1:1920x1080,DP-1:1920x1080", 0, DisplayModeExtend)
+	hdmi := s.getMonitor("HDMI-1")
+	dp := s.getMonitor("DP-1")
+
+	// 无法确定内置显示器时有多个候选
+	s.m.builtinMonitor = hdmi
+	s.m.candidateBuiltinMonitors = []*Monitor{hdmi, dp}
+
+	s.m.handleMonitorConnectedChanged(hdmi, false)
+	s.Equal(dp, s.m.getBuiltinMonitor())
+	s.Nil(s.m.candidateBuiltinMonitors)
+	s.NotContains(s.m.sysConfig.Config.Cache.ConnectTime, "HDMI-1")
+
+	cfg, err := s.sysDisplay.getSavedConfig()
+	s.Require().NoError(err)
+	s.Equal("DP-1", cfg.Config.Cache.BuiltinMonitor)
+
+	s.m.handleMonitorConnectedChanged(hdmi, true)
+	s.Contains(s.m.sysConfig.Config.Cache.ConnectTime, "HDMI-1")
+	s.Equal(dp, s.m.getBuiltinMonitor())
+}
+
+func (s *FakeDisplayTestSuite) TestAddCustomMode() {
+	s.start("HDMI-1:1024x768", 0, DisplayModeExtend)
+	hdmi := s.getMonitor("HDMI-1")
+	hasMode := func() bool {
+		s.mm.mu.Lock()
+		defer s.mm.mu.Unlock()
+		for _, mode := range s.mm.getOutputByName("HDMI-1").modes {
+			if mode.name == "1920x1080_60.00" {
+				return true
+			}
+		}
+		return false
+	}
+
+	customMode := CustomMode{Width: 1920, Height: 1080, Rate: 60}
+	s.Require().NoError(s.m.addMonitorCustomMode(hdmi, customMode))
+	s.True(hasMode())
+	mode := findFirstMode(hdmi.Modes, func(mode ModeInfo) bool {
+		return mode.Width == 1920 && mode.Height == 1080
+	})
+	s.InDelta(59.96, mode.Rate, 0.01)
+	s.Equal([]CustomMode{customMode}, s.m.getUserMonitorConfig(hdmi.uuid).CustomModes)
+
+	// 重复添加
+	modesNum := len(hdmi.Modes)
+	s.Require().NoError(s.m.addMonitorCustomMode(hdmi, customMode))
+	s.Len(hdmi.Modes, modesNum)
+	s.Len(s.m.getUserMonitorConfig(hdmi.uuid).CustomModes, 1)
+
+	// 模拟重新连接后模式丢失，需要自动添加
+	s.mm.mu.Lock()
+	output := s.mm.getOutputByName("HDMI-1")
+	output.modes = output.modes[:len(output.modes)-1]
+	s.mm.mu.Unlock()
+	s.Require().NoError(s.mm.hotplug("HDMI-1", false))
+	s.False(hasMode())
+	s.Require().NoError(s.mm.hotplug("HDMI-1", true))
+	s.Eventually(hasMode, time.Second, 10*time.Millisecond)
+
+	err := s.m.addMonitorCustomMode(hdmi, CustomMode{Width: 0, Height: 1080})
+	s.Error(err)
+}
+
+func TestFakeDisplayTestSuite(t *testing.T) {
+	suite.Run(t, new(FakeDisplayTestSuite))
+}
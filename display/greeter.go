@@ -5,7 +5,14 @@
 package display
 
 import (
+	"errors"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	sysdisplay "github.com/linuxdeepin/go-dbus-factory/system/org.deepin.dde.display1"
+	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/input"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
 )
 
 // 放和 greeter-display-daemon 有关的代码
@@ -46,3 +53,135 @@ func (m *Manager) beginTouch() {
 func (m *Manager) doShowCursor(show bool) error {
 	return m.mm.showCursor(show)
 }
+
+// GreeterDisplay 给 cmd/greeter-display-daemon 使用，不导出 D-Bus 服务，
+// 通过 RandR 应用会话使用的系统级配置，没有和已连接显示器匹配的配置时自动扩展。
+type GreeterDisplay struct {
+	mu sync.Mutex
+	m  *Manager
+	mm *xMonitorManager
+}
+
+// NewGreeterDisplay 创建 GreeterDisplay，要求 randr 版本大于等于 1.2
+func NewGreeterDisplay(xConn *x.Conn) *GreeterDisplay {
+	mm := newXMonitorManager(xConn, true)
+	// 不设置 mm 的 hooks，显示器的改变只更新 mm 中的缓存
+	m := &Manager{
+		xConn:      xConn,
+		mm:         mm,
+		monitorMap: make(map[uint32]*Monitor),
+	}
+	return &GreeterDisplay{m: m, mm: mm}
+}
+
+// LoadSysConfig 从系统级 display 服务读取配置
+func (gd *GreeterDisplay) LoadSysConfig() error {
+	sysBus, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+	cfg, err := readSysConfig(sysdisplay.NewDisplay(sysBus))
+	if err != nil {
+		return err
+	}
+	gd.m.sysConfig.CopyFrom(cfg)
+	return nil
+}
+
+// HandleEvent 处理 randr 的 CrtcChangeNotifyEvent 和 OutputChangeNotifyEvent 事件
+func (gd *GreeterDisplay) HandleEvent(ev interface{}) {
+	gd.mm.HandleEvent(ev)
+}
+
+// HandleScreenChanged 处理 randr 的 ScreenChangeNotifyEvent 事件
+func (gd *GreeterDisplay) HandleScreenChanged(e *randr.ScreenChangeNotifyEvent) {
+	gd.mm.HandleScreenChanged(e)
+}
+
+func (gd *GreeterDisplay) newMonitor(monitorInfo *MonitorInfo) *Monitor {
+	monitor := &Monitor{
+		m:             gd.m,
+		ID:            monitorInfo.ID,
+		Name:          monitorInfo.Name,
+		Connected:     monitorInfo.VirtualConnected,
+		realConnected: monitorInfo.Connected,
+		MmWidth:       monitorInfo.MmWidth,
+		MmHeight:      monitorInfo.MmHeight,
+		Enabled:       monitorInfo.Enabled,
+		uuid:          monitorInfo.UUID,
+		uuidV0:        monitorInfo.UuidV0,
+		X:             monitorInfo.X,
+		Y:             monitorInfo.Y,
+		Width:         monitorInfo.Width,
+		Height:        monitorInfo.Height,
+		CurrentMode:   monitorInfo.CurrentMode,
+	}
+	// greeter 中读不到 gsettings 的刷新率过滤配置
+	monitor.Modes = filterModeInfos(monitorInfo.Modes, monitorInfo.PreferredMode)
+	monitor.BestMode = getBestMode(monitor.Modes, monitorInfo.PreferredMode)
+	monitor.Rotation, monitor.Reflect = parseCrtcRotation(monitorInfo.Rotation)
+	monitor.RefreshRate = monitorInfo.CurrentMode.Rate
+	return monitor
+}
+
+// Apply 按系统级配置设置显示器，返回是否找到了和已连接显示器匹配的配置
+func (gd *GreeterDisplay) Apply() (matched bool, err error) {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+	m := gd.m
+
+	monitorMap := make(map[uint32]*Monitor)
+	for _, monitorInfo := range gd.mm.getMonitors() {
+		monitorMap[monitorInfo.ID] = gd.newMonitor(monitorInfo)
+	}
+	// mm.apply 中会通过 monitor.m.monitorMap 查找显示器
+	m.monitorMapMu.Lock()
+	m.monitorMap = monitorMap
+	m.monitorMapMu.Unlock()
+
+	monitors := getConnectedMonitors(monitorMap)
+	if len(monitors) == 0 {
+		return false, nil
+	}
+	monitorsId := monitors.getMonitorsId()
+
+	m.sysConfig.Mu.Lock()
+	displayMode := m.sysConfig.Config.DisplayMode
+	configs := getSysMonitorConfigs(&m.sysConfig.Config, monitorsId, displayMode, len(monitors) == 1)
+	m.sysConfig.Mu.Unlock()
+
+	matched = len(configs) > 0
+	if !matched {
+		logger.Debug("no config matches monitors, extend", monitorsId.v1)
+		if len(monitors) == 1 {
+			configs = m.buildConfigForSingle(monitors[0])
+		} else {
+			displayMode = DisplayModeExtend
+			configs, err = m.buildConfigForModeExtend(monitors)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	if len(monitors) == 1 {
+		displayMode = DisplayModeInvalid
+	}
+
+	primaryMonitorID, enabledMonitors := setMonitorsByConfigs(monitorMap, configs)
+	if len(enabledMonitors) == 0 {
+		return matched, errors.New("invalid configs: no enabled monitor")
+	}
+	if primaryMonitorID == 0 {
+		primaryMonitorID = m.getDefaultPrimaryMonitor(enabledMonitors).ID
+	}
+
+	screen := m.xConn.GetDefaultScreen()
+	prevScreenSize := screenSize{width: screen.WidthInPixels, height: screen.HeightInPixels}
+	// 显示器的 AvailableFillModes 为空，不设置平铺方式
+	err = gd.mm.apply(monitorsId, monitorMap, prevScreenSize, nil, nil, primaryMonitorID, displayMode)
+	if err != nil {
+		return matched, err
+	}
+	err = gd.mm.setMonitorPrimary(primaryMonitorID)
+	return matched, err
+}
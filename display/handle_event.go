@@ -108,6 +108,7 @@ func (m *Manager) handleScreenChanged(ev *randr.ScreenChangeNotifyEvent, cfgTsCh
 
 	logger.Info("redo map touch screen")
 	m.handleTouchscreenChanged()
+	m.handleTabletChanged()
 
 	if cfgTsChanged {
 		m.showTouchscreenDialogs()
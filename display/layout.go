@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"sort"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// 每次应用配置后发送一个 LayoutChanged 信号，参数是上一次信号之后和现在的完整布局，以及改变了什么，
+// 都是 JSON 格式。前端不需要监听 Monitors、Primary、PrimaryRect 和各个 Monitor 的属性再自己拼出布局，
+// 也不会看到应用过程中的中间状态。
+
+const (
+	layoutChangeDisplayMode  = "display-mode"
+	layoutChangePrimary      = "primary"
+	layoutChangeScreenSize   = "screen-size"
+	layoutChangeConnected    = "connected"
+	layoutChangeDisconnected = "disconnected"
+	layoutChangeEnabled      = "enabled"
+	layoutChangeDisabled     = "disabled"
+	// 位置、旋转或者反射改变
+	layoutChangeGeometry = "geometry"
+	// 分辨率或者刷新率改变
+	layoutChangeMode = "mode"
+)
+
+type layoutMonitor struct {
+	Name        string
+	UUID        string
+	Enabled     bool
+	X           int16
+	Y           int16
+	Width       uint16
+	Height      uint16
+	Rotation    uint16
+	Reflect     uint16
+	RefreshRate float64
+}
+
+type displayLayout struct {
+	DisplayMode byte
+	Primary     string
+	PrimaryRect x.Rectangle
+	// 按启用的显示器计算出的屏幕尺寸
+	ScreenWidth  uint16
+	ScreenHeight uint16
+	// 按名称排序
+	Monitors []layoutMonitor
+}
+
+type layoutChange struct {
+	Type string
+	// 显示器名称，只用于显示器的改变
+	Name string `json:",omitempty"`
+}
+
+type layoutNotifier struct {
+	mu     sync.Mutex
+	layout *displayLayout
+}
+
+func (l *displayLayout) getMonitor(uuid string) *layoutMonitor {
+	for i := range l.Monitors {
+		if l.Monitors[i].UUID == uuid {
+			return &l.Monitors[i]
+		}
+	}
+	return nil
+}
+
+// diffLayout 比较两个布局，返回改变的列表
+func diffLayout(before, after *displayLayout) []layoutChange {
+	var changes []layoutChange
+	if before.DisplayMode != after.DisplayMode {
+		changes = append(changes, layoutChange{Type: layoutChangeDisplayMode})
+	}
+	if before.Primary != after.Primary || before.PrimaryRect != after.PrimaryRect {
+		changes = append(changes, layoutChange{Type: layoutChangePrimary})
+	}
+	if before.ScreenWidth != after.ScreenWidth || before.ScreenHeight != after.ScreenHeight {
+		changes = append(changes, layoutChange{Type: layoutChangeScreenSize})
+	}
+
+	for _, monitor := range before.Monitors {
+		if after.getMonitor(monitor.UUID) == nil {
+			changes = append(changes, layoutChange{Type: layoutChangeDisconnected, Name: monitor.Name})
+		}
+	}
+	for _, monitor := range after.Monitors {
+		old := before.getMonitor(monitor.UUID)
+		if old == nil {
+			changes = append(changes, layoutChange{Type: layoutChangeConnected, Name: monitor.Name})
+			continue
+		}
+		if old.Enabled != monitor.Enabled {
+			typ := layoutChangeDisabled
+			if monitor.Enabled {
+				typ = layoutChangeEnabled
+			}
+			changes = append(changes, layoutChange{Type: typ, Name: monitor.Name})
+		}
+		if !monitor.Enabled {
+			continue
+		}
+		if old.X != monitor.X || old.Y != monitor.Y ||
+			old.Rotation != monitor.Rotation || old.Reflect != monitor.Reflect {
+			changes = append(changes, layoutChange{Type: layoutChangeGeometry, Name: monitor.Name})
+		}
+		if old.Width != monitor.Width || old.Height != monitor.Height ||
+			old.RefreshRate != monitor.RefreshRate {
+			changes = append(changes, layoutChange{Type: layoutChangeMode, Name: monitor.Name})
+		}
+	}
+	return changes
+}
+
+func (m *Manager) getLayout() *displayLayout {
+	layout := &displayLayout{}
+	m.PropsMu.RLock()
+	layout.DisplayMode = m.DisplayMode
+	layout.Primary = m.Primary
+	layout.PrimaryRect = m.PrimaryRect
+	m.PropsMu.RUnlock()
+
+	var x1, y1, x2, y2 int
+	hasEnabled := false
+	for _, monitor := range m.getConnectedMonitors() {
+		monitor.PropsMu.RLock()
+		lm := layoutMonitor{
+			Name:        monitor.Name,
+			UUID:        monitor.uuid,
+			Enabled:     monitor.Enabled,
+			X:           monitor.X,
+			Y:           monitor.Y,
+			Width:       monitor.Width,
+			Height:      monitor.Height,
+			Rotation:    monitor.Rotation,
+			Reflect:     monitor.Reflect,
+			RefreshRate: monitor.RefreshRate,
+		}
+		monitor.PropsMu.RUnlock()
+		layout.Monitors = append(layout.Monitors, lm)
+
+		if !lm.Enabled {
+			continue
+		}
+		left, top := int(lm.X), int(lm.Y)
+		right, bottom := left+int(lm.Width), top+int(lm.Height)
+		if !hasEnabled {
+			x1, y1, x2, y2 = left, top, right, bottom
+			hasEnabled = true
+			continue
+		}
+		if left < x1 {
+			x1 = left
+		}
+		if top < y1 {
+			y1 = top
+		}
+		if right > x2 {
+			x2 = right
+		}
+		if bottom > y2 {
+			y2 = bottom
+		}
+	}
+	layout.ScreenWidth = uint16(x2 - x1)
+	layout.ScreenHeight = uint16(y2 - y1)
+	sort.Slice(layout.Monitors, func(i, j int) bool {
+		return layout.Monitors[i].Name < layout.Monitors[j].Name
+	})
+	return layout
+}
+
+// notifyLayoutChanged 在应用配置之后调用，和上一次的布局比较，有改变时发送 LayoutChanged 信号
+func (m *Manager) notifyLayoutChanged() {
+	n := &m.layoutNotifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	after := m.getLayout()
+	before := n.layout
+	n.layout = after
+	if before == nil {
+		// 第一次应用配置，只记录布局
+		return
+	}
+	changes := diffLayout(before, after)
+	if len(changes) == 0 || _greeterMode {
+		return
+	}
+	logger.Debugf("layout changed: %+v", changes)
+	err := m.service.Emit(m, "LayoutChanged", jsonMarshal(before), jsonMarshal(after), jsonMarshal(changes))
+	if err != nil {
+		logger.Warning(err)
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffLayout(t *testing.T) {
+	before := &displayLayout{
+		DisplayMode:  DisplayModeExtend,
+		Primary:      "eDP-1",
+		PrimaryRect:  x.Rectangle{Width: 1920, Height: 1080},
+		ScreenWidth:  3840,
+		ScreenHeight: 1080,
+		Monitors: []layoutMonitor{
+			{Name: "DP-1", UUID: "dp", Enabled: true, X: 1920, Width: 1920, Height: 1080, RefreshRate: 60},
+			{Name: "eDP-1", UUID: "edp", Enabled: true, Width: 1920, Height: 1080, RefreshRate: 60},
+		},
+	}
+	assert.Empty(t, diffLayout(before, before))
+
+	after := &displayLayout{
+		DisplayMode:  DisplayModeExtend,
+		Primary:      "eDP-1",
+		PrimaryRect:  x.Rectangle{Width: 1920, Height: 1080},
+		ScreenWidth:  4480,
+		ScreenHeight: 1440,
+		Monitors: []layoutMonitor{
+			{Name: "DP-1", UUID: "dp", Enabled: true, X: 1920, Width: 2560, Height: 1440, RefreshRate: 60},
+			{Name: "eDP-1", UUID: "edp", Enabled: true, Width: 1920, Height: 1080, RefreshRate: 60},
+		},
+	}
+	assert.Equal(t, []layoutChange{
+		{Type: layoutChangeScreenSize},
+		{Type: layoutChangeMode, Name: "DP-1"},
+	}, diffLayout(before, after))
+
+	after = &displayLayout{
+		DisplayMode:  DisplayModeOnlyOne,
+		Primary:      "HDMI-1",
+		PrimaryRect:  x.Rectangle{Width: 1920, Height: 1080},
+		ScreenWidth:  1920,
+		ScreenHeight: 1080,
+		Monitors: []layoutMonitor{
+			{Name: "HDMI-1", UUID: "hdmi", Enabled: true, Width: 1920, Height: 1080, RefreshRate: 60},
+			{Name: "eDP-1", UUID: "edp", Enabled: false, Width: 1920, Height: 1080, RefreshRate: 60},
+		},
+	}
+	assert.Equal(t, []layoutChange{
+		{Type: layoutChangeDisplayMode},
+		{Type: layoutChangePrimary},
+		{Type: layoutChangeScreenSize},
+		{Type: layoutChangeDisconnected, Name: "DP-1"},
+		{Type: layoutChangeConnected, Name: "HDMI-1"},
+		{Type: layoutChangeDisabled, Name: "eDP-1"},
+	}, diffLayout(before, after))
+
+	// 旋转后宽高交换，位置和分辨率都算改变
+	after = &displayLayout{
+		DisplayMode:  DisplayModeExtend,
+		Primary:      "eDP-1",
+		PrimaryRect:  x.Rectangle{Width: 1080, Height: 1920},
+		ScreenWidth:  3000,
+		ScreenHeight: 1920,
+		Monitors: []layoutMonitor{
+			{Name: "DP-1", UUID: "dp", Enabled: true, X: 1080, Width: 1920, Height: 1080, RefreshRate: 60},
+			{Name: "eDP-1", UUID: "edp", Enabled: true, Width: 1080, Height: 1920, Rotation: 2, RefreshRate: 60},
+		},
+	}
+	assert.Equal(t, []layoutChange{
+		{Type: layoutChangePrimary},
+		{Type: layoutChangeScreenSize},
+		{Type: layoutChangeGeometry, Name: "DP-1"},
+		{Type: layoutChangeGeometry, Name: "eDP-1"},
+		{Type: layoutChangeMode, Name: "eDP-1"},
+	}, diffLayout(before, after))
+}
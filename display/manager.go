@@ -5,6 +5,7 @@
 package display
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,7 +15,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -39,15 +39,16 @@ import (
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
 	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/linuxdeepin/startdde/display/core"
 	"golang.org/x/xerrors"
 )
 
 const (
-	DisplayModeCustom uint8 = iota
-	DisplayModeMirror
-	DisplayModeExtend
-	DisplayModeOnlyOne
-	DisplayModeUnknown
+	DisplayModeCustom  = core.DisplayModeCustom
+	DisplayModeMirror  = core.DisplayModeMirror
+	DisplayModeExtend  = core.DisplayModeExtend
+	DisplayModeOnlyOne = core.DisplayModeOnlyOne
+	DisplayModeUnknown = core.DisplayModeUnknown
 )
 
 // DisplayModeInvalid 无效的模式
@@ -73,9 +74,18 @@ const (
 	DSettingsAppID                       = "org.deepin.startdde"
 	DSettingsDisplayName                 = "org.deepin.Display"
 	DSettingsKeyAutoColorTemperature     = "auto-color-temperature"
+	DSettingsKeyColorTempTransition      = "auto-color-temperature-transition"
 	DSettingsKeyDefaultTemperatureManual = "default-temperature-manual"
 	DSettingsKeyCustomModeTime           = "custom-mode-time"
 	DSettingKeyColorTemperatureModeOn    = "color-temperature-mode-on"
+	DSettingsKeyApplyConfirmTimeout      = "apply-confirm-timeout"
+	DSettingsKeyBrightnessSchedule       = "brightness-schedule"
+	DSettingsKeyBrightnessScheduleRamp   = "brightness-schedule-ramp"
+	DSettingsKeyAdaptiveBrightness       = "adaptive-brightness"
+	DSettingsKeyLightCurve               = "adaptive-brightness-curve"
+	DSettingsKeyTouchscreenPolicies      = "touchscreen-map-policies"
+	DSettingsKeyRotationLocked           = "rotation-locked"
+	DSettingsKeyAllowedOrientations      = "allowed-orientations"
 
 	gsSchemaDisplay  = "com.deepin.dde.display"
 	gsKeyDisplayMode = "display-mode"
@@ -84,7 +94,6 @@ const (
 	gsKeyMapOutput   = "map-output"
 	gsKeyRateFilter  = "rate-filter"
 	//gsKeyPrimary     = "primary"
-	gsKeyCustomMode              = "current-custom-mode"
 	gsKeyColorTemperatureMode    = "color-temperature-mode"
 	gsKeyColorTemperatureManual  = "color-temperature-manual"
 	gsKeyRotateScreenTimeDelay   = "rotate-screen-time-delay"
@@ -97,38 +106,18 @@ const (
 	cmdTouchscreenDialogBin = "/usr/lib/deepin-daemon/dde-touchscreen-dialog"
 )
 
-const (
-	priorityEDP = iota
-	priorityDP
-	priorityHDMI
-	priorityDVI
-	priorityVGA
-	priorityOther
-)
-
-var (
-	_monitorTypePriority = map[string]int{
-		"edp":  priorityEDP,
-		"dp":   priorityDP,
-		"hdmi": priorityHDMI,
-		"dvi":  priorityDVI,
-		"vga":  priorityVGA,
-	}
-)
-
 var (
 	startBuildInScreenRotationMutex sync.Mutex
 	rotationScreenValue             = map[string]uint16{
 		"normal": randr.RotationRotate0,
 		"left":   randr.RotationRotate270, // 屏幕重力旋转左转90
 		"right":  randr.RotationRotate90,  // 屏幕重力旋转右转90
+		// 屏幕倒置
+		"bottom-up": randr.RotationRotate180,
 	}
 )
 
-type touchscreenMapValue struct {
-	OutputName string
-	Auto       bool
-}
+type touchscreenMapValue = core.TouchscreenMapValue
 
 //go:generate dbusutil-gen -output display_dbusutil.go -import github.com/godbus/dbus/v5,github.com/linuxdeepin/go-x11-client,github.com/linuxdeepin/go-lib/strv -type Manager,Monitor manager.go monitor.go
 //go:generate dbusutil-gen em -type Manager,Monitor
@@ -155,11 +144,11 @@ type Manager struct {
 	builtinMonitorMu         sync.Mutex
 	candidateBuiltinMonitors []*Monitor // 候补的
 
-	monitorMap     map[uint32]*Monitor
-	monitorMapMu   sync.Mutex
-	mm             monitorManager
-	debugOpts      debugOptions
-	redshiftRunner *redshiftRunner
+	monitorMap      map[uint32]*Monitor
+	monitorMapMu    sync.Mutex
+	mm              monitorManager
+	debugOpts       debugOptions
+	colorTempRunner *colorTempRunner
 
 	sessionActive bool
 	newSysCfg     *SysRootConfig
@@ -182,10 +171,21 @@ type Manager struct {
 	applySaveMu              sync.Mutex
 	inApply                  bool
 	futureConfig             monitorsFutureConfig
+	applyTx                  applyTransactionManager
+	eventHistory             displayEventHistory
+	brightnessScheduler      brightnessScheduler
+	adaptiveBrightness       adaptiveBrightness
+	layoutNotifier           layoutNotifier
+	iccProfileAtomsMu        sync.Mutex
+	// 上次设置的 _ICC_PROFILE 属性的个数
+	iccProfileAtomNum int
+	// 内置显示器被自动旋转到了和配置不同的方向
+	autoRotated bool
 
 	// dbusutil-gen: equal=objPathsEqual
 	Monitors []dbus.ObjectPath
 	// dbusutil-gen: equal=nil
+	// 当前显示器组合下保存的命名布局列表
 	CustomIdList []string
 	HasChanged   bool
 	DisplayMode  byte
@@ -196,12 +196,18 @@ type Manager struct {
 	// dbusutil-gen: equal=nil
 	TouchscreensV2 dxTouchscreens
 	// dbusutil-gen: equal=nil
-	TouchMap       map[string]string
-	touchscreenMap map[string]touchscreenMapValue
+	TouchMap            map[string]string
+	touchscreenMap      map[string]touchscreenMapValue
+	touchscreenPolicies touchscreenPolicies
+	// dbusutil-gen: equal=nil
+	Tablets            []Tablet
+	tabletMap          map[string]tabletMapValue
+	tabletRefreshTimer *time.Timer
 	// touch.uuid -> touchScreenDialog cmd
 	touchScreenDialogMap   map[string]*exec.Cmd
 	touchScreenDialogMutex sync.RWMutex
 
+	// 当前应用的命名布局，布局被修改后为空
 	CurrentCustomId        string
 	Primary                string
 	PrimaryRect            x.Rectangle
@@ -229,6 +235,31 @@ type Manager struct {
 	// adjust color temperature by manual adjustment
 	ColorTemperatureManual    int32
 	CustomColorTempTimePeriod string
+	// 亮度计划，JSON 格式，为空表示没有亮度计划
+	BrightnessSchedule string
+	// 是否根据环境光传感器自动调节亮度
+	AdaptiveBrightness bool
+	// 是否锁定内置显示器的方向，锁定后不根据重力传感器自动旋转
+	RotationLocked bool `prop:"access:rw"`
+	// dbusutil-gen: equal=nil
+	// 允许自动旋转到的方向
+	AllowedOrientations []string
+	// 设备姿态，笔记本姿态下不自动旋转
+	Posture string
+
+	//nolint
+	signals *struct {
+		// ApplyChanges 的修改超时未确认，已经恢复
+		ChangesReverted struct {
+			id uint32
+		}
+		// 应用配置之后布局改变，参数都是 JSON 格式
+		LayoutChanged struct {
+			before  string
+			after   string
+			changes string
+		}
+	}
 }
 
 type monitorSizeInfo struct {
@@ -243,15 +274,21 @@ var _dsDefaultTemperatureManual int32
 
 func newManager(service *dbusutil.Service) *Manager {
 	m := &Manager{
-		service:        service,
-		monitorMap:     make(map[uint32]*Monitor),
-		Brightness:     make(map[string]float64),
-		redshiftRunner: newRedshiftRunner(),
+		service:         service,
+		monitorMap:      make(map[uint32]*Monitor),
+		Brightness:      make(map[string]float64),
+		colorTempRunner: newColorTempRunner(),
 		unsupportGammaDrmList: []string{
 			"Loongson",
 		},
 	}
-	m.redshiftRunner.cb = func(value int) {
+	m.applyTx.timeout = defaultApplyConfirmTimeout
+	m.brightnessScheduler.ramp = defaultBrightnessScheduleRamp
+	m.initAdaptiveBrightness()
+	m.touchscreenPolicies, _ = parseTouchscreenPolicies(defaultTouchscreenPolicies)
+	m.AllowedOrientations, _ = parseOrientations(defaultAllowedOrientations)
+	m.Posture = PostureUnknown
+	m.colorTempRunner.cb = func(value int) {
 		m.setColorTempOneShot()
 	}
 
@@ -264,23 +301,26 @@ func newManager(service *dbusutil.Service) *Manager {
 	}
 
 	m.settings = gio.NewSettings(gsSchemaDisplay)
-	m.CurrentCustomId = m.settings.GetString(gsKeyCustomMode)
 	m.rotateScreenTimeDelay = m.settings.GetInt(gsKeyRotateScreenTimeDelay)
 	m.ColorTemperatureManual = defaultTemperatureManual
 	m.ColorTemperatureMode = defaultTemperatureMode
 
 	m.xConn = _xConn
 
-	screen := m.xConn.GetDefaultScreen()
-	m.ScreenWidth = screen.WidthInPixels
-	m.ScreenHeight = screen.HeightInPixels
+	if m.xConn != nil {
+		screen := m.xConn.GetDefaultScreen()
+		m.ScreenWidth = screen.WidthInPixels
+		m.ScreenHeight = screen.HeightInPixels
+	}
 
 	sessionSigLoop := dbusutil.NewSignalLoop(m.service.Conn(), 10)
 	m.sessionSigLoop = sessionSigLoop
 	sessionSigLoop.Start()
 
-	if _useWayland {
-		m.mm = newKMonitorManager(sessionSigLoop)
+	if useFakeMonitorManager() {
+		m.mm = newFakeMonitorManager(_fakeOutputSpecs, 0)
+	} else if _useWayland {
+		m.mm = newKMonitorManager(sessionSigLoop, m.xConn)
 	} else {
 		m.mm = newXMonitorManager(m.xConn, _hasRandr1d2)
 	}
@@ -324,6 +364,7 @@ func newManager(service *dbusutil.Service) *Manager {
 		if !isSleep {
 			logger.Info("system Wakeup, need reacquire screen status", isSleep)
 			m.initScreenRotation()
+			m.triggerBrightnessSchedule()
 
 			logger.Info("Cancel wm blackscreen effect")
 			cmd := exec.Command("/bin/bash", "-c", "dbus-send --print-reply --dest=org.kde.KWin /BlackScreen org.kde.kwin.BlackScreen.setActive boolean:false")
@@ -380,6 +421,7 @@ func newManager(service *dbusutil.Service) *Manager {
 		}
 
 		m.handleTouchscreenChanged()
+		m.handleTabletChanged()
 		m.showTouchscreenDialogs()
 
 		// 监听用户的session Active属性改变信号，当切换到当前已经登录的用户时
@@ -450,9 +492,132 @@ func (m *Manager) initDSettings(sysBus *dbus.Conn) {
 		logger.Info("Custom Mode on:", m.colorTemperatureModeOn)
 	}
 
+	getApplyConfirmTimeout := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyApplyConfirmTimeout)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		var seconds int64
+		switch vv := v.Value().(type) {
+		case float64:
+			seconds = int64(vv)
+		case int64:
+			seconds = vv
+		default:
+			logger.Warning("type is wrong!")
+			return
+		}
+		m.applyTx.setTimeout(time.Duration(seconds) * time.Second)
+		logger.Info("Apply confirm timeout:", seconds)
+	}
+
+	getBrightnessSchedule := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyBrightnessSchedule)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		data, _ := v.Value().(string)
+		err = m.loadBrightnessSchedule(data)
+		if err != nil {
+			logger.Warning("invalid brightness schedule:", err)
+		}
+	}
+
+	getBrightnessScheduleRamp := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyBrightnessScheduleRamp)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		var seconds int64
+		switch vv := v.Value().(type) {
+		case float64:
+			seconds = int64(vv)
+		case int64:
+			seconds = vv
+		default:
+			logger.Warning("type is wrong!")
+			return
+		}
+		m.setBrightnessScheduleRamp(time.Duration(seconds) * time.Second)
+	}
+
+	getAdaptiveBrightness := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyAdaptiveBrightness)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		enabled, _ := v.Value().(bool)
+		m.loadAdaptiveBrightness(enabled)
+	}
+
+	getLightCurve := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyLightCurve)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		data, _ := v.Value().(string)
+		err = m.loadLightCurve(data)
+		if err != nil {
+			logger.Warning("invalid adaptive brightness curve:", err)
+		}
+	}
+
+	getTouchscreenPolicies := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyTouchscreenPolicies)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		data, _ := v.Value().(string)
+		policies, err := parseTouchscreenPolicies(data)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		m.PropsMu.Lock()
+		m.touchscreenPolicies = policies
+		m.PropsMu.Unlock()
+	}
+
+	getRotationLocked := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyRotationLocked)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		locked, _ := v.Value().(bool)
+		m.loadRotationLocked(locked)
+	}
+
+	getAllowedOrientations := func() {
+		v, err := _dsConfigManager.Value(0, DSettingsKeyAllowedOrientations)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		data, _ := v.Value().(string)
+		err = m.loadAllowedOrientations(data)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+
 	getDefaultTemperatureManual()
 	getCustomTemperatureTime()
 	getColorTemperatureModeOn()
+	getApplyConfirmTimeout()
+	getBrightnessScheduleRamp()
+	getBrightnessSchedule()
+	getLightCurve()
+	getAdaptiveBrightness()
+	getTouchscreenPolicies()
+	getRotationLocked()
+	getAllowedOrientations()
 	m.ColorTemperatureManual = _dsDefaultTemperatureManual
 
 	_dsConfigManager.InitSignalExt(m.sysSigLoop, true)
@@ -462,6 +627,23 @@ func (m *Manager) initDSettings(sysBus *dbus.Conn) {
 			getCustomTemperatureTime()
 		case DSettingKeyColorTemperatureModeOn:
 			getColorTemperatureModeOn()
+		case DSettingsKeyApplyConfirmTimeout:
+			getApplyConfirmTimeout()
+		case DSettingsKeyBrightnessSchedule:
+			getBrightnessSchedule()
+		case DSettingsKeyBrightnessScheduleRamp:
+			getBrightnessScheduleRamp()
+		case DSettingsKeyAdaptiveBrightness:
+			getAdaptiveBrightness()
+		case DSettingsKeyLightCurve:
+			getLightCurve()
+		case DSettingsKeyTouchscreenPolicies:
+			getTouchscreenPolicies()
+			m.handleTouchscreenChanged()
+		case DSettingsKeyRotationLocked:
+			getRotationLocked()
+		case DSettingsKeyAllowedOrientations:
+			getAllowedOrientations()
 		default:
 			break
 		}
@@ -514,7 +696,7 @@ func (m *Manager) initSysDisplay() {
 func (m *Manager) handleSysConfigUpdated(newSysConfig *SysRootConfig) {
 	logger.Debug("handleSysConfigUpdated")
 	setCfg := func() {
-		m.sysConfig.copyFrom(newSysConfig)
+		m.sysConfig.CopyFrom(newSysConfig)
 	}
 
 	currentCfg := &m.sysConfig.Config
@@ -529,17 +711,17 @@ func (m *Manager) handleSysConfigUpdated(newSysConfig *SysRootConfig) {
 
 	monitorMap := m.cloneMonitorMap()
 	monitors := getConnectedMonitors(monitorMap)
-	newCfg.updateUuid(monitors)
+	updateSysConfigUuid(newCfg, monitors)
 
 	fillModesEq := reflect.DeepEqual(currentCfg.FillModes, newCfg.FillModes)
 	displayModeEq := currentCfg.DisplayMode == newCfg.DisplayMode
 	scaleFactorsEq := reflect.DeepEqual(currentCfg.ScaleFactors, newCfg.ScaleFactors)
 	single := len(monitors) == 1
 	monitorsId := monitors.getMonitorsId()
-	currentMonitorCfgs := currentCfg.getMonitorConfigs(monitorsId, currentCfg.DisplayMode, single)
-	currentMonitorCfgs.sort()
-	newMonitorCfgs := newCfg.getMonitorConfigs(monitorsId, currentCfg.DisplayMode, single)
-	newMonitorCfgs.sort()
+	currentMonitorCfgs := getSysMonitorConfigs(currentCfg, monitorsId, currentCfg.DisplayMode, single)
+	currentMonitorCfgs.Sort()
+	newMonitorCfgs := getSysMonitorConfigs(newCfg, monitorsId, currentCfg.DisplayMode, single)
+	newMonitorCfgs.Sort()
 	monitorCfgsEq := reflect.DeepEqual(currentMonitorCfgs, newMonitorCfgs)
 	logger.Debugf("fillModeEq: %v, displayModeEq: %v, scaleFactorsEq: %v, monitorCfgsEq: %v, monitorsId: %v, single: %v",
 		fillModesEq, displayModeEq, scaleFactorsEq, monitorCfgsEq, monitorsId, single)
@@ -549,6 +731,7 @@ func (m *Manager) handleSysConfigUpdated(newSysConfig *SysRootConfig) {
 	}
 
 	setCfg()
+	m.updatePropProfiles(nil)
 
 	if !scaleFactorsEq {
 		// scale factors 改变了
@@ -583,7 +766,7 @@ func (m *Manager) handleSysConfigUpdated(newSysConfig *SysRootConfig) {
 
 	doApply := false
 	if !monitorCfgsEq {
-		if currentMonitorCfgs.onlyBrNotEq(newMonitorCfgs) {
+		if currentMonitorCfgs.OnlyBrNotEq(newMonitorCfgs) {
 			// 仅亮度改变
 			logger.Debug("monitorCfgs not eq, but only brightness changed")
 			go func() {
@@ -754,7 +937,7 @@ func (m *Manager) applyDisplayConfig(mode byte, monitorsId monitorsId, monitorMa
 		return errors.New("monitorsId is empty")
 	}
 	// X 环境下，如果 randr 版本低于 1.2 时，不做操作
-	if !_useWayland && !_hasRandr1d2 {
+	if !hasMultiMonitorSupport() {
 		return nil
 	}
 	monitors := getConnectedMonitors(monitorMap)
@@ -781,7 +964,7 @@ func (m *Manager) applyDisplayConfig(mode byte, monitorsId monitorsId, monitorMa
 
 		needSaveCfg := false
 
-		monitorConfigs := screenCfg.getSingleMonitorConfigs()
+		monitorConfigs := screenCfg.GetSingleMonitorConfigs()
 		if len(monitorConfigs) == 0 {
 			// 没有单屏配置
 			needSaveCfg = true
@@ -795,7 +978,7 @@ func (m *Manager) applyDisplayConfig(mode byte, monitorsId monitorsId, monitorMa
 			return err
 		}
 		if needSaveCfg {
-			screenCfg.setSingleMonitorConfigs(monitorConfigs)
+			screenCfg.SetSingleMonitorConfigs(monitorConfigs)
 			m.setSysScreenConfig(monitorsId, screenCfg)
 			err = m.saveSysConfig("single")
 			if err != nil {
@@ -833,6 +1016,11 @@ func (m *Manager) updateMonitorsId(options applyOptions) (changed bool) {
 	if newMonitorsId != oldMonitorsId && newMonitorsId.v1 != "" {
 		m.monitorsId = newMonitorsId
 		logger.Debugf("monitors id changed, old monitors id: %v, new monitors id: %v", oldMonitorsId.v1, newMonitorsId.v1)
+		m.addEvent(displayEvent{
+			Type:       eventMonitorsIdChanged,
+			MonitorsId: newMonitorsId.v1,
+			Detail:     "old: " + oldMonitorsId.v1,
+		})
 		m.markClean()
 
 		const delayApplyDuration = 1 * time.Second
@@ -889,6 +1077,7 @@ func (m *Manager) delayApplyConfig() {
 	m.PropsMu.Lock()
 	m.setPropMonitors(paths)
 	m.PropsMu.Unlock()
+	m.updatePropProfiles(nil)
 }
 
 func (m *Manager) getDelayApplyOptions() applyOptions {
@@ -935,19 +1124,19 @@ func (m *Manager) applyConfig(setColorTemp bool, options applyOptions) (paths []
 func (m *Manager) migrateOldConfig() {
 	if _greeterMode {
 		// greeter 模式无法读取gsetting值，在没有显示系统级配置文件时，默认多屏模式为扩展
-		m.sysConfig.mu.Lock()
+		m.sysConfig.Mu.Lock()
 		m.sysConfig.Config.DisplayMode = DisplayModeExtend
 		m.DisplayMode = m.sysConfig.Config.DisplayMode
-		m.sysConfig.mu.Unlock()
+		m.sysConfig.Mu.Unlock()
 		return
 	}
 	logger.Debug("migrateOldConfig")
 
 	// 当系统级配置文件不存在时，此时的display Mode取gsetting中的值，确保升级前后一致
-	m.sysConfig.mu.Lock()
+	m.sysConfig.Mu.Lock()
 	m.sysConfig.Config.DisplayMode = uint8(m.settings.GetEnum(gsKeyDisplayMode))
 	m.DisplayMode = m.sysConfig.Config.DisplayMode
-	m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Unlock()
 	// NOTE: 在设置 m.DisplayMode, m.Brightness, m.gsColorTemperatureMode, m.gsColorTemperatureManual 之后
 	// 再加载配置文件并迁移，主要原因是 loadOldConfig 中的 ConfigV3D3.toConfig 和 ConfigV4.toConfig 需要。
 	m.gsColorTemperatureMode = m.settings.GetInt(gsKeyColorTemperatureMode)
@@ -1006,7 +1195,7 @@ func (m *Manager) migrateOldConfig() {
 		m.sysConfig.Config.Cache.ConnectTime = connectTime
 	}
 
-	m.sysConfig.fix()
+	m.sysConfig.Fix()
 	if err := m.saveSysConfigNoLock("migrate old config"); err != nil {
 		logger.Warning(err)
 	}
@@ -1030,7 +1219,7 @@ func (m *Manager) init() {
 		_scaleFactors = nil
 	}
 
-	if _hasRandr1d2 || _useWayland {
+	if hasMultiMonitorSupport() {
 		monitors := m.mm.getMonitors()
 		logger.Debug("len monitors", len(monitors))
 		err := m.recordMonitorsConnected(monitors)
@@ -1048,6 +1237,7 @@ func (m *Manager) init() {
 		m.initBuiltinMonitor()
 		m.monitorsId = m.getMonitorsId()
 		m.updatePropMonitors()
+		m.updatePropProfiles(nil)
 
 	} else {
 		// randr 版本低于 1.2
@@ -1078,6 +1268,9 @@ func (m *Manager) init() {
 	if err != nil {
 		logger.Warning("loadUserConfig err:", err)
 	}
+	m.loadMonitorsColorTemp()
+	m.loadMonitorsColorProfile()
+	m.restoreMonitorsCustomModes()
 
 	// NOTE: m.listenXEvents 应该在 m.applyDisplayConfig 之前，否则会造成它里面的 m.apply 函数的等待超时。
 	m.listenXEvents()
@@ -1233,7 +1426,7 @@ func (m *Manager) recordMonitorConnected(name string, connected bool, t time.Tim
 }
 
 func (m *Manager) recordMonitorConnectedAux(name string, connected bool, t time.Time) (needSave bool) {
-	m.sysConfig.mu.Lock()
+	m.sysConfig.Mu.Lock()
 	connectTime := m.sysConfig.Config.Cache.ConnectTime
 	if connected {
 		// 连接
@@ -1252,7 +1445,7 @@ func (m *Manager) recordMonitorConnectedAux(name string, connected bool, t time.
 			needSave = true
 		}
 	}
-	m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Unlock()
 	return needSave
 }
 
@@ -1279,6 +1472,7 @@ func (m *Manager) addMonitor(monitorInfo *MonitorInfo) error {
 		Enabled:            monitorInfo.Enabled,
 		uuid:               monitorInfo.UUID,
 		uuidV0:             monitorInfo.UuidV0,
+		edidBase64:         base64.StdEncoding.EncodeToString(monitorInfo.EDID),
 		Manufacturer:       monitorInfo.Manufacturer,
 		Model:              monitorInfo.Model,
 		AvailableFillModes: monitorInfo.AvailableFillModes,
@@ -1311,6 +1505,8 @@ func (m *Manager) addMonitor(monitorInfo *MonitorInfo) error {
 	m.monitorMapMu.Lock()
 	m.monitorMap[monitorInfo.ID] = monitor
 	m.monitorMapMu.Unlock()
+	m.loadMonitorColorTemp(monitor)
+	m.loadMonitorColorProfile(monitor, true)
 
 	monitorObj := m.service.GetServerObject(monitor)
 	err = monitorObj.SetWriteCallback(monitor, "CurrentFillMode",
@@ -1348,6 +1544,14 @@ func (m *Manager) updateMonitor(monitorInfo *MonitorInfo) {
 	}
 	monitor.uuid = monitorInfo.UUID
 	monitor.uuidV0 = monitorInfo.UuidV0
+	edidBase64 := base64.StdEncoding.EncodeToString(monitorInfo.EDID)
+	if monitor.edidBase64 != edidBase64 {
+		monitor.edidBase64 = edidBase64
+		// 接入了其他显示器，重新检测 DDC/CI 支持
+		go brightness.RefreshDDCCI()
+	}
+	// 重新连接后需要添加自定义模式
+	reconnected := monitorInfo.Connected && !monitor.realConnected
 	monitor.realConnected = monitorInfo.Connected
 	monitor.setPropAvailableFillModes(monitorInfo.AvailableFillModes)
 	monitor.setPropManufacturer(monitorInfo.Manufacturer)
@@ -1380,11 +1584,29 @@ func (m *Manager) updateMonitor(monitorInfo *MonitorInfo) {
 	monitor.setPropRefreshRate(monitorInfo.CurrentMode.Rate)
 	monitor.PropsMu.Unlock()
 
+	// uuid 可能改变了
+	m.loadMonitorColorTemp(monitor)
+	m.updateMonitorColorProfile(monitor)
 	m.updateScreenSize()
+	if reconnected {
+		go m.restoreCustomModes(monitor)
+	}
 }
 
 func (m *Manager) handleMonitorConnectedChanged(monitor *Monitor, connected bool) {
-	err := m.recordMonitorConnected(monitor.Name, connected, time.Now())
+	now := time.Now()
+	ev := displayEvent{
+		Time: now,
+		Type: eventMonitorDisconnected,
+		Name: monitor.Name,
+		UUID: monitor.uuid,
+	}
+	if connected {
+		ev.Type = eventMonitorConnected
+	}
+	m.addEvent(ev)
+
+	err := m.recordMonitorConnected(monitor.Name, connected, now)
 	if err != nil {
 		logger.Warning(err)
 	}
@@ -1398,31 +1620,11 @@ func (m *Manager) handleMonitorConnectedChanged(monitor *Monitor, connected bool
 
 func (m *Manager) buildConfigForModeMirror(monitors Monitors) (monitorCfgs SysMonitorConfigs, err error) {
 	logger.Debug("switch mode mirror")
-	commonSizes := getMonitorsCommonSizes(monitors)
-	if len(commonSizes) == 0 {
-		err = errors.New("not found common size")
-		return
-	}
-	maxSize := getMaxAreaSize(commonSizes)
 	primaryMonitor := m.getDefaultPrimaryMonitor(monitors)
-	for _, monitor := range monitors {
-		cfg := monitor.toBasicSysConfig()
-		cfg.Enabled = true
-		if monitor.ID == primaryMonitor.ID {
-			cfg.Primary = true
-		}
-		mode := getFirstModeBySize(monitor.Modes, maxSize.width, maxSize.height)
-		cfg.Width = mode.Width
-		cfg.Height = mode.Height
-		cfg.RefreshRate = mode.Rate
-		cfg.X = 0
-		cfg.Y = 0
-		cfg.Rotation = randr.RotationRotate0
-		cfg.Reflect = 0
-		cfg.Brightness = 1
-		monitorCfgs = append(monitorCfgs, cfg)
+	if primaryMonitor == nil {
+		return nil, errors.New("not found primary monitor")
 	}
-	return
+	return core.BuildMirrorConfigs(monitors.toCoreMonitors(), primaryMonitor.toCoreMonitor())
 }
 
 func (m *Manager) applyModeMirror(monitorsId monitorsId, monitorMap map[uint32]*Monitor, options applyOptions) (err error) {
@@ -1432,7 +1634,7 @@ func (m *Manager) applyModeMirror(monitorsId monitorsId, monitorMap map[uint32]*
 
 	needSaveCfg := false
 
-	configs := screenCfg.getMonitorConfigs(DisplayModeMirror, "")
+	configs := screenCfg.GetMonitorConfigs(DisplayModeMirror, "")
 
 	if len(configs) == 0 {
 		needSaveCfg = true
@@ -1448,7 +1650,7 @@ func (m *Manager) applyModeMirror(monitorsId monitorsId, monitorMap map[uint32]*
 	}
 
 	if needSaveCfg {
-		screenCfg.setMonitorConfigs(DisplayModeMirror, "", configs)
+		screenCfg.SetMonitorConfigs(DisplayModeMirror, "", configs)
 		m.setSysScreenConfig(monitorsId, screenCfg)
 		return m.saveSysConfig("mode mirror")
 	}
@@ -1461,10 +1663,10 @@ func (m *Manager) getSuitableSysMonitorConfigs(displayMode byte, monitorsId moni
 	if len(monitors) == 0 {
 		return nil
 	} else if len(monitors) == 1 {
-		return screenCfg.getSingleMonitorConfigs()
+		return screenCfg.GetSingleMonitorConfigs()
 	}
 	uuid := getOnlyOneMonitorUuid(displayMode, monitors)
-	return screenCfg.getMonitorConfigs(displayMode, uuid)
+	return screenCfg.GetMonitorConfigs(displayMode, uuid)
 }
 
 func (m *Manager) getSuitableUserMonitorModeConfig(displayMode byte) *UserMonitorModeConfig {
@@ -1486,15 +1688,15 @@ func (m *Manager) modifySuitableSysMonitorConfigs(fn func(configs SysMonitorConf
 	if len(monitors) == 0 {
 		return
 	} else if len(monitors) == 1 {
-		configs := screenCfg.getSingleMonitorConfigs()
+		configs := screenCfg.GetSingleMonitorConfigs()
 		configs = fn(configs)
-		screenCfg.setSingleMonitorConfigs(configs)
+		screenCfg.SetSingleMonitorConfigs(configs)
 	} else {
 		displayMode := m.DisplayMode
 		uuid := getOnlyOneMonitorUuid(displayMode, monitors)
-		configs := screenCfg.getMonitorConfigs(displayMode, uuid)
+		configs := screenCfg.GetMonitorConfigs(displayMode, uuid)
 		configs = fn(configs)
-		screenCfg.setMonitorConfigs(displayMode, uuid, configs)
+		screenCfg.SetMonitorConfigs(displayMode, uuid, configs)
 	}
 	m.setSysScreenConfig(monitorsId, screenCfg)
 }
@@ -1579,7 +1781,12 @@ func (m *Manager) handlePrimaryRectChanged(pmi primaryMonitorInfo) {
 	m.PropsMu.Lock()
 	defer m.PropsMu.Unlock()
 
-	m.setPropPrimary(pmi.Name)
+	if m.setPropPrimary(pmi.Name) {
+		m.addEvent(displayEvent{
+			Type: eventPrimaryChanged,
+			Name: pmi.Name,
+		})
+	}
 	if !pmi.IsRectEmpty() {
 		m.setPropPrimaryRect(pmi.Rect)
 	}
@@ -1603,7 +1810,7 @@ func (m *Manager) setPrimary(name string) error {
 		monitors := getConnectedMonitors(monitorMap)
 		monitorsId := monitors.getMonitorsId()
 		screenCfg := m.getSysScreenConfig(monitorsId)
-		configs := screenCfg.getMonitorConfigs(DisplayModeExtend, "")
+		configs := screenCfg.GetMonitorConfigs(DisplayModeExtend, "")
 
 		var primaryMonitor *Monitor
 		for _, monitor := range monitorMap {
@@ -1629,7 +1836,7 @@ func (m *Manager) setPrimary(name string) error {
 			// modify configs
 			// TODO 这里为什么需要更新 Name？
 			updateSysMonitorConfigsName(configs, m.monitorMap)
-			configs.setPrimary(primaryMonitor.uuid)
+			configs.SetPrimary(primaryMonitor.uuid)
 		}
 
 		err := m.mm.setMonitorPrimary(primaryMonitor.ID)
@@ -1637,12 +1844,14 @@ func (m *Manager) setPrimary(name string) error {
 			return err
 		}
 
-		screenCfg.setMonitorConfigs(DisplayModeExtend, "", configs)
+		screenCfg.SetMonitorConfigs(DisplayModeExtend, "", configs)
+		screenCfg.CurrentProfile = ""
 		m.setSysScreenConfig(monitorsId, screenCfg)
 		err = m.saveSysConfig("primary changed")
 		if err != nil {
 			return err
 		}
+		m.updatePropProfiles(screenCfg)
 
 	default:
 		return fmt.Errorf("invalid display mode %v", m.DisplayMode)
@@ -1652,36 +1861,11 @@ func (m *Manager) setPrimary(name string) error {
 
 func (m *Manager) buildConfigForModeExtend(monitors Monitors) (monitorCfgs SysMonitorConfigs, err error) {
 	// 先获取主屏
-	var primaryMonitor *Monitor
-	primaryMonitor = m.getDefaultPrimaryMonitor(monitors)
-
-	sortMonitorsByPrimaryAndId(monitors, primaryMonitor)
-	var xOffset int
-
-	for _, monitor := range monitors {
-		cfg := monitor.toBasicSysConfig()
-		cfg.Enabled = true
-		if monitor.ID == primaryMonitor.ID {
-			cfg.Primary = true
-		}
-		mode := monitor.BestMode
-		// 不用考虑旋转，默认不旋转
-		cfg.Width = mode.Width
-		cfg.Height = mode.Height
-		cfg.RefreshRate = mode.Rate
-
-		if xOffset > math.MaxInt16 {
-			xOffset = math.MaxInt16
-		}
-		cfg.X = int16(xOffset)
-		//cfg.Y = 0
-		cfg.Rotation = randr.RotationRotate0
-		//cfg.Reflect = 0
-		cfg.Brightness = 1
-		xOffset += int(cfg.Width)
-		monitorCfgs = append(monitorCfgs, cfg)
+	primaryMonitor := m.getDefaultPrimaryMonitor(monitors)
+	if primaryMonitor == nil {
+		return nil, errors.New("not found primary monitor")
 	}
-	return
+	return core.BuildExtendConfigs(monitors.toCoreMonitors(), primaryMonitor.toCoreMonitor()), nil
 }
 
 func (m *Manager) applyModeExtend(monitorsId monitorsId, monitorMap map[uint32]*Monitor, options applyOptions) (err error) {
@@ -1691,7 +1875,7 @@ func (m *Manager) applyModeExtend(monitorsId monitorsId, monitorMap map[uint32]*
 
 	needSaveCfg := false
 
-	configs := screenCfg.getMonitorConfigs(DisplayModeExtend, "")
+	configs := screenCfg.GetMonitorConfigs(DisplayModeExtend, "")
 
 	if len(configs) == 0 {
 		needSaveCfg = true
@@ -1707,7 +1891,7 @@ func (m *Manager) applyModeExtend(monitorsId monitorsId, monitorMap map[uint32]*
 	}
 
 	if needSaveCfg {
-		screenCfg.setMonitorConfigs(DisplayModeExtend, "", configs)
+		screenCfg.SetMonitorConfigs(DisplayModeExtend, "", configs)
 		m.setSysScreenConfig(monitorsId, screenCfg)
 		return m.saveSysConfig("mode extend")
 	}
@@ -1715,25 +1899,7 @@ func (m *Manager) applyModeExtend(monitorsId monitorsId, monitorMap map[uint32]*
 }
 
 func (m *Manager) buildConfigForModeOnlyOne(monitors Monitors, uuid string) (monitorCfgs SysMonitorConfigs, err error) {
-	for _, monitor := range monitors {
-		mode := monitor.BestMode
-		cfg := monitor.toBasicSysConfig()
-		if monitor.uuid == uuid {
-			cfg.Enabled = true
-			cfg.Primary = true
-			cfg.Width = mode.Width
-			cfg.Height = mode.Height
-			cfg.RefreshRate = mode.Rate
-			//cfg.X = 0
-			//cfg.Y = 0
-			cfg.Rotation = randr.RotationRotate0
-			//cfg.Reflect = 0
-			cfg.Brightness = 1
-			monitorCfgs = append(monitorCfgs, cfg)
-			return
-		}
-	}
-	return
+	return core.BuildOnlyOneConfigs(monitors.toCoreMonitors(), uuid), nil
 }
 
 func (m *Manager) applyModeOnlyOne(monitorsId monitorsId, monitorMap map[uint32]*Monitor, options applyOptions) (err error) {
@@ -1778,7 +1944,7 @@ func (m *Manager) applyModeOnlyOne(monitorsId monitorsId, monitorMap map[uint32]
 		return errors.New("uuid is empty")
 	}
 
-	configs := screenCfg.getMonitorConfigs(DisplayModeOnlyOne, uuid)
+	configs := screenCfg.GetMonitorConfigs(DisplayModeOnlyOne, uuid)
 	if len(configs) == 0 {
 		needSaveCfg = true
 		logger.Debug("buildConfigForModeOnlyOne", uuid)
@@ -1794,7 +1960,7 @@ func (m *Manager) applyModeOnlyOne(monitorsId monitorsId, monitorMap map[uint32]
 	}
 
 	if needSaveCfg {
-		screenCfg.setMonitorConfigs(DisplayModeOnlyOne, uuid, configs)
+		screenCfg.SetMonitorConfigs(DisplayModeOnlyOne, uuid, configs)
 		screenCfg.OnlyOneUuid = uuid
 		m.setSysScreenConfig(monitorsId, screenCfg)
 		return m.saveSysConfig("mode only one")
@@ -1812,11 +1978,13 @@ func (m *Manager) switchModeAux(mode, oldMode byte, monitorsId monitorsId, monit
 		return err
 	}
 	if oldMode != mode {
+		// 切换了显示模式，不再处于命名布局中
+		m.clearCurrentProfile(monitorsId)
 		// 保存设置
-		m.sysConfig.mu.Lock()
+		m.sysConfig.Mu.Lock()
 		m.sysConfig.Config.DisplayMode = mode
 		err = m.saveSysConfigNoLock("switch mode")
-		m.sysConfig.mu.Unlock()
+		m.sysConfig.Mu.Unlock()
 
 		if err != nil {
 			logger.Warning(err)
@@ -1834,6 +2002,11 @@ type delaySwitchMode struct {
 }
 
 func (m *Manager) switchMode(mode byte, name string) (err error) {
+	if mode == DisplayModeCustom {
+		// 自定义模式即应用名称为 name 的命名布局
+		return m.applyProfile(name)
+	}
+
 	oldMode := m.DisplayMode
 	monitorMap := m.cloneMonitorMap()
 	monitors := getConnectedMonitors(monitorMap)
@@ -1922,11 +2095,13 @@ func (m *Manager) save() (err error) {
 		return
 	}
 	if len(monitors) == 1 {
-		screenCfg.setSingleMonitorConfigs(configs)
+		screenCfg.SetSingleMonitorConfigs(configs)
 	} else {
 		uuid := getOnlyOneMonitorUuid(m.DisplayMode, monitors)
-		screenCfg.setMonitorConfigs(m.DisplayMode, uuid, configs)
+		screenCfg.SetMonitorConfigs(m.DisplayMode, uuid, configs)
 	}
+	// 布局被修改了，不再处于命名布局中
+	screenCfg.CurrentProfile = ""
 	m.setSysScreenConfig(monitorsId, screenCfg)
 
 	err = m.saveSysConfig("save")
@@ -1934,6 +2109,7 @@ func (m *Manager) save() (err error) {
 		return err
 	}
 	m.markClean()
+	m.updatePropProfiles(screenCfg)
 	return nil
 }
 
@@ -1953,6 +2129,8 @@ func (m *Manager) markClean() {
 	m.PropsMu.Unlock()
 
 	m.futureConfig.clear()
+	// 修改已保存或者被丢弃，不再需要确认
+	m.applyTx.cancel()
 }
 
 type monitorsFutureConfig struct {
@@ -1974,7 +2152,7 @@ func (mfc *monitorsFutureConfig) setConfigs(monitorsId monitorsId, configs SysMo
 	defer mfc.mu.Unlock()
 
 	mfc.monitorsId = monitorsId
-	mfc.configs = configs.clone()
+	mfc.configs = configs.Clone()
 }
 
 func (mfc *monitorsFutureConfig) getConfigs(monitorsId monitorsId) SysMonitorConfigs {
@@ -1985,23 +2163,25 @@ func (mfc *monitorsFutureConfig) getConfigs(monitorsId monitorsId) SysMonitorCon
 		return nil
 	}
 
-	return mfc.configs.clone()
+	return mfc.configs.Clone()
 }
 
-func (m *Manager) applyChanges() error {
+// applyChanges 应用 DBus 对显示器做的修改，返回的事务 id 需要通过 ConfirmChanges 确认，否则超时后恢复。
+func (m *Manager) applyChanges() (uint32, error) {
 	if m.getInApply() {
 		logger.Debug("no apply changes, in apply")
-		return nil
+		return 0, nil
 	}
 
 	m.PropsMu.RLock()
 	if !m.HasChanged {
 		m.PropsMu.RUnlock()
 		logger.Debug("no apply changes, no changed")
-		return nil
+		return 0, nil
 	}
 	m.PropsMu.RUnlock()
 
+	prevConfigs := m.getPrevSysMonitorConfigs()
 	monitorMap := m.cloneMonitorMap()
 	monitors := getConnectedMonitors(monitorMap)
 	monitorsId := monitors.getMonitorsId()
@@ -2012,17 +2192,18 @@ func (m *Manager) applyChanges() error {
 		if monitor == nil {
 			continue
 		}
-		config.modify(monitor.changes)
+		modifySysMonitorConfig(config, monitor.changes)
 	}
 
 	err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, configs, nil)
 	if err != nil {
 		logger.Warning("[applyChanges] apply sys monitor configs failed:", err)
 		m.futureConfig.clear()
-	} else {
-		m.futureConfig.setConfigs(monitorsId, configs)
+		return 0, err
 	}
-	return err
+	m.futureConfig.setConfigs(monitorsId, configs)
+	id := m.applyTx.begin(monitorsId, prevConfigs, m.revertChanges)
+	return id, nil
 }
 
 func (m *Manager) resetChangesWithoutApply() {
@@ -2063,6 +2244,25 @@ func (m *Manager) cloneMonitorMapNoLock() map[uint32]*Monitor {
 }
 
 func (m *Manager) applySysMonitorConfigs(mode byte, monitorsId monitorsId, monitorMap map[uint32]*Monitor, configs SysMonitorConfigs, options applyOptions) error {
+	err := m.applySysMonitorConfigsAux(mode, monitorsId, monitorMap, configs, options)
+	ev := displayEvent{
+		Type:        eventConfigApplied,
+		MonitorsId:  monitorsId.v1,
+		DisplayMode: mode,
+		Configs:     configs.Clone(),
+	}
+	if err != nil {
+		ev.Type = eventApplyFailed
+		ev.Detail = err.Error()
+	}
+	m.addEvent(ev)
+	// 应用失败时布局也可能部分改变了
+	m.notifyLayoutChanged()
+	m.updateICCProfileAtoms()
+	return err
+}
+
+func (m *Manager) applySysMonitorConfigsAux(mode byte, monitorsId monitorsId, monitorMap map[uint32]*Monitor, configs SysMonitorConfigs, options applyOptions) error {
 	if logger.GetLogLevel() == log.LevelDebug {
 		logger.Debugf("applySysMonitorConfigs configs: %s, options: %v", spew.Sdump(configs), options)
 	}
@@ -2078,39 +2278,7 @@ func (m *Manager) applySysMonitorConfigs(mode byte, monitorsId monitorsId, monit
 		return errors.New("invalid configs: no enabled monitor")
 	}
 
-	var primaryMonitorID uint32
-	var enabledMonitors []*Monitor
-	for _, monitor := range monitorMap {
-		monitorCfg := configs.getByUuid(monitor.uuid)
-		if monitorCfg == nil {
-			logger.Debug("disable monitor", monitor)
-			monitor.Enabled = false
-		} else {
-			if monitorCfg.Enabled {
-				logger.Debug("enable monitor", monitor)
-				if monitorCfg.Primary {
-					primaryMonitorID = monitor.ID
-				}
-				enabledMonitors = append(enabledMonitors, monitor)
-				//所有可设置的值都设置为配置文件中的值
-				monitor.X = monitorCfg.X
-				monitor.Y = monitorCfg.Y
-				monitor.Rotation = monitorCfg.Rotation
-				monitor.Reflect = monitorCfg.Reflect
-
-				// monitorCfg 中的宽和高是经过 rotation 调整的
-				width := monitorCfg.Width
-				height := monitorCfg.Height
-				swapWidthHeightWithRotation(monitorCfg.Rotation, &width, &height)
-				mode := monitor.selectMode(width, height, monitorCfg.RefreshRate)
-				monitor.setModeNoEmitChanged(mode)
-				monitor.Enabled = true
-			} else {
-				logger.Debug("disable monitor", monitor)
-				monitor.Enabled = false
-			}
-		}
-	}
+	primaryMonitorID, enabledMonitors := setMonitorsByConfigs(monitorMap, configs)
 
 	if primaryMonitorID == 0 {
 		primaryMonitor := m.getDefaultPrimaryMonitor(enabledMonitors)
@@ -2172,6 +2340,43 @@ func (m *Manager) applySysMonitorConfigs(mode byte, monitorsId monitorsId, monit
 	return nil
 }
 
+// setMonitorsByConfigs 把 monitorMap 中显示器的属性设置为配置中的值，不在配置中的显示器被禁用，
+// 返回配置中主屏的 id 和启用的显示器。
+func setMonitorsByConfigs(monitorMap map[uint32]*Monitor, configs SysMonitorConfigs) (primaryMonitorID uint32, enabledMonitors []*Monitor) {
+	for _, monitor := range monitorMap {
+		monitorCfg := configs.GetByUuid(monitor.uuid)
+		if monitorCfg == nil {
+			logger.Debug("disable monitor", monitor)
+			monitor.Enabled = false
+		} else {
+			if monitorCfg.Enabled {
+				logger.Debug("enable monitor", monitor)
+				if monitorCfg.Primary {
+					primaryMonitorID = monitor.ID
+				}
+				enabledMonitors = append(enabledMonitors, monitor)
+				//所有可设置的值都设置为配置文件中的值
+				monitor.X = monitorCfg.X
+				monitor.Y = monitorCfg.Y
+				monitor.Rotation = monitorCfg.Rotation
+				monitor.Reflect = monitorCfg.Reflect
+
+				// monitorCfg 中的宽和高是经过 rotation 调整的
+				width := monitorCfg.Width
+				height := monitorCfg.Height
+				swapWidthHeightWithRotation(monitorCfg.Rotation, &width, &height)
+				mode := monitor.selectMode(width, height, monitorCfg.RefreshRate)
+				monitor.setModeNoEmitChanged(mode)
+				monitor.Enabled = true
+			} else {
+				logger.Debug("disable monitor", monitor)
+				monitor.Enabled = false
+			}
+		}
+	}
+	return
+}
+
 type applyFailed struct {
 	reason   string
 	err      error
@@ -2186,65 +2391,25 @@ func (err *applyFailed) Error() string {
 	return fmt.Sprintf("apply failed, reason: %v, original error: %v", err.reason, err.err)
 }
 
+// getDefaultPrimaryMonitor 获取默认的主屏，规则见 core.GetPrimaryMonitor
 func (m *Manager) getDefaultPrimaryMonitor(monitors []*Monitor) *Monitor {
-	if len(monitors) == 0 {
-		return nil
-	}
 	builtinMonitor := m.getBuiltinMonitor()
-	if builtinMonitor != nil && Monitors(monitors).GetById(builtinMonitor.ID) != nil {
-		return builtinMonitor
+	coreMonitors := make([]core.Monitor, len(monitors))
+	for i, monitor := range monitors {
+		coreMonitors[i] = monitor.toCoreMonitor()
+		coreMonitors[i].Builtin = builtinMonitor != nil && monitor.ID == builtinMonitor.ID
 	}
-
-	monitor := m.getPriorMonitor(monitors)
-	return monitor
-}
-
-func (m *Manager) getMonitorConnectTime(name string) time.Time {
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
-	return m.sysConfig.Config.Cache.ConnectTime[name]
-}
-
-// getPriorMonitor 获取优先级最高的显示器，用于作为主屏。
-func (m *Manager) getPriorMonitor(monitors []*Monitor) *Monitor {
-	if len(monitors) == 0 {
+	primary, ok := core.GetPrimaryMonitor(coreMonitors, m.getMonitorConnectTime)
+	if !ok {
 		return nil
 	}
-	sort.Slice(monitors, func(i, j int) bool {
-		mi := monitors[i]
-		mj := monitors[j]
-
-		pi := getPortPriority(mi.Name)
-		pj := getPortPriority(mj.Name)
-
-		// 多字段排序
-		// 按优先级从小到大排序，如果优先级相同，按最后连接时间从早到晚排序。
-		if pi == pj {
-			ti := m.getMonitorConnectTime(mi.Name)
-			tj := m.getMonitorConnectTime(mj.Name)
-			return ti.Before(tj)
-		}
-		return pi < pj
-	})
-	return monitors[0]
+	return Monitors(monitors).GetById(primary.ID)
 }
 
-// getPortType 根据显示器名称判断出端口类型，比如 vga，hdmi，edp 等。
-func getPortType(name string) string {
-	i := strings.IndexRune(name, '-')
-	if i != -1 {
-		name = name[0:i]
-	}
-	return strings.ToLower(name)
-}
-
-func getPortPriority(name string) int {
-	portType := getPortType(name)
-	p, ok := _monitorTypePriority[portType]
-	if ok {
-		return p
-	}
-	return priorityOther
+func (m *Manager) getMonitorConnectTime(name string) time.Time {
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
+	return m.sysConfig.Config.Cache.ConnectTime[name]
 }
 
 func (m *Manager) getMonitorsId() monitorsId {
@@ -2437,21 +2602,24 @@ func (m *Manager) initTouchMap() {
 		return
 	}
 
-	err := jsonUnmarshal(value, &m.touchscreenMap)
+	touchscreenMap, err := core.ParseTouchscreenMap(value)
 	if err != nil {
 		logger.Warningf("[initTouchMap] unmarshal (%s) failed: %v",
 			value, err)
 		return
 	}
+	m.touchscreenMap = touchscreenMap
 
-	for touchUUID, v := range m.touchscreenMap {
-		for _, t := range m.Touchscreens {
-			if t.UUID == touchUUID {
-				m.TouchMap[t.UUID] = v.OutputName
-				break
-			}
-		}
+	m.syncTouchMapNoLock(m.getConnectedMonitors())
+}
+
+// syncTouchMapNoLock 根据映射配置更新属性 TouchMap，只包含已连接的触摸屏和显示器
+func (m *Manager) syncTouchMapNoLock(monitors Monitors) {
+	touchscreens := make([]string, len(m.Touchscreens))
+	for i, touch := range m.Touchscreens {
+		touchscreens[i] = touch.UUID
 	}
+	m.setPropTouchMap(core.GetTouchMap(m.touchscreenMap, touchscreens, monitors.toCoreMonitors()))
 }
 
 func (m *Manager) doSetTouchMap(monitor0 *Monitor, touchUUID string) error {
@@ -2478,7 +2646,8 @@ func (m *Manager) doSetTouchMap(monitor0 *Monitor, touchUUID string) error {
 		}
 	}
 
-	if monitor0.Enabled {
+	// monitor0 为 nil 时禁用触摸屏
+	if monitor0 != nil && monitor0.Enabled {
 		matrix := genTransformationMatrix(monitor0.X, monitor0.Y, monitor0.Width, monitor0.Height, monitor0.Rotation|monitor0.Reflect)
 
 		for _, touchID := range touchIDs {
@@ -2522,40 +2691,27 @@ func (m *Manager) doSetTouchMap(monitor0 *Monitor, touchUUID string) error {
 	return nil
 }
 
-func (m *Manager) updateTouchscreenMap(outputName string, touchUUID string, auto bool) {
-	var err error
-
+func (m *Manager) updateTouchscreenMap(monitor *Monitor, touchUUID string, auto bool) {
 	m.touchscreenMap[touchUUID] = touchscreenMapValue{
-		OutputName: outputName,
-		Auto:       auto,
+		OutputName:  monitor.Name,
+		Auto:        auto,
+		MonitorUuid: monitor.uuid,
 	}
 	m.settings.SetString(gsKeyMapOutput, jsonMarshal(m.touchscreenMap))
-
-	m.TouchMap[touchUUID] = outputName
-
-	err = m.emitPropChangedTouchMap(m.TouchMap)
-	if err != nil {
-		logger.Warning("failed to emit TouchMap PropChanged:", err)
-	}
+	m.syncTouchMapNoLock(m.getConnectedMonitors())
 }
 
 func (m *Manager) removeTouchscreenMap(touchUUID string) {
 	delete(m.touchscreenMap, touchUUID)
 	m.settings.SetString(gsKeyMapOutput, jsonMarshal(m.touchscreenMap))
-
-	delete(m.TouchMap, touchUUID)
-
-	err := m.emitPropChangedTouchMap(m.TouchMap)
-	if err != nil {
-		logger.Warning("failed to emit TouchMap PropChanged:", err)
-	}
+	m.syncTouchMapNoLock(m.getConnectedMonitors())
 }
 
 func (m *Manager) associateTouch(monitor *Monitor, touchUUID string, auto bool) error {
 	m.PropsMu.Lock()
 	defer m.PropsMu.Unlock()
 
-	if v, ok := m.touchscreenMap[touchUUID]; ok && v.OutputName == monitor.Name {
+	if v, ok := m.touchscreenMap[touchUUID]; ok && v.MonitorUuid == monitor.uuid && v.OutputName == monitor.Name {
 		return nil
 	}
 
@@ -2565,7 +2721,7 @@ func (m *Manager) associateTouch(monitor *Monitor, touchUUID string, auto bool)
 		return err
 	}
 
-	m.updateTouchscreenMap(monitor.Name, touchUUID, auto)
+	m.updateTouchscreenMap(monitor, touchUUID, auto)
 
 	return nil
 }
@@ -2631,26 +2787,20 @@ func (m *Manager) loadSysConfig() {
 	if err != nil {
 		logger.Warning(err)
 		// 修正一下空配置
-		m.sysConfig.fix()
+		m.sysConfig.Fix()
 	} else {
-		m.sysConfig.copyFrom(cfg)
-	}
-}
-
-func (c *SysRootConfig) fix() {
-	cfg := &c.Config
-	// 默认显示模式为复制模式
-	if cfg.DisplayMode == DisplayModeUnknown || cfg.DisplayMode == DisplayModeCustom {
-		cfg.DisplayMode = DisplayModeMirror
-	}
-	for _, screenConfig := range cfg.Screens {
-		screenConfig.fix()
+		m.sysConfig.CopyFrom(cfg)
 	}
 }
 
 // 无需对结果再次地调用 fix 方法
 func (m *Manager) getSysConfig() (*SysRootConfig, error) {
-	cfgJson, err := m.sysDisplay.GetConfig(0)
+	return readSysConfig(m.sysDisplay)
+}
+
+// readSysConfig 从系统级 display 服务读取配置
+func readSysConfig(sysDisplay sysdisplay.Display) (*SysRootConfig, error) {
+	cfgJson, err := sysDisplay.GetConfig(0)
 	if err != nil {
 		return nil, err
 	}
@@ -2659,14 +2809,14 @@ func (m *Manager) getSysConfig() (*SysRootConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	rootCfg.fix()
+	rootCfg.Fix()
 	return &rootCfg, nil
 }
 
 // saveSysConfig 保存系统级配置
 func (m *Manager) saveSysConfig(reason string) error {
-	m.sysConfig.mu.Lock()
-	defer m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
 
 	err := m.saveSysConfigNoLock(reason)
 	return err
@@ -2709,13 +2859,13 @@ func (m *Manager) setMonitorFillMode(monitor *Monitor, fillMode string) error {
 		return errors.New("monitor do not support set fill mode")
 	}
 
-	m.sysConfig.mu.Lock()
+	m.sysConfig.Mu.Lock()
 	cfg := &m.sysConfig.Config
 	fillModeKey := monitor.generateFillModeKey()
 	if fillMode == "" {
 		fillMode = cfg.FillModes[fillModeKey]
 	}
-	m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Unlock()
 	if fillMode == "" {
 		fillMode = fillModeDefault
 	}
@@ -2727,13 +2877,13 @@ func (m *Manager) setMonitorFillMode(monitor *Monitor, fillMode string) error {
 		return err
 	}
 
-	m.sysConfig.mu.Lock()
+	m.sysConfig.Mu.Lock()
 	if cfg.FillModes == nil {
 		cfg.FillModes = make(map[string]string)
 	}
 	cfg.FillModes[fillModeKey] = fillMode
 	err = m.saveSysConfigNoLock("fill mode changed")
-	m.sysConfig.mu.Unlock()
+	m.sysConfig.Mu.Unlock()
 
 	return err
 }
@@ -2777,100 +2927,95 @@ func (m *Manager) handleTouchscreenChanged() {
 	logger.Debugf("touchscreens changed %#v", m.Touchscreens)
 
 	monitors := m.getConnectedMonitors()
+	m.PropsMu.RLock()
+	policies := m.touchscreenPolicies
+	m.PropsMu.RUnlock()
 
-	// 清除已拔下触摸屏的配置
-	for uuid := range m.touchscreenMap {
-		found := false
-		for _, touch := range m.Touchscreens {
-			if touch.UUID == uuid {
-				found = true
-				break
+	// 只有一个显示器时总是映射到这个显示器，已有其他显示器的配置时不修改配置
+	if len(m.Touchscreens) == 1 && len(monitors) == 1 {
+		touch := m.Touchscreens[0]
+		v, ok := m.touchscreenMap[touch.UUID]
+		if ok && getTouchscreenMapMonitor(v, monitors) == nil {
+			logger.Debugf("assigned %s to %s, only one monitor", touch.UUID, monitors[0].Name)
+			err := m.doSetTouchMap(monitors[0], touch.UUID)
+			if err != nil {
+				logger.Warning("failed to map touchscreen:", err)
+			}
+		} else {
+			err := m.associateTouch(monitors[0], touch.UUID, true)
+			if err != nil {
+				logger.Warning(err)
 			}
 		}
-		if !found {
-			m.removeTouchscreenMap(uuid)
-		}
-	}
-
-	if len(m.Touchscreens) == 1 && len(monitors) == 1 {
-		m.associateTouch(monitors[0], m.Touchscreens[0].UUID, true)
+		m.PropsMu.Lock()
+		m.syncTouchMapNoLock(monitors)
+		m.PropsMu.Unlock()
+		return
 	}
 
 	for _, touch := range m.Touchscreens {
 		// 有配置，直接使配置生效
 		if v, ok := m.touchscreenMap[touch.UUID]; ok {
-			monitor := monitors.GetByName(v.OutputName)
-			if monitor != nil {
-				logger.Debugf("assigned %s to %s, cfg", touch.UUID, v.OutputName)
+			monitor := getTouchscreenMapMonitor(v, monitors)
+			if monitor != nil && (monitor.Enabled || policies.disableWhenTargetDisabled) {
+				logger.Debugf("assigned %s to %s, cfg", touch.UUID, monitor.Name)
 				err := m.doSetTouchMap(monitor, touch.UUID)
 				if err != nil {
 					logger.Warning("failed to map touchscreen:", err)
 				}
+				if v.MonitorUuid != monitor.uuid || v.OutputName != monitor.Name {
+					// 旧配置中没有 uuid，或者显示器换了接口
+					m.PropsMu.Lock()
+					m.updateTouchscreenMap(monitor, touch.UUID, v.Auto)
+					m.PropsMu.Unlock()
+				}
 				continue
 			}
 
-			// else 配置中的显示器不存在，忽略配置并删除
-			m.removeTouchscreenMap(touch.UUID)
-		}
-
-		if touch.outputName != "" {
-			logger.Debugf("assigned %s to %s, WL_OUTPUT", touch.UUID, touch.outputName)
-			monitor := monitors.GetByName(touch.outputName)
-			if monitor == nil {
-				logger.Warning("WL_OUTPUT not found")
-				continue
-			}
-			err := m.associateTouch(monitor, touch.UUID, true)
-			if err != nil {
-				logger.Warning(err)
-			}
-			continue
-		}
-
-		// 物理大小匹配
-		assigned := false
-		for _, monitor := range monitors {
-			logger.Debugf("monitor %s w %d h %d, touch %s w %d h %d",
-				monitor.Name, monitor.MmWidth, monitor.MmHeight,
-				touch.UUID, uint32(math.Round(touch.width)), uint32(math.Round(touch.height)))
-
-			if monitor.MmWidth == uint32(math.Round(touch.width)) && monitor.MmHeight == uint32(math.Round(touch.height)) {
-				logger.Debugf("assigned %s to %s, phy size", touch.UUID, monitor.Name)
-				err := m.associateTouch(monitor, touch.UUID, true)
+			if monitor == nil && !v.Auto && policies.disableWhenTargetDisabled {
+				logger.Debugf("disable %s, monitor %s not connected", touch.UUID, v.OutputName)
+				err := m.doSetTouchMap(nil, touch.UUID)
 				if err != nil {
-					logger.Warning(err)
+					logger.Warning("failed to disable touchscreen:", err)
 				}
-				assigned = true
-				break
+				continue
 			}
-		}
-		if assigned {
-			continue
-		}
 
-		// 有内置显示器，且触摸屏不是通过 USB 连接，关联内置显示器
-		if m.builtinMonitor != nil {
-			if touch.busType != BusTypeUSB {
-				logger.Debugf("assigned %s to %s, builtin", touch.UUID, m.builtinMonitor.Name)
-				err := m.associateTouch(m.builtinMonitor, touch.UUID, true)
+			// 配置中的显示器不可用，按策略临时映射，保留配置
+			monitor, policy := policies.autoMap(touch, monitors, m.builtinMonitor, m.Primary)
+			if monitor != nil {
+				logger.Debugf("assigned %s to %s, %s, keep cfg", touch.UUID, monitor.Name, policy)
+				err := m.doSetTouchMap(monitor, touch.UUID)
 				if err != nil {
-					logger.Warning(err)
+					logger.Warning("failed to map touchscreen:", err)
 				}
-				continue
 			}
+			continue
 		}
 
-		// 关联主显示器，不保存主显示器不保存配置，并显示配置 Dialog
-		monitor := monitors.GetByName(m.Primary)
+		monitor, policy := policies.autoMap(touch, monitors, m.builtinMonitor, m.Primary)
 		if monitor == nil {
-			logger.Warningf("primary output %s not found", m.Primary)
-		} else {
+			logger.Warningf("no monitor for touchscreen %s", touch.UUID)
+			continue
+		}
+		logger.Debugf("assigned %s to %s, %s", touch.UUID, monitor.Name, policy)
+		if policy == touchPolicyPrimary {
+			// 关联主显示器不保存配置，并显示配置 Dialog
 			err := m.doSetTouchMap(monitor, touch.UUID)
 			if err != nil {
 				logger.Warning("failed to map touchscreen:", err)
 			}
+			continue
+		}
+		err := m.associateTouch(monitor, touch.UUID, true)
+		if err != nil {
+			logger.Warning(err)
 		}
 	}
+
+	m.PropsMu.Lock()
+	m.syncTouchMapNoLock(monitors)
+	m.PropsMu.Unlock()
 }
 
 /* 根据从内核获取的屏幕的初始状态(屏幕的方向)，旋转桌面到对应的方向 */
@@ -2887,12 +3032,7 @@ func (m *Manager) initScreenRotation() {
 			return
 		}
 
-		startBuildInScreenRotationMutex.Lock()
-		defer startBuildInScreenRotationMutex.Unlock()
-		rotationRotate, ok := rotationScreenValue[strings.TrimSpace(screenRatationStatus)]
-		if ok {
-			m.startBuildInScreenRotation(rotationRotate)
-		}
+		m.handleSensorOrientation(strings.TrimSpace(screenRatationStatus))
 	}
 }
 
@@ -2923,6 +3063,9 @@ func (m *Manager) syncPropBrightness() {
 
 func (m *Manager) getRateFilter() RateFilterMap {
 	data := make(RateFilterMap)
+	if m.settings == nil {
+		return data
+	}
 	jsonStr := m.settings.GetString(gsKeyRateFilter)
 	err := json.Unmarshal([]byte(jsonStr), &data)
 	if err != nil {
@@ -2964,12 +3107,7 @@ func (m *Manager) listenRotateSignal() {
 
 			if rotationScreenTimer == nil {
 				rotationScreenTimer = time.AfterFunc(time.Millisecond*time.Duration(m.rotateScreenTimeDelay), func() {
-					startBuildInScreenRotationMutex.Lock()
-					defer startBuildInScreenRotationMutex.Unlock()
-					rotationRotate, ok := rotationScreenValue[strings.TrimSpace(rotateScreenValue)]
-					if ok {
-						m.startBuildInScreenRotation(rotationRotate)
-					}
+					m.handleSensorOrientation(strings.TrimSpace(rotateScreenValue))
 				})
 			} else {
 				rotationScreenTimer.Reset(time.Millisecond * time.Duration(m.rotateScreenTimeDelay))
@@ -2982,32 +3120,23 @@ func (m *Manager) startBuildInScreenRotation(latestRotationValue uint16) {
 	// 判断旋转信号值是否符合要求
 	if latestRotationValue != randr.RotationRotate0 &&
 		latestRotationValue != randr.RotationRotate90 &&
+		latestRotationValue != randr.RotationRotate180 &&
 		latestRotationValue != randr.RotationRotate270 {
 		logger.Warningf("get Rotation screen value failed: %d", latestRotationValue)
 		return
 	}
 
 	if m.builtinMonitor != nil {
-		err := m.builtinMonitor.SetRotation(latestRotationValue)
-		if err != nil {
-			logger.Warning("call SetRotation failed:", err)
-			return
-		}
-
-		// 使旋转后配置生效
-		err = m.ApplyChanges()
-		if err != nil {
-			logger.Warning("call ApplyChanges failed:", err)
-			return
-		}
-
-		err = m.Save()
+		// 自动旋转不保存配置，避免传感器的误报覆盖手动设置的布局
+		err := m.applyAutoRotation(m.builtinMonitor, latestRotationValue)
 		if err != nil {
-			logger.Warning("call Save failed:", err)
+			logger.Warning("failed to apply auto rotation:", err)
 			return
 		}
 
+		m.builtinMonitor.PropsMu.Lock()
 		m.builtinMonitor.setPropCurrentRotateMode(RotationFinishModeAuto)
+		m.builtinMonitor.PropsMu.Unlock()
 	}
 }
 
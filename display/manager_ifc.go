@@ -11,21 +11,31 @@ import (
 
 	"github.com/godbus/dbus/v5"
 	"github.com/linuxdeepin/go-lib/dbusutil"
-	"github.com/linuxdeepin/go-lib/strv"
+	"github.com/linuxdeepin/startdde/display/brightness"
 )
 
 func (m *Manager) GetInterfaceName() string {
 	return dbusInterface
 }
 
-func (m *Manager) ApplyChanges() *dbus.Error {
+// ApplyChanges 应用修改，返回事务 id，需要在超时前调用 ConfirmChanges 确认或者调用 Save 保存，
+// 否则恢复到应用之前的布局，并发送 ChangesReverted 信号。返回 0 表示不需要确认。
+func (m *Manager) ApplyChanges() (id uint32, busErr *dbus.Error) {
 	logger.Debug("dbus call ApplyChanges")
-	err := m.applyChanges()
+	id, err := m.applyChanges()
+	return id, dbusutil.ToError(err)
+}
+
+// ConfirmChanges 确认 ApplyChanges 应用的修改，阻止超时恢复。
+func (m *Manager) ConfirmChanges(id uint32) *dbus.Error {
+	logger.Debug("dbus call ConfirmChanges", id)
+	err := m.confirmChanges(id)
 	return dbusutil.ToError(err)
 }
 
 func (m *Manager) ResetChanges() *dbus.Error {
 	logger.Debug("dbus call ResetChanges")
+	m.applyTx.cancel()
 	m.PropsMu.Lock()
 	if !m.HasChanged {
 		m.PropsMu.Unlock()
@@ -107,9 +117,38 @@ func (m *Manager) AssociateTouchByUUID(outputName, touchUUID string) *dbus.Error
 	return dbusutil.ToError(err)
 }
 
+// AssociateTablet 把数位板映射到显示器 outputName，outputName 为空时映射到整个屏幕。
+// keepAspect 为 true 时限制数位板的输入区域，使其宽高比和显示器相同。
+func (m *Manager) AssociateTablet(outputName, uuid string, keepAspect bool) *dbus.Error {
+	logger.Debug("dbus call AssociateTablet", outputName, uuid, keepAspect)
+	err := m.associateTablet(outputName, uuid, keepAspect)
+	return dbusutil.ToError(err)
+}
+
+// SetTabletArea 限制数位板的输入区域，area 为相对整个数位板的比例 [x1, y1, x2, y2]，为空时使用整个数位板。
+func (m *Manager) SetTabletArea(uuid string, area []float64) *dbus.Error {
+	logger.Debug("dbus call SetTabletArea", uuid, area)
+	err := m.setTabletArea(uuid, area)
+	return dbusutil.ToError(err)
+}
+
+// ListTouchscreenMaps 列出保存的触摸屏映射配置，JSON 格式，包括未连接的触摸屏和显示器的配置。
+func (m *Manager) ListTouchscreenMaps() (maps string, busErr *dbus.Error) {
+	maps, err := m.listTouchscreenMaps()
+	return maps, dbusutil.ToError(err)
+}
+
+// ClearTouchscreenMap 清除触摸屏的映射配置并按策略重新映射，touchUUID 为空时清除所有配置。
+func (m *Manager) ClearTouchscreenMap(touchUUID string) *dbus.Error {
+	logger.Debug("dbus call ClearTouchscreenMap", touchUUID)
+	err := m.clearTouchscreenMap(touchUUID)
+	return dbusutil.ToError(err)
+}
+
 // ChangeBrightness 通过键盘控制所有显示器一起亮度加或减，保存配置。
 func (m *Manager) ChangeBrightness(raised bool) *dbus.Error {
 	logger.Debug("dbus call ChangeBrightness", raised)
+	m.yieldBrightnessSchedule()
 	err := m.changeBrightness(raised)
 	return dbusutil.ToError(err)
 }
@@ -141,30 +180,113 @@ func (m *Manager) ListOutputsCommonModes() ([]ModeInfo, *dbus.Error) {
 	commonSizes := getMonitorsCommonSizes(monitors)
 	result := make([]ModeInfo, len(commonSizes))
 	for i, size := range commonSizes {
-		result[i] = getFirstModeBySize(monitors[0].Modes, size.width, size.height)
+		result[i] = getFirstModeBySize(monitors[0].Modes, size.Width, size.Height)
 	}
 	return result, nil
 }
 
-// ModifyConfigName 废弃方法
+// ModifyConfigName 重命名布局，同 RenameProfile
 func (m *Manager) ModifyConfigName(name, newName string) *dbus.Error {
-	return dbusutil.ToError(errors.New("obsoleted method"))
+	logger.Debug("dbus call ModifyConfigName", name, newName)
+	err := m.renameProfile(name, newName)
+	return dbusutil.ToError(err)
 }
 
-// DeleteCustomMode 废弃方法
+// DeleteCustomMode 删除布局，同 DeleteProfile
 func (m *Manager) DeleteCustomMode(name string) *dbus.Error {
-	return dbusutil.ToError(errors.New("obsoleted method"))
+	logger.Debug("dbus call DeleteCustomMode", name)
+	err := m.deleteProfile(name)
+	return dbusutil.ToError(err)
+}
+
+// SaveProfile 把当前显示布局保存为命名布局，同名布局会被覆盖。
+func (m *Manager) SaveProfile(name string) *dbus.Error {
+	logger.Debug("dbus call SaveProfile", name)
+	err := m.saveProfile(name)
+	return dbusutil.ToError(err)
 }
 
-// RefreshBrightness 重置亮度，主要被 session/power 模块调用。从配置恢复亮度。
+// ApplyProfile 应用当前显示器组合下的命名布局
+func (m *Manager) ApplyProfile(name string) *dbus.Error {
+	logger.Debug("dbus call ApplyProfile", name)
+	err := m.applyProfile(name)
+	return dbusutil.ToError(err)
+}
+
+func (m *Manager) RenameProfile(name, newName string) *dbus.Error {
+	logger.Debug("dbus call RenameProfile", name, newName)
+	err := m.renameProfile(name, newName)
+	return dbusutil.ToError(err)
+}
+
+func (m *Manager) DeleteProfile(name string) *dbus.Error {
+	logger.Debug("dbus call DeleteProfile", name)
+	err := m.deleteProfile(name)
+	return dbusutil.ToError(err)
+}
+
+// ListProfiles 列出当前显示器组合下保存的命名布局
+func (m *Manager) ListProfiles() ([]string, *dbus.Error) {
+	logger.Debug("dbus call ListProfiles")
+	return m.listProfiles(), nil
+}
+
+// GetEventHistory 返回最近的显示事件记录，JSON 格式，按时间先后排列。
+func (m *Manager) GetEventHistory() (history string, busErr *dbus.Error) {
+	logger.Debug("dbus call GetEventHistory")
+	history, err := m.getEventHistory()
+	return history, dbusutil.ToError(err)
+}
+
+// DumpState 返回当前显示状态的快照，JSON 格式，用于收集诊断信息。
+func (m *Manager) DumpState() (state string, busErr *dbus.Error) {
+	logger.Debug("dbus call DumpState")
+	state, err := m.dumpState()
+	return state, dbusutil.ToError(err)
+}
+
+// ExportConfig 导出当前这组显示器的显示配置，JSON 格式，显示器用 EDID 信息标识。
+func (m *Manager) ExportConfig() (config string, busErr *dbus.Error) {
+	logger.Debug("dbus call ExportConfig")
+	config, err := m.exportConfig()
+	return config, dbusutil.ToError(err)
+}
+
+// ImportConfig 导入由 ExportConfig 导出的显示配置并应用，已连接的显示器需要和配置中的相匹配。
+func (m *Manager) ImportConfig(config string) *dbus.Error {
+	logger.Debug("dbus call ImportConfig")
+	err := m.importConfig(config)
+	return dbusutil.ToError(err)
+}
+
+// RefreshBrightness 重置亮度，主要被 session/power 模块调用。
+// 用 DDC/CI 调节亮度的显示器读取显示器上实际的亮度，开启自适应亮度时恢复自动调节，其他显示器从配置恢复亮度。
 func (m *Manager) RefreshBrightness() *dbus.Error {
 	logger.Debug("dbus call RefreshBrightness")
 	monitors := m.getConnectedMonitors()
 	monitorsId := monitors.getMonitorsId()
+	var adaptiveMonitors Monitors
+	if m.resumeAdaptiveBrightness() {
+		adaptiveMonitors = m.getAdaptiveBrightnessMonitors()
+	}
 	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitorsId, monitors)
 	for _, config := range configs {
-		if config.Enabled {
-			err := m.setBrightness(config.Name, config.Brightness)
+		if config.Enabled && adaptiveMonitors.GetByName(config.Name) == nil {
+			value := config.Brightness
+			monitor := monitors.GetByName(config.Name)
+			if monitor != nil && m.getMonitorBrightnessSetter(monitor) == brightness.SetterDDCCI {
+				// 用户可能通过显示器上的按键调节过亮度
+				monitor.PropsMu.RLock()
+				edidBase64 := monitor.edidBase64
+				monitor.PropsMu.RUnlock()
+				br, err := brightness.GetDDCCI(edidBase64)
+				if err != nil {
+					logger.Warning(err)
+				} else {
+					value = br
+				}
+			}
+			err := m.setBrightness(config.Name, value)
 			if err != nil {
 				logger.Warning(err)
 			}
@@ -186,6 +308,7 @@ func (m *Manager) SetAndSaveBrightness(outputName string, value float64) *dbus.E
 	if !can {
 		return dbusutil.ToError(fmt.Errorf("the port %s cannot set brightness", outputName))
 	}
+	m.yieldBrightnessSchedule()
 	err := m.setBrightnessAndSync(outputName, value)
 	if err != nil {
 		logger.Warning(err)
@@ -199,6 +322,7 @@ func (m *Manager) SetAndSaveBrightness(outputName string, value float64) *dbus.E
 		logger.Warning(err)
 		return dbusutil.ToError(err)
 	}
+	m.learnAdaptiveBrightness(outputName, value)
 	return nil
 }
 
@@ -214,6 +338,7 @@ func (m *Manager) SetBrightness(outputName string, value float64) *dbus.Error {
 		return dbusutil.ToError(fmt.Errorf("the port %s cannot set brightness", outputName))
 	}
 
+	m.pauseAdaptiveBrightness()
 	err := m.setBrightnessAndSync(outputName, value)
 	if err != nil {
 		logger.Warning(err)
@@ -246,7 +371,12 @@ func (m *Manager) CanSetBrightness(outputName string) (bool, *dbus.Error) {
 			return false, nil
 		}
 	}
-	return true, nil
+
+	monitor := m.getConnectedMonitors().GetByName(outputName)
+	if monitor == nil {
+		return false, dbusutil.ToError(InvalidOutputNameError{Name: outputName})
+	}
+	return m.mm.canSetBrightness(m.getBrightnessTarget(monitor)), nil
 }
 
 func (m *Manager) getBuiltinMonitor() *Monitor {
@@ -283,33 +413,7 @@ func (m *Manager) SetColorTemperature(value int32) *dbus.Error {
 
 func (m *Manager) GetRealDisplayMode() (uint8, *dbus.Error) {
 	monitors := m.getConnectedMonitors()
-
-	mode := DisplayModeUnknown
-	var pairs strv.Strv
-	for _, m := range monitors {
-		if !m.Enabled {
-			continue
-		}
-
-		pair := fmt.Sprintf("%d,%d", m.X, m.Y)
-
-		// 左上角座标相同，是复制
-		if pairs.Contains(pair) {
-			mode = DisplayModeMirror
-		}
-
-		pairs = append(pairs, pair)
-	}
-
-	if mode == DisplayModeUnknown && len(pairs) != 0 {
-		if len(pairs) == 1 {
-			mode = DisplayModeOnlyOne
-		} else {
-			mode = DisplayModeExtend
-		}
-	}
-
-	return mode, nil
+	return toSysMonitorConfigs(monitors, "").GetRealDisplayMode(), nil
 }
 
 func (m *Manager) SupportSetColorTemperature() (bool, *dbus.Error) {
@@ -324,3 +428,39 @@ func (m *Manager) SetCustomColorTempTimePeriod(timePeriod string) *dbus.Error {
 	}
 	return nil
 }
+
+// SetBrightnessSchedule 设置亮度计划，JSON 格式，例如 [{"Time":"07:30","Level":0.8},{"Time":"sunset-30","Level":0.5}]，
+// Time 可以是 "HH:MM"，"sunrise" 或 "sunset"，后两者可以带以分钟为单位的偏移，空字符串表示关闭亮度计划。
+func (m *Manager) SetBrightnessSchedule(schedule string) *dbus.Error {
+	logger.Debug("dbus call SetBrightnessSchedule", schedule)
+	err := m.setBrightnessSchedule(schedule)
+	return dbusutil.ToError(err)
+}
+
+// SetAdaptiveBrightness 开启或关闭根据环境光传感器自动调节亮度
+func (m *Manager) SetAdaptiveBrightness(enabled bool) *dbus.Error {
+	logger.Debug("dbus call SetAdaptiveBrightness", enabled)
+	err := m.setAdaptiveBrightness(enabled)
+	return dbusutil.ToError(err)
+}
+
+// ResetAdaptiveBrightnessCurve 丢弃手动调节亮度时学习到的照度亮度曲线，恢复默认曲线
+func (m *Manager) ResetAdaptiveBrightnessCurve() *dbus.Error {
+	logger.Debug("dbus call ResetAdaptiveBrightnessCurve")
+	err := m.resetLightCurve()
+	return dbusutil.ToError(err)
+}
+
+// SetAllowedOrientations 设置允许自动旋转到的方向，可选值为 normal、left、right 和 bottom-up
+func (m *Manager) SetAllowedOrientations(orientations []string) *dbus.Error {
+	logger.Debug("dbus call SetAllowedOrientations", orientations)
+	err := m.setAllowedOrientations(orientations)
+	return dbusutil.ToError(err)
+}
+
+// SetPosture 设置设备姿态，可选值为 unknown、laptop、tablet 和 tent，由监听铰链角度或者平板模式开关的服务调用
+func (m *Manager) SetPosture(posture string) *dbus.Error {
+	logger.Debug("dbus call SetPosture", posture)
+	err := m.setPosture(posture)
+	return dbusutil.ToError(err)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/linuxdeepin/go-lib/strv"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 type ModeInfo struct {
@@ -21,6 +22,14 @@ type ModeInfo struct {
 	Rate   float64
 }
 
+func (mi ModeInfo) toCoreMode() core.Mode {
+	return core.Mode{
+		Width:  mi.Width,
+		Height: mi.Height,
+		Rate:   mi.Rate,
+	}
+}
+
 func (mi ModeInfo) isZero() bool {
 	return mi == ModeInfo{}
 }
@@ -261,17 +270,3 @@ func getFirstModeBySizeRate(modes []ModeInfo, width, height uint16, rate float64
 	}
 	return ModeInfo{}
 }
-
-type Size struct {
-	width  uint16
-	height uint16
-}
-
-func getSizeModeMap(modes []ModeInfo) map[Size][]uint32 {
-	result := make(map[Size][]uint32)
-	for _, modeInfo := range modes {
-		result[Size{modeInfo.Width, modeInfo.Height}] = append(
-			result[Size{modeInfo.Width, modeInfo.Height}], modeInfo.Id)
-	}
-	return result
-}
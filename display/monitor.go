@@ -18,6 +18,7 @@ import (
 	"github.com/linuxdeepin/go-lib/strv"
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 const (
@@ -68,6 +69,19 @@ type Monitor struct {
 	CurrentRotateMode uint8
 
 	oldRotation uint16
+	// base64 编码的 EDID，用于 DDC/CI 调节亮度
+	edidBase64 string
+
+	// 是否调节此显示器的色温，关闭后保持 6500K
+	ColorTemperatureEnabled bool
+	// 此显示器的色温相对于全局色温的偏移，单位是 K
+	ColorTemperatureOffset int32
+	// ICC 配置文件的路径，为空表示没有设置
+	ColorProfile string
+	// ICC 配置文件的内容
+	iccProfile []byte
+	// 加载 ICC 配置文件时显示器的 uuid，为空表示还没有加载
+	iccProfileUuid string
 
 	CurrentMode     ModeInfo
 	CurrentFillMode string `prop:"access:rw"`
@@ -136,6 +150,7 @@ func (m *Monitor) clone() *Monitor {
 		service:            m.service,
 		uuid:               m.uuid,
 		uuidV0:             m.uuidV0,
+		edidBase64:         m.edidBase64,
 		ID:                 m.ID,
 		Name:               m.Name,
 		Connected:          m.Connected,
@@ -352,6 +367,39 @@ func (m *Monitor) SetReflect(value uint16) *dbus.Error {
 	return nil
 }
 
+// SetColorTemperatureEnabled 设置是否调节此显示器的色温
+func (m *Monitor) SetColorTemperatureEnabled(enabled bool) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetColorTemperatureEnabled %v", m.ID, m.Name, enabled)
+	err := m.m.setMonitorColorTempEnabled(m, enabled)
+	return dbusutil.ToError(err)
+}
+
+// SetColorTemperatureOffset 设置此显示器的色温相对于全局色温的偏移
+func (m *Monitor) SetColorTemperatureOffset(offset int32) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetColorTemperatureOffset %v", m.ID, m.Name, offset)
+	err := m.m.setMonitorColorTempOffset(m, offset)
+	return dbusutil.ToError(err)
+}
+
+// SetColorProfile 设置此显示器的 ICC 配置文件，为空时清除
+func (m *Monitor) SetColorProfile(filename string) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetColorProfile %v", m.ID, m.Name, filename)
+	err := m.m.setMonitorColorProfile(m, filename)
+	return dbusutil.ToError(err)
+}
+
+// AddCustomMode 按 CVT 标准添加一个自定义模式，reducedBlanking 表示使用减少消隐
+func (m *Monitor) AddCustomMode(width, height uint16, rate float64, reducedBlanking bool) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call AddCustomMode %v %v %v %v", m.ID, m.Name, width, height, rate, reducedBlanking)
+	err := m.m.addMonitorCustomMode(m, CustomMode{
+		Width:           width,
+		Height:          height,
+		Rate:            rate,
+		ReducedBlanking: reducedBlanking,
+	})
+	return dbusutil.ToError(err)
+}
+
 func (m *Monitor) SetRotation(value uint16) *dbus.Error {
 	m.PropsMu.Lock()
 	defer m.PropsMu.Unlock()
@@ -443,6 +491,29 @@ func toSysMonitorConfigs(monitors []*Monitor, primary string) SysMonitorConfigs
 	return result
 }
 
+func (m *Monitor) toCoreMonitor() core.Monitor {
+	modes := make([]core.Mode, len(m.Modes))
+	for i, mode := range m.Modes {
+		modes[i] = mode.toCoreMode()
+	}
+	return core.Monitor{
+		ID:       m.ID,
+		UUID:     m.uuid,
+		UUIDV0:   m.uuidV0,
+		Name:     m.Name,
+		BestMode: m.BestMode.toCoreMode(),
+		Modes:    modes,
+	}
+}
+
+func (monitors Monitors) toCoreMonitors() []core.Monitor {
+	result := make([]core.Monitor, len(monitors))
+	for i, monitor := range monitors {
+		result[i] = monitor.toCoreMonitor()
+	}
+	return result
+}
+
 func (m *Monitor) toBasicSysConfig() *SysMonitorConfig {
 	return &SysMonitorConfig{
 		UUID: m.uuid,
@@ -466,6 +537,30 @@ func (m *Monitor) toSysConfig() *SysMonitorConfig {
 	}
 }
 
+// toPrevSysConfig 返回显示器在 DBus 修改之前的配置，没有修改时同 toSysConfig。
+func (m *Monitor) toPrevSysConfig() *SysMonitorConfig {
+	cfg := m.toSysConfig()
+	b := m.backup
+	if b == nil {
+		return cfg
+	}
+
+	width := b.Mode.Width
+	height := b.Mode.Height
+	swapWidthHeightWithRotation(b.Rotation, &width, &height)
+
+	cfg.Enabled = b.Enabled
+	cfg.X = b.X
+	cfg.Y = b.Y
+	cfg.Width = width
+	cfg.Height = height
+	cfg.Rotation = b.Rotation
+	cfg.Reflect = b.Reflect
+	cfg.RefreshRate = b.Mode.Rate
+	cfg.Brightness = b.Brightness
+	return cfg
+}
+
 func (m *Monitor) dumpInfoForDebug() {
 	logger.Debugf("dump info monitor %v %v, enabled: %v, uuid: %v, %v+%v,%vx%v, rotation: %v, reflect: %v, current mode: %+v",
 		m.ID,
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+)
+
+// 每个显示器的色温设置。全局的色温模式和色温值按显示模式保存，每个显示器可以关闭色温调节，
+// 比如校色过的外接显示器始终保持 6500K，也可以在全局色温上加一个偏移。
+// 设置按显示器的 uuid 保存在用户配置的 Monitors 中。
+
+const maxColorTempOffset = 5000
+
+func (c *UserMonitorConfig) fix() {
+	if c.ColorTemperatureOffset > maxColorTempOffset {
+		c.ColorTemperatureOffset = maxColorTempOffset
+	} else if c.ColorTemperatureOffset < -maxColorTempOffset {
+		c.ColorTemperatureOffset = -maxColorTempOffset
+	}
+}
+
+func getDefaultUserMonitorConfig() *UserMonitorConfig {
+	return &UserMonitorConfig{
+		ColorTemperatureEnabled: true,
+	}
+}
+
+// getMonitorColorTemp 根据全局色温计算显示器的色温，active 表示全局色温调节是否在起作用
+func getMonitorColorTemp(temperature int, active bool, cfg *UserMonitorConfig) int {
+	if !active {
+		return temperature
+	}
+	if !cfg.ColorTemperatureEnabled {
+		return defaultTemperatureManual
+	}
+	temperature += int(cfg.ColorTemperatureOffset)
+	if temperature < 1000 {
+		temperature = 1000
+	} else if temperature > 25000 {
+		temperature = 25000
+	}
+	return temperature
+}
+
+// isColorTempActive 全局色温调节是否在起作用，自定义模式不在设置的时间段内时不起作用
+func (m *Manager) isColorTempActive() bool {
+	m.PropsMu.RLock()
+	mode := m.ColorTemperatureMode
+	m.PropsMu.RUnlock()
+	switch mode {
+	case ColorTemperatureModeNone:
+		return false
+	case ColorTemperatureModeCustom:
+		return m.customColorTempFlag
+	}
+	return true
+}
+
+func (m *Manager) getUserMonitorConfig(uuid string) *UserMonitorConfig {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	cfg := m.userConfig.Monitors[uuid]
+	if cfg == nil {
+		return getDefaultUserMonitorConfig()
+	}
+	cfgCp := *cfg
+	return &cfgCp
+}
+
+// getMonitorColorTemperatureValue 返回显示器实际使用的色温
+func (m *Manager) getMonitorColorTemperatureValue(monitor *Monitor) int {
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	monitor.PropsMu.RUnlock()
+	return getMonitorColorTemp(m.getColorTemperatureValue(), m.isColorTempActive(), m.getUserMonitorConfig(uuid))
+}
+
+// loadMonitorColorTemp 从用户配置加载显示器的色温设置到属性
+func (m *Manager) loadMonitorColorTemp(monitor *Monitor) {
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	monitor.PropsMu.RUnlock()
+	cfg := m.getUserMonitorConfig(uuid)
+
+	monitor.PropsMu.Lock()
+	monitor.setPropColorTemperatureEnabled(cfg.ColorTemperatureEnabled)
+	monitor.setPropColorTemperatureOffset(cfg.ColorTemperatureOffset)
+	monitor.PropsMu.Unlock()
+}
+
+func (m *Manager) loadMonitorsColorTemp() {
+	for _, monitor := range m.getConnectedMonitors() {
+		m.loadMonitorColorTemp(monitor)
+	}
+}
+
+// updateUserMonitorConfig 修改并保存显示器的用户配置
+func (m *Manager) updateUserMonitorConfig(uuid string, fn func(cfg *UserMonitorConfig)) error {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	if m.userConfig.Monitors == nil {
+		m.userConfig.Monitors = make(map[string]*UserMonitorConfig)
+	}
+	cfg := m.userConfig.Monitors[uuid]
+	if cfg == nil {
+		cfg = getDefaultUserMonitorConfig()
+		m.userConfig.Monitors[uuid] = cfg
+	}
+	fn(cfg)
+	cfg.fix()
+	return m.saveUserConfigNoLock()
+}
+
+// setMonitorColorTemp 修改并保存显示器的色温设置，然后重新设置色温
+func (m *Manager) setMonitorColorTemp(monitor *Monitor, fn func(cfg *UserMonitorConfig)) error {
+	monitor.PropsMu.RLock()
+	uuid := monitor.uuid
+	name := monitor.Name
+	br := monitor.Brightness
+	monitor.PropsMu.RUnlock()
+
+	err := m.updateUserMonitorConfig(uuid, fn)
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	m.loadMonitorColorTemp(monitor)
+	_setColorTempMu.Lock()
+	defer _setColorTempMu.Unlock()
+	return m.setBrightness(name, br)
+}
+
+// dbus 上导出的方法
+func (m *Manager) setMonitorColorTempEnabled(monitor *Monitor, enabled bool) error {
+	return m.setMonitorColorTemp(monitor, func(cfg *UserMonitorConfig) {
+		cfg.ColorTemperatureEnabled = enabled
+	})
+}
+
+// dbus 上导出的方法
+func (m *Manager) setMonitorColorTempOffset(monitor *Monitor, offset int32) error {
+	if offset > maxColorTempOffset || offset < -maxColorTempOffset {
+		return fmt.Errorf("offset %d out of range [%d, %d]", offset, -maxColorTempOffset, maxColorTempOffset)
+	}
+	return m.setMonitorColorTemp(monitor, func(cfg *UserMonitorConfig) {
+		cfg.ColorTemperatureOffset = offset
+	})
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getMonitorColorTemp(t *testing.T) {
+	cfg := getDefaultUserMonitorConfig()
+	assert.Equal(t, 4000, getMonitorColorTemp(4000, true, cfg))
+	assert.Equal(t, 6500, getMonitorColorTemp(6500, false, cfg))
+
+	cfg.ColorTemperatureOffset = -500
+	assert.Equal(t, 3500, getMonitorColorTemp(4000, true, cfg))
+	// 全局色温调节不起作用时不偏移
+	assert.Equal(t, 6500, getMonitorColorTemp(6500, false, cfg))
+	assert.Equal(t, 1000, getMonitorColorTemp(1200, true, cfg))
+
+	cfg.ColorTemperatureOffset = 5000
+	assert.Equal(t, 25000, getMonitorColorTemp(24000, true, cfg))
+
+	// 关闭色温调节的显示器保持 6500K
+	cfg.ColorTemperatureEnabled = false
+	assert.Equal(t, defaultTemperatureManual, getMonitorColorTemp(4000, true, cfg))
+}
+
+func Test_UserMonitorConfig_fix(t *testing.T) {
+	cfg := UserConfig{
+		Monitors: map[string]*UserMonitorConfig{
+			"a": {ColorTemperatureEnabled: true, ColorTemperatureOffset: 9000},
+			"b": {ColorTemperatureOffset: -9000},
+			"c": nil,
+		},
+	}
+	cfg.fix()
+	assert.Len(t, cfg.Monitors, 2)
+	assert.Equal(t, int32(maxColorTempOffset), cfg.Monitors["a"].ColorTemperatureOffset)
+	assert.Equal(t, int32(-maxColorTempOffset), cfg.Monitors["b"].ColorTemperatureOffset)
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 命名布局（profile）的实现。
+// 布局按 monitorsId 保存在系统级配置 SysScreenConfig.Profiles 中，同一组显示器可以保存多个布局，
+// 比如 "Desk"，"Presenting"，应用布局时会把布局中的配置写入对应显示模式的配置中。
+
+var errProfileNameEmpty = errors.New("profile name is empty")
+
+type profileNotFoundError = core.ProfileNotFoundError
+
+func normalizeProfileName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errProfileNameEmpty
+	}
+	return name, nil
+}
+
+// saveProfile 把当前的显示布局保存为名称为 name 的布局，同名布局会被覆盖。
+func (m *Manager) saveProfile(name string) error {
+	name, err := normalizeProfileName(name)
+	if err != nil {
+		return err
+	}
+
+	monitors := m.getConnectedMonitors()
+	if len(monitors) == 0 {
+		return errors.New("no monitor connected")
+	}
+	monitorsId := monitors.getMonitorsId()
+
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	configs := toSysMonitorConfigs(monitors, primary)
+	configs.Sort()
+
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	screenCfg.SetProfile(name, &SysProfileConfig{
+		DisplayMode: displayMode,
+		Monitors:    configs,
+	})
+	screenCfg.CurrentProfile = name
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	err = m.saveSysConfig("save profile")
+	if err != nil {
+		return err
+	}
+	m.updatePropProfiles(screenCfg)
+	return nil
+}
+
+// applyProfile 应用名称为 name 的布局，并把它设置为对应显示模式的当前配置。
+func (m *Manager) applyProfile(name string) error {
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	if len(monitors) == 0 {
+		return errors.New("no monitor connected")
+	}
+	monitorsId := monitors.getMonitorsId()
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	profile := screenCfg.GetProfile(name)
+	if profile == nil {
+		return profileNotFoundError{Name: name}
+	}
+	configs := profile.Monitors.Clone()
+
+	if len(monitors) == 1 {
+		err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, configs, nil)
+		if err != nil {
+			return err
+		}
+		screenCfg.SetSingleMonitorConfigs(configs)
+	} else {
+		m.PropsMu.RLock()
+		oldMode := m.DisplayMode
+		m.PropsMu.RUnlock()
+
+		var options applyOptions
+		if oldMode != profile.DisplayMode {
+			options = getSwitchModeOptions(profile.DisplayMode, "")
+		}
+		err := m.applySysMonitorConfigs(profile.DisplayMode, monitorsId, monitorMap, configs, options)
+		if err != nil {
+			return err
+		}
+
+		uuid := ""
+		if profile.DisplayMode == DisplayModeOnlyOne {
+			for _, config := range configs {
+				if config.Enabled {
+					uuid = config.UUID
+					break
+				}
+			}
+			screenCfg.OnlyOneUuid = uuid
+		}
+		screenCfg.SetMonitorConfigs(profile.DisplayMode, uuid, configs)
+	}
+	screenCfg.CurrentProfile = name
+	m.setSysScreenConfig(monitorsId, screenCfg)
+
+	m.sysConfig.Mu.Lock()
+	if len(monitors) > 1 {
+		m.sysConfig.Config.DisplayMode = profile.DisplayMode
+	}
+	err := m.saveSysConfigNoLock("apply profile")
+	m.sysConfig.Mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.markClean()
+	m.updatePropProfiles(screenCfg)
+	return nil
+}
+
+func (m *Manager) renameProfile(name, newName string) error {
+	newName, err := normalizeProfileName(newName)
+	if err != nil {
+		return err
+	}
+	monitorsId := m.getMonitorsId()
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	err = screenCfg.RenameProfile(name, newName)
+	if err != nil {
+		return err
+	}
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	err = m.saveSysConfig("rename profile")
+	if err != nil {
+		return err
+	}
+	m.updatePropProfiles(screenCfg)
+	return nil
+}
+
+func (m *Manager) deleteProfile(name string) error {
+	monitorsId := m.getMonitorsId()
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	err := screenCfg.DeleteProfile(name)
+	if err != nil {
+		return err
+	}
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	err = m.saveSysConfig("delete profile")
+	if err != nil {
+		return err
+	}
+	m.updatePropProfiles(screenCfg)
+	return nil
+}
+
+func (m *Manager) listProfiles() []string {
+	return m.getSysScreenConfig(m.getMonitorsId()).GetProfileNames()
+}
+
+// clearCurrentProfile 当前布局被修改后，不再处于某个命名布局中。
+func (m *Manager) clearCurrentProfile(monitorsId monitorsId) {
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	if screenCfg.CurrentProfile == "" {
+		return
+	}
+	screenCfg.CurrentProfile = ""
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	m.updatePropProfiles(screenCfg)
+}
+
+// updatePropProfiles 根据屏幕配置更新 CustomIdList 和 CurrentCustomId 属性，screenCfg 为 nil 时使用当前 monitorsId 对应的配置。
+func (m *Manager) updatePropProfiles(screenCfg *SysScreenConfig) {
+	if screenCfg == nil {
+		screenCfg = m.getSysScreenConfig(m.getMonitorsId())
+	}
+	m.PropsMu.Lock()
+	m.setPropCustomIdList(screenCfg.GetProfileNames())
+	m.setPropCurrentCustomId(screenCfg.CurrentProfile)
+	m.PropsMu.Unlock()
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SysScreenConfigProfiles(t *testing.T) {
+	screenCfg := &SysScreenConfig{}
+	assert.Nil(t, screenCfg.GetProfileNames())
+
+	desk := &SysProfileConfig{
+		DisplayMode: DisplayModeExtend,
+		Monitors: SysMonitorConfigs{
+			{UUID: "HDMI-1|a|v1", Enabled: true, Primary: true, Width: 1920, Height: 1080, Brightness: 1},
+			{UUID: "eDP-1|b|v1", Enabled: true, X: 1920, Width: 1366, Height: 768, Brightness: 1},
+		},
+	}
+	screenCfg.SetProfile("Desk", desk)
+	screenCfg.SetProfile("Presenting", &SysProfileConfig{DisplayMode: DisplayModeMirror})
+	screenCfg.CurrentProfile = "Desk"
+	assert.Equal(t, []string{"Desk", "Presenting"}, screenCfg.GetProfileNames())
+
+	// clone 是深拷贝
+	cloned := screenCfg.Clone()
+	cloned.GetProfile("Desk").Monitors[0].X = 100
+	assert.Equal(t, int16(0), screenCfg.GetProfile("Desk").Monitors[0].X)
+	assert.Equal(t, "Desk", cloned.CurrentProfile)
+
+	err := screenCfg.RenameProfile("Desk", "Presenting")
+	assert.Error(t, err)
+	err = screenCfg.RenameProfile("NotExist", "Other")
+	assert.Equal(t, profileNotFoundError{Name: "NotExist"}, err)
+
+	err = screenCfg.RenameProfile("Desk", "Docked")
+	require.NoError(t, err)
+	assert.Equal(t, "Docked", screenCfg.CurrentProfile)
+	assert.Nil(t, screenCfg.GetProfile("Desk"))
+	assert.Equal(t, desk, screenCfg.GetProfile("Docked"))
+
+	err = screenCfg.DeleteProfile("Docked")
+	require.NoError(t, err)
+	assert.Equal(t, "", screenCfg.CurrentProfile)
+	assert.Equal(t, []string{"Presenting"}, screenCfg.GetProfileNames())
+
+	err = screenCfg.DeleteProfile("Presenting")
+	require.NoError(t, err)
+	assert.Nil(t, screenCfg.Profiles)
+}
+
+func Test_SysScreenConfigProfilesFix(t *testing.T) {
+	screenCfg := &SysScreenConfig{
+		Profiles: map[string]*SysProfileConfig{
+			"Desk": {
+				DisplayMode: DisplayModeCustom,
+				Monitors: SysMonitorConfigs{
+					{UUID: "HDMI-1|a|v1", Primary: true},
+				},
+			},
+			"Broken": nil,
+		},
+		CurrentProfile: "Broken",
+	}
+	screenCfg.Fix()
+
+	assert.Equal(t, []string{"Desk"}, screenCfg.GetProfileNames())
+	assert.Equal(t, "", screenCfg.CurrentProfile)
+	desk := screenCfg.GetProfile("Desk")
+	assert.Equal(t, DisplayModeExtend, desk.DisplayMode)
+	assert.False(t, desk.Monitors[0].Primary)
+	assert.Equal(t, float64(1), desk.Monitors[0].Brightness)
+}
+
+func Test_normalizeProfileName(t *testing.T) {
+	name, err := normalizeProfileName("  Desk ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Desk", name)
+
+	_, err = normalizeProfileName(" ")
+	assert.Equal(t, errProfileNameEmpty, err)
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+)
+
+// 根据重力传感器自动旋转内置显示器。
+// 旋转锁定、不在允许的方向列表中、或者处于笔记本姿态时不自动旋转。
+// 自动旋转只应用到当前布局，不保存到配置中，手动设置的布局不会被传感器的误报覆盖。
+
+// 设备姿态，由 SetPosture 输入
+const (
+	PostureUnknown = "unknown"
+	// 笔记本姿态，键盘在屏幕下方，不自动旋转
+	PostureLaptop = "laptop"
+	PostureTablet = "tablet"
+	PostureTent   = "tent"
+)
+
+const defaultAllowedOrientations = "normal,left,right"
+
+// parseOrientations 解析逗号分隔的方向列表
+func parseOrientations(str string) ([]string, error) {
+	var result []string
+	for _, orientation := range strings.Split(str, ",") {
+		orientation = strings.TrimSpace(orientation)
+		if orientation == "" {
+			continue
+		}
+		if _, ok := rotationScreenValue[orientation]; !ok {
+			return nil, fmt.Errorf("invalid orientation %q", orientation)
+		}
+		result = append(result, orientation)
+	}
+	return result, nil
+}
+
+// canAutoRotate 是否可以自动旋转到方向 orientation
+func canAutoRotate(locked bool, posture string, allowed []string, orientation string) bool {
+	if locked || posture == PostureLaptop {
+		return false
+	}
+	for _, o := range allowed {
+		if o == orientation {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) loadRotationLocked(locked bool) {
+	m.PropsMu.Lock()
+	m.setPropRotationLocked(locked)
+	m.PropsMu.Unlock()
+}
+
+func (m *Manager) loadAllowedOrientations(str string) error {
+	orientations, err := parseOrientations(str)
+	if err != nil {
+		return err
+	}
+	m.PropsMu.Lock()
+	m.setPropAllowedOrientations(orientations)
+	m.PropsMu.Unlock()
+	return nil
+}
+
+// RotationLocked 属性的写回调
+func (m *Manager) writeRotationLocked(write *dbusutil.PropertyWrite) *dbus.Error {
+	locked, ok := write.Value.(bool)
+	if !ok {
+		return dbusutil.ToError(errors.New("type is not bool"))
+	}
+	logger.Debug("set RotationLocked", locked)
+	err := setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyRotationLocked, dbus.MakeVariant(locked))
+	if err != nil {
+		return dbusutil.ToError(err)
+	}
+	m.loadRotationLocked(locked)
+	if !locked {
+		m.handleRotationPolicyChanged()
+	}
+	return nil
+}
+
+// dbus 上导出的方法
+func (m *Manager) setAllowedOrientations(orientations []string) error {
+	str := strings.Join(orientations, ",")
+	_, err := parseOrientations(str)
+	if err != nil {
+		return err
+	}
+	err = setGlobalDconfValue(DSettingsAppID, DSettingsDisplayName, "", DSettingsKeyAllowedOrientations, dbus.MakeVariant(str))
+	if err != nil {
+		return err
+	}
+	err = m.loadAllowedOrientations(str)
+	if err != nil {
+		return err
+	}
+	m.handleRotationPolicyChanged()
+	return nil
+}
+
+// dbus 上导出的方法
+func (m *Manager) setPosture(posture string) error {
+	switch posture {
+	case PostureUnknown, PostureLaptop, PostureTablet, PostureTent:
+	default:
+		return fmt.Errorf("invalid posture %q", posture)
+	}
+	m.PropsMu.Lock()
+	changed := m.setPropPosture(posture)
+	m.PropsMu.Unlock()
+	if !changed {
+		return nil
+	}
+
+	if posture == PostureLaptop {
+		// 回到笔记本姿态，恢复配置中的方向
+		m.restoreAutoRotation()
+		return nil
+	}
+	m.handleRotationPolicyChanged()
+	return nil
+}
+
+// handleRotationPolicyChanged 解除锁定或者策略改变后，按传感器当前的方向旋转
+func (m *Manager) handleRotationPolicyChanged() {
+	go m.initScreenRotation()
+}
+
+// handleSensorOrientation 处理传感器报告的方向
+func (m *Manager) handleSensorOrientation(orientation string) {
+	rotation, ok := rotationScreenValue[orientation]
+	if !ok {
+		logger.Warningf("invalid sensor orientation %q", orientation)
+		return
+	}
+	m.PropsMu.RLock()
+	can := canAutoRotate(m.RotationLocked, m.Posture, m.AllowedOrientations, orientation)
+	m.PropsMu.RUnlock()
+	if !can {
+		logger.Debugf("skip auto rotation to %s", orientation)
+		return
+	}
+
+	startBuildInScreenRotationMutex.Lock()
+	defer startBuildInScreenRotationMutex.Unlock()
+	m.startBuildInScreenRotation(rotation)
+}
+
+// applyAutoRotation 旋转内置显示器，只应用不保存配置
+func (m *Manager) applyAutoRotation(builtinMonitor *Monitor, rotation uint16) error {
+	if m.getInApply() {
+		return errors.New("in apply")
+	}
+	builtinMonitor.PropsMu.RLock()
+	currentRotation := builtinMonitor.Rotation
+	builtinMonitor.PropsMu.RUnlock()
+	if currentRotation == rotation {
+		return nil
+	}
+
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	monitorsId := monitors.getMonitorsId()
+	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitorsId, monitors).Clone()
+	config := configs.GetByUuid(builtinMonitor.uuid)
+	if config == nil || !config.Enabled {
+		return errors.New("builtin monitor is not enabled")
+	}
+	savedRotation := config.Rotation
+
+	// 配置中的宽和高是经过 rotation 调整的
+	width, height := config.Width, config.Height
+	swapWidthHeightWithRotation(savedRotation, &width, &height)
+	swapWidthHeightWithRotation(rotation, &width, &height)
+	modifySysMonitorConfig(config, monitorChanges{
+		monitorPropWidth:    width,
+		monitorPropHeight:   height,
+		monitorPropRotation: rotation,
+	})
+
+	err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, configs, nil)
+	if err != nil {
+		return err
+	}
+	m.autoRotated = rotation != savedRotation
+	return nil
+}
+
+// restoreAutoRotation 恢复配置中的布局，撤销自动旋转
+func (m *Manager) restoreAutoRotation() {
+	startBuildInScreenRotationMutex.Lock()
+	defer startBuildInScreenRotationMutex.Unlock()
+	if !m.autoRotated {
+		return
+	}
+	m.autoRotated = false
+
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	monitorsId := monitors.getMonitorsId()
+	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitorsId, monitors)
+	err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, configs, nil)
+	if err != nil {
+		logger.Warning("failed to restore rotation:", err)
+		return
+	}
+	if builtinMonitor := m.getBuiltinMonitor(); builtinMonitor != nil {
+		builtinMonitor.PropsMu.Lock()
+		builtinMonitor.setPropCurrentRotateMode(RotationFinishModeManual)
+		builtinMonitor.PropsMu.Unlock()
+	}
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseOrientations(t *testing.T) {
+	orientations, err := parseOrientations(defaultAllowedOrientations)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"normal", "left", "right"}, orientations)
+
+	orientations, err = parseOrientations(" bottom-up , ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bottom-up"}, orientations)
+
+	orientations, err = parseOrientations("")
+	require.NoError(t, err)
+	assert.Empty(t, orientations)
+
+	_, err = parseOrientations("normal,upside-down")
+	assert.Error(t, err)
+}
+
+func Test_canAutoRotate(t *testing.T) {
+	allowed := []string{"normal", "left", "right"}
+	assert.True(t, canAutoRotate(false, PostureUnknown, allowed, "left"))
+	assert.True(t, canAutoRotate(false, PostureTablet, allowed, "normal"))
+	assert.False(t, canAutoRotate(true, PostureTablet, allowed, "left"))
+	assert.False(t, canAutoRotate(false, PostureLaptop, allowed, "left"))
+	assert.False(t, canAutoRotate(false, PostureTent, allowed, "bottom-up"))
+	assert.False(t, canAutoRotate(false, PostureUnknown, nil, "normal"))
+}
@@ -0,0 +1,389 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/linuxdeepin/dde-api/dxinput"
+	"github.com/linuxdeepin/dde-api/dxinput/common"
+	dxutil "github.com/linuxdeepin/dde-api/dxinput/utils"
+	"github.com/linuxdeepin/go-x11-client/ext/input"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// 数位板（手写板和手写屏）和显示器的映射，和触摸屏一样用坐标变换矩阵把输入映射到显示器上。
+// 一个数位板在 X 中一般有笔、橡皮擦、按键板等多个设备，按去掉后缀的设备名分组。
+// 映射配置保存在 gsettings 的 map-tablet 中，key 是数位板的 uuid，显示器用 uuid 标识。
+// 输入区域用相对整个数位板的比例表示，不依赖数位板的坐标范围。
+
+const gsKeyMapTablet = "map-tablet"
+
+const propCoordinateTransformationMatrix = "Coordinate Transformation Matrix"
+
+// 输入设备热插拔后等待一会儿再刷新，插入一个数位板会产生多个事件
+const tabletRefreshDelay = 500 * time.Millisecond
+
+type Tablet struct {
+	UUID string
+	Name string
+	// X 输入设备 id
+	DeviceIds []int32
+	// 映射到的显示器，为空表示映射到整个屏幕
+	OutputName string
+	// 是否限制数位板的输入区域，使其宽高比和显示器相同
+	KeepAspect bool
+	// 用户设置的输入区域 [x1, y1, x2, y2]，是相对整个数位板的比例，为空表示整个数位板
+	Area []float64
+
+	devices common.DeviceInfos
+}
+
+type tabletMapValue struct {
+	OutputName  string
+	MonitorUuid string
+	KeepAspect  bool
+	Area        []float64 `json:",omitempty"`
+}
+
+// 按从长到短的顺序排列
+var tabletDeviceSuffixes = []string{
+	" finger touch",
+	" pen stylus",
+	" pen eraser",
+	" pen cursor",
+	" pad pad",
+	" stylus",
+	" eraser",
+	" cursor",
+	" touch",
+	" pad",
+	" pen",
+}
+
+// getTabletName 去掉设备名中表示笔、橡皮擦等的后缀，得到数位板的名称
+func getTabletName(deviceName string) string {
+	lower := strings.ToLower(deviceName)
+	for _, suffix := range tabletDeviceSuffixes {
+		if strings.HasSuffix(lower, suffix) && len(deviceName) > len(suffix) {
+			return deviceName[:len(deviceName)-len(suffix)]
+		}
+	}
+	return deviceName
+}
+
+// groupTabletDevices 把 wacom 类型的输入设备按数位板分组
+func groupTabletDevices(infos common.DeviceInfos) []Tablet {
+	var tablets []Tablet
+	indexMap := make(map[string]int)
+	for _, info := range infos {
+		if info.Type != common.DevTypeWacom {
+			continue
+		}
+		name := getTabletName(info.Name)
+		idx, ok := indexMap[name]
+		if !ok {
+			idx = len(tablets)
+			indexMap[name] = idx
+			tablets = append(tablets, Tablet{
+				UUID: fmt.Sprintf("%x", md5.Sum([]byte(name))),
+				Name: name,
+			})
+		}
+		tablets[idx].DeviceIds = append(tablets[idx].DeviceIds, info.Id)
+		tablets[idx].devices = append(tablets[idx].devices, info)
+	}
+	return tablets
+}
+
+// genTabletArea 缩小数位板的输入区域 (x1, y1)-(x2, y2)，使其宽高比和显示器相同，保持左上角不变
+func genTabletArea(x1, y1, x2, y2 int, width, height, rotation uint16) (int, int, int, int) {
+	if rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		width, height = height, width
+	}
+	areaWidth := x2 - x1
+	areaHeight := y2 - y1
+	if width == 0 || height == 0 || areaWidth <= 0 || areaHeight <= 0 {
+		return x1, y1, x2, y2
+	}
+	// 比较 areaWidth/areaHeight 和 width/height
+	if areaWidth*int(height) > areaHeight*int(width) {
+		areaWidth = areaHeight * int(width) / int(height)
+	} else {
+		areaHeight = areaWidth * int(height) / int(width)
+	}
+	return x1, y1, x1 + areaWidth, y1 + areaHeight
+}
+
+// checkTabletArea 检查输入区域，为空或者是 0 到 1 之间的 [x1, y1, x2, y2]
+func checkTabletArea(area []float64) error {
+	if len(area) == 0 {
+		return nil
+	}
+	if len(area) != 4 {
+		return fmt.Errorf("invalid tablet area %v", area)
+	}
+	for _, v := range area {
+		if math.IsNaN(v) || v < 0 || v > 1 {
+			return fmt.Errorf("invalid tablet area %v", area)
+		}
+	}
+	if area[0] >= area[2] || area[1] >= area[3] {
+		return fmt.Errorf("invalid tablet area %v", area)
+	}
+	return nil
+}
+
+// genTabletSubArea 按比例 area 取数位板输入区域 (x1, y1)-(x2, y2) 中的一部分
+func genTabletSubArea(x1, y1, x2, y2 int, area []float64) (int, int, int, int) {
+	if len(area) != 4 {
+		return x1, y1, x2, y2
+	}
+	width := float64(x2 - x1)
+	height := float64(y2 - y1)
+	return x1 + int(width*area[0]), y1 + int(height*area[1]),
+		x1 + int(width*area[2]), y1 + int(height*area[3])
+}
+
+func (m *Manager) initTablets() {
+	if _useWayland || useFakeMonitorManager() || m.settings == nil {
+		return
+	}
+	m.tabletMap = make(map[string]tabletMapValue)
+	value := m.settings.GetString(gsKeyMapTablet)
+	if value != "" {
+		err := jsonUnmarshal(value, &m.tabletMap)
+		if err != nil {
+			logger.Warningf("[initTablets] unmarshal (%s) failed: %v", value, err)
+		}
+		for uuid, v := range m.tabletMap {
+			if checkTabletArea(v.Area) != nil {
+				logger.Warningf("[initTablets] invalid area %v of tablet %s", v.Area, uuid)
+				v.Area = nil
+				m.tabletMap[uuid] = v
+			}
+		}
+	}
+	m.refreshTablets()
+}
+
+// selectInputHierarchyEvent 监听输入设备的添加和删除
+func (m *Manager) selectInputHierarchyEvent() error {
+	root := m.xConn.GetDefaultScreen().Root
+	err := input.XISelectEventsChecked(m.xConn, root, []input.EventMask{
+		{
+			DeviceId: input.DeviceAll,
+			Mask:     []uint32{input.XIEventMaskHierarchy},
+		},
+	}).Check(m.xConn)
+	return err
+}
+
+// handleInputDevicesChanged 输入设备热插拔后刷新数位板
+func (m *Manager) handleInputDevicesChanged() {
+	m.PropsMu.Lock()
+	defer m.PropsMu.Unlock()
+	if m.tabletMap == nil {
+		return
+	}
+	if m.tabletRefreshTimer == nil {
+		m.tabletRefreshTimer = time.AfterFunc(tabletRefreshDelay, m.refreshTablets)
+		return
+	}
+	m.tabletRefreshTimer.Reset(tabletRefreshDelay)
+}
+
+func (m *Manager) refreshTablets() {
+	tablets := groupTabletDevices(getDeviceInfos(true))
+	m.PropsMu.Lock()
+	m.setPropTablets(tablets)
+	m.PropsMu.Unlock()
+	m.handleTabletChanged()
+}
+
+// handleTabletChanged 按配置映射所有数位板，显示器改变后也需要调用
+func (m *Manager) handleTabletChanged() {
+	monitors := m.getConnectedMonitors()
+	m.PropsMu.Lock()
+	if m.tabletMap == nil {
+		m.PropsMu.Unlock()
+		return
+	}
+
+	tablets := make([]Tablet, len(m.Tablets))
+	copy(tablets, m.Tablets)
+	targets := make([]*Monitor, len(tablets))
+	for i := range tablets {
+		tablet := &tablets[i]
+		var monitor *Monitor
+		v := m.tabletMap[tablet.UUID]
+		// 只设置了输入区域时没有显示器
+		if v.MonitorUuid != "" {
+			monitor = monitors.GetByUuid(v.MonitorUuid)
+		}
+		if monitor == nil && v.OutputName != "" {
+			monitor = monitors.GetByName(v.OutputName)
+		}
+		if monitor != nil && !monitor.Enabled {
+			monitor = nil
+		}
+
+		tablet.OutputName = ""
+		tablet.KeepAspect = v.KeepAspect
+		tablet.Area = v.Area
+		if monitor != nil {
+			tablet.OutputName = monitor.Name
+		}
+		targets[i] = monitor
+	}
+	m.setPropTablets(tablets)
+	m.PropsMu.Unlock()
+
+	// xsetwacom 比较慢，不持有锁
+	for i := range tablets {
+		m.doSetTabletMap(&tablets[i], targets[i], tablets[i].KeepAspect, tablets[i].Area)
+	}
+}
+
+// doSetTabletMap 把数位板映射到显示器，monitor 为 nil 时映射到整个屏幕，
+// area 不为空时先限制输入区域，再按 keepAspect 缩小
+func (m *Manager) doSetTabletMap(tablet *Tablet, monitor *Monitor, keepAspect bool, area []float64) {
+	matrix := TransformationMatrix{1, 0, 0, 0, 1, 0, 0, 0, 1}
+	if monitor != nil {
+		matrix = genTransformationMatrix(monitor.X, monitor.Y, monitor.Width, monitor.Height,
+			monitor.Rotation|monitor.Reflect)
+	} else {
+		keepAspect = false
+	}
+	logger.Debugf("matrix: %v, tablet: %s(%v)", matrix, tablet.Name, tablet.DeviceIds)
+
+	for _, info := range tablet.devices {
+		err := dxutil.SetFloat32Prop(info.Id, propCoordinateTransformationMatrix, matrix[:])
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+
+		wacom, err := dxinput.NewWacomFromDevInfo(info)
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		switch wacom.QueryType() {
+		case dxinput.WacomTypeStylus, dxinput.WacomTypeEraser:
+		default:
+			// 按键板等设备没有输入区域
+			continue
+		}
+		err = wacom.ResetArea()
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		if !keepAspect && len(area) == 0 {
+			continue
+		}
+		x1, y1, x2, y2, err := wacom.GetArea()
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		x1, y1, x2, y2 = genTabletSubArea(x1, y1, x2, y2, area)
+		if keepAspect {
+			x1, y1, x2, y2 = genTabletArea(x1, y1, x2, y2, monitor.Width, monitor.Height, monitor.Rotation)
+		}
+		err = wacom.SetArea(x1, y1, x2, y2)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+}
+
+// hasTabletNoLock 数位板是否存在，调用时需要持有 PropsMu
+func (m *Manager) hasTabletNoLock(uuid string) bool {
+	for _, tablet := range m.Tablets {
+		if tablet.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// setTabletMapNoLock 保存数位板的配置，既没有显示器也没有输入区域时删除，调用时需要持有 PropsMu
+func (m *Manager) setTabletMapNoLock(uuid string, v tabletMapValue) {
+	if v.MonitorUuid == "" && v.OutputName == "" && len(v.Area) == 0 {
+		delete(m.tabletMap, uuid)
+	} else {
+		m.tabletMap[uuid] = v
+	}
+	m.settings.SetString(gsKeyMapTablet, jsonMarshal(m.tabletMap))
+}
+
+// dbus 上导出的方法
+func (m *Manager) associateTablet(outputName, uuid string, keepAspect bool) error {
+	var monitor *Monitor
+	if outputName != "" {
+		monitor = m.getConnectedMonitors().GetByName(outputName)
+		if monitor == nil {
+			return InvalidOutputNameError{Name: outputName}
+		}
+	}
+
+	m.PropsMu.Lock()
+	if m.tabletMap == nil {
+		m.PropsMu.Unlock()
+		return errors.New("tablet is not supported")
+	}
+	if !m.hasTabletNoLock(uuid) {
+		m.PropsMu.Unlock()
+		return fmt.Errorf("tablet %q not exists", uuid)
+	}
+
+	// 保留输入区域
+	v := tabletMapValue{Area: m.tabletMap[uuid].Area}
+	if monitor != nil {
+		v.OutputName = monitor.Name
+		v.MonitorUuid = monitor.uuid
+		v.KeepAspect = keepAspect
+	}
+	m.setTabletMapNoLock(uuid, v)
+	m.PropsMu.Unlock()
+
+	m.handleTabletChanged()
+	return nil
+}
+
+func (m *Manager) setTabletArea(uuid string, area []float64) error {
+	err := checkTabletArea(area)
+	if err != nil {
+		return err
+	}
+
+	m.PropsMu.Lock()
+	if m.tabletMap == nil {
+		m.PropsMu.Unlock()
+		return errors.New("tablet is not supported")
+	}
+	if !m.hasTabletNoLock(uuid) {
+		m.PropsMu.Unlock()
+		return fmt.Errorf("tablet %q not exists", uuid)
+	}
+
+	v := m.tabletMap[uuid]
+	v.Area = area
+	if len(area) == 0 {
+		v.Area = nil
+	}
+	m.setTabletMapNoLock(uuid, v)
+	m.PropsMu.Unlock()
+
+	m.handleTabletChanged()
+	return nil
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"math"
+	"testing"
+
+	"github.com/linuxdeepin/dde-api/dxinput/common"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getTabletName(t *testing.T) {
+	assert.Equal(t, "Wacom Intuos S", getTabletName("Wacom Intuos S Pen stylus"))
+	assert.Equal(t, "Wacom Intuos S", getTabletName("Wacom Intuos S Pad pad"))
+	assert.Equal(t, "Wacom One", getTabletName("Wacom One Finger touch"))
+	assert.Equal(t, "HUION Tablet", getTabletName("HUION Tablet eraser"))
+	assert.Equal(t, "Pen", getTabletName("Pen"))
+}
+
+func Test_groupTabletDevices(t *testing.T) {
+	tablets := groupTabletDevices(common.DeviceInfos{
+		{Id: 10, Type: common.DevTypeWacom, Name: "Wacom Intuos S Pen stylus"},
+		{Id: 11, Type: common.DevTypeMouse, Name: "Logitech Mouse"},
+		{Id: 12, Type: common.DevTypeWacom, Name: "Wacom Intuos S Pad pad"},
+		{Id: 13, Type: common.DevTypeWacom, Name: "Wacom One Pen stylus"},
+		{Id: 14, Type: common.DevTypeWacom, Name: "Wacom Intuos S Pen eraser"},
+	})
+	require.Len(t, tablets, 2)
+	assert.Equal(t, "Wacom Intuos S", tablets[0].Name)
+	assert.Equal(t, []int32{10, 12, 14}, tablets[0].DeviceIds)
+	assert.Equal(t, "Wacom One", tablets[1].Name)
+	assert.Equal(t, []int32{13}, tablets[1].DeviceIds)
+	assert.NotEqual(t, tablets[0].UUID, tablets[1].UUID)
+}
+
+func Test_genTabletArea(t *testing.T) {
+	// 16:10 的数位板映射到 16:9 的显示器，缩小高度
+	x1, y1, x2, y2 := genTabletArea(0, 0, 16000, 10000, 1920, 1080, randr.RotationRotate0)
+	assert.Equal(t, []int{0, 0, 16000, 9000}, []int{x1, y1, x2, y2})
+
+	// 显示器竖屏，缩小宽度
+	x1, y1, x2, y2 = genTabletArea(0, 0, 16000, 10000, 1920, 1080, randr.RotationRotate90)
+	assert.Equal(t, []int{0, 0, 5625, 10000}, []int{x1, y1, x2, y2})
+
+	x1, y1, x2, y2 = genTabletArea(100, 100, 16100, 10100, 0, 0, randr.RotationRotate0)
+	assert.Equal(t, []int{100, 100, 16100, 10100}, []int{x1, y1, x2, y2})
+}
+
+func Test_checkTabletArea(t *testing.T) {
+	assert.NoError(t, checkTabletArea(nil))
+	assert.NoError(t, checkTabletArea([]float64{0, 0, 0.5, 1}))
+	assert.Error(t, checkTabletArea([]float64{0, 0, 1}))
+	assert.Error(t, checkTabletArea([]float64{0.5, 0, 0.5, 1}))
+	assert.Error(t, checkTabletArea([]float64{0, 0, 1.5, 1}))
+	assert.Error(t, checkTabletArea([]float64{0, 0, math.NaN(), 1}))
+}
+
+func Test_genTabletSubArea(t *testing.T) {
+	x1, y1, x2, y2 := genTabletSubArea(100, 100, 16100, 10100, []float64{0.25, 0, 0.75, 0.5})
+	assert.Equal(t, []int{4100, 100, 12100, 5100}, []int{x1, y1, x2, y2})
+
+	x1, y1, x2, y2 = genTabletSubArea(0, 0, 16000, 10000, nil)
+	assert.Equal(t, []int{0, 0, 16000, 10000}, []int{x1, y1, x2, y2})
+}
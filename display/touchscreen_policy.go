@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 触摸屏和显示器的映射。映射配置保存在 gsettings 的 map-output 中，key 是触摸屏的 uuid，
+// 显示器用 getOutputUuid 得到的 uuid 标识，所以显示器换一个接口后也能找到。
+// 触摸屏或者显示器拔出后配置仍然保留，可以通过 ListTouchscreenMaps 和 ClearTouchscreenMap 查看和清除。
+//
+// 没有配置的触摸屏按策略自动映射，策略保存在 DConfig 的 touchscreen-map-policies 中，用逗号分隔，按顺序尝试:
+//
+//	proximity 按物理位置映射，比如 Wayland 下输入设备提供的 WL_OUTPUT，或者触摸屏和显示器的物理尺寸相同
+//	builtin   不是通过 USB 连接的触摸屏映射到内置显示器
+//	primary   映射到主显示器，不保存配置，并显示配置对话框
+//
+// 另外 disable-when-target-disabled 表示配置中的显示器被禁用，或者手动配置的显示器被拔出时禁用触摸屏，
+// 而不是按策略临时映射到其他显示器。
+
+const (
+	touchPolicyProximity                 = "proximity"
+	touchPolicyBuiltin                   = "builtin"
+	touchPolicyPrimary                   = "primary"
+	touchPolicyDisableWhenTargetDisabled = "disable-when-target-disabled"
+)
+
+const defaultTouchscreenPolicies = "proximity,builtin,primary,disable-when-target-disabled"
+
+type touchscreenPolicies struct {
+	order                     []string
+	disableWhenTargetDisabled bool
+}
+
+func parseTouchscreenPolicies(str string) (touchscreenPolicies, error) {
+	var result touchscreenPolicies
+	for _, policy := range strings.Split(str, ",") {
+		policy = strings.TrimSpace(policy)
+		switch policy {
+		case "":
+			continue
+		case touchPolicyProximity, touchPolicyBuiltin, touchPolicyPrimary:
+			result.order = append(result.order, policy)
+		case touchPolicyDisableWhenTargetDisabled:
+			result.disableWhenTargetDisabled = true
+		default:
+			return touchscreenPolicies{}, fmt.Errorf("invalid touchscreen map policy %q", policy)
+		}
+	}
+	return result, nil
+}
+
+// autoMap 按策略为触摸屏选择显示器，返回选中的显示器和使用的策略，没有合适的显示器时返回 nil。
+func (p touchscreenPolicies) autoMap(touch *Touchscreen, monitors Monitors, builtinMonitor *Monitor,
+	primary string) (*Monitor, string) {
+	isAvailable := func(monitor *Monitor) bool {
+		return monitor != nil && monitors.GetById(monitor.ID) != nil &&
+			(monitor.Enabled || p.disableWhenTargetDisabled)
+	}
+
+	for _, policy := range p.order {
+		var monitor *Monitor
+		switch policy {
+		case touchPolicyProximity:
+			monitor = getProximityMonitor(touch, monitors)
+		case touchPolicyBuiltin:
+			if touch.busType != BusTypeUSB {
+				monitor = builtinMonitor
+			}
+		case touchPolicyPrimary:
+			monitor = monitors.GetByName(primary)
+		}
+		if isAvailable(monitor) {
+			return monitor, policy
+		}
+	}
+	return nil, ""
+}
+
+// getProximityMonitor 返回和触摸屏在物理上是同一个设备的显示器
+func getProximityMonitor(touch *Touchscreen, monitors Monitors) *Monitor {
+	if touch.outputName != "" {
+		return monitors.GetByName(touch.outputName)
+	}
+	width := uint32(math.Round(touch.width))
+	height := uint32(math.Round(touch.height))
+	if width == 0 || height == 0 {
+		return nil
+	}
+	for _, monitor := range monitors {
+		if monitor.MmWidth == width && monitor.MmHeight == height {
+			return monitor
+		}
+	}
+	return nil
+}
+
+// getTouchscreenMapMonitor 返回映射配置中的显示器，优先用 uuid 查找，旧配置中只有接口名
+func getTouchscreenMapMonitor(v touchscreenMapValue, monitors Monitors) *Monitor {
+	monitor, ok := core.GetTouchscreenMapMonitor(v, monitors.toCoreMonitors())
+	if !ok {
+		return nil
+	}
+	return monitors.GetById(monitor.ID)
+}
+
+// TouchscreenMapInfo 是 ListTouchscreenMaps 返回的映射配置
+type TouchscreenMapInfo struct {
+	TouchscreenUUID string
+	MonitorUuid     string
+	// 配置时显示器的接口名
+	OutputName string
+	// 是否是按策略自动映射的
+	Auto bool
+	// 触摸屏和显示器是否都已连接
+	Active bool
+}
+
+func (m *Manager) listTouchscreenMaps() (string, error) {
+	monitors := m.getConnectedMonitors()
+	m.PropsMu.RLock()
+	result := make([]TouchscreenMapInfo, 0, len(m.touchscreenMap))
+	for touchUUID, v := range m.touchscreenMap {
+		_, active := m.TouchMap[touchUUID]
+		result = append(result, TouchscreenMapInfo{
+			TouchscreenUUID: touchUUID,
+			MonitorUuid:     v.MonitorUuid,
+			OutputName:      v.OutputName,
+			Auto:            v.Auto,
+			Active:          active && getTouchscreenMapMonitor(v, monitors) != nil,
+		})
+	}
+	m.PropsMu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TouchscreenUUID < result[j].TouchscreenUUID
+	})
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// clearTouchscreenMap 清除触摸屏 touchUUID 的映射配置，为空时清除所有配置，然后重新映射
+func (m *Manager) clearTouchscreenMap(touchUUID string) error {
+	m.PropsMu.Lock()
+	if touchUUID == "" {
+		for uuid := range m.touchscreenMap {
+			m.removeTouchscreenMap(uuid)
+		}
+	} else {
+		if _, ok := m.touchscreenMap[touchUUID]; !ok {
+			m.PropsMu.Unlock()
+			return fmt.Errorf("touchscreen map %q not exists", touchUUID)
+		}
+		m.removeTouchscreenMap(touchUUID)
+	}
+	m.PropsMu.Unlock()
+
+	m.handleTouchscreenChanged()
+	m.showTouchscreenDialogs()
+	return nil
+}
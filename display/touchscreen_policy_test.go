@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseTouchscreenPolicies(t *testing.T) {
+	p, err := parseTouchscreenPolicies(defaultTouchscreenPolicies)
+	require.NoError(t, err)
+	assert.Equal(t, []string{touchPolicyProximity, touchPolicyBuiltin, touchPolicyPrimary}, p.order)
+	assert.True(t, p.disableWhenTargetDisabled)
+
+	p, err = parseTouchscreenPolicies(" primary , ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{touchPolicyPrimary}, p.order)
+	assert.False(t, p.disableWhenTargetDisabled)
+
+	_, err = parseTouchscreenPolicies("proximity,nearest")
+	assert.Error(t, err)
+}
+
+func Test_touchscreenPolicies_autoMap(t *testing.T) {
+	builtin := &Monitor{ID: 1, Name: "eDP-1", Enabled: true, MmWidth: 310, MmHeight: 170, uuid: "edp"}
+	external := &Monitor{ID: 2, Name: "HDMI-1", Enabled: true, MmWidth: 530, MmHeight: 300, uuid: "hdmi"}
+	monitors := Monitors{builtin, external}
+	p, _ := parseTouchscreenPolicies(defaultTouchscreenPolicies)
+
+	// 物理尺寸相同
+	touch := &Touchscreen{UUID: "t1", busType: BusTypeUSB, width: 530.2, height: 299.8}
+	monitor, policy := p.autoMap(touch, monitors, builtin, "eDP-1")
+	assert.Equal(t, external, monitor)
+	assert.Equal(t, touchPolicyProximity, policy)
+
+	// 不是 USB 设备，映射到内置显示器
+	touch = &Touchscreen{UUID: "t2"}
+	monitor, policy = p.autoMap(touch, monitors, builtin, "HDMI-1")
+	assert.Equal(t, builtin, monitor)
+	assert.Equal(t, touchPolicyBuiltin, policy)
+
+	// USB 设备，映射到主显示器
+	touch = &Touchscreen{UUID: "t3", busType: BusTypeUSB}
+	monitor, policy = p.autoMap(touch, monitors, builtin, "HDMI-1")
+	assert.Equal(t, external, monitor)
+	assert.Equal(t, touchPolicyPrimary, policy)
+
+	// 没有 disable-when-target-disabled 时跳过被禁用的显示器
+	p, _ = parseTouchscreenPolicies("builtin,primary")
+	builtin.Enabled = false
+	monitor, policy = p.autoMap(&Touchscreen{UUID: "t4"}, monitors, builtin, "HDMI-1")
+	assert.Equal(t, external, monitor)
+	assert.Equal(t, touchPolicyPrimary, policy)
+}
+
+func Test_getTouchscreenMapMonitor(t *testing.T) {
+	monitors := Monitors{
+		&Monitor{ID: 1, Name: "DP-2", uuid: "touch-monitor"},
+		&Monitor{ID: 2, Name: "HDMI-1", uuid: "other"},
+	}
+	// 显示器从 DP-1 换到了 DP-2
+	v := touchscreenMapValue{OutputName: "DP-1", MonitorUuid: "touch-monitor"}
+	assert.Equal(t, monitors[0], getTouchscreenMapMonitor(v, monitors))
+
+	// 旧配置只有接口名
+	v = touchscreenMapValue{OutputName: "HDMI-1"}
+	assert.Equal(t, monitors[1], getTouchscreenMapMonitor(v, monitors))
+
+	v = touchscreenMapValue{OutputName: "HDMI-1", MonitorUuid: "gone"}
+	assert.Nil(t, getTouchscreenMapMonitor(v, monitors))
+}
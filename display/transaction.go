@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ApplyChanges 之后，需要在超时前调用 ConfirmChanges 确认，否则恢复到应用之前的布局，
+// 避免应用的模式导致黑屏时用户无法点击恢复。
+
+const defaultApplyConfirmTimeout = 15 * time.Second
+
+type applyTransaction struct {
+	id         uint32
+	monitorsId monitorsId
+	// 应用之前的显示器配置
+	prevConfigs SysMonitorConfigs
+	timer       *time.Timer
+}
+
+type applyTransactionManager struct {
+	mu      sync.Mutex
+	serial  uint32
+	timeout time.Duration
+	current *applyTransaction
+}
+
+func (tm *applyTransactionManager) setTimeout(timeout time.Duration) {
+	tm.mu.Lock()
+	tm.timeout = timeout
+	tm.mu.Unlock()
+}
+
+// begin 开始新的事务，未确认的旧事务被新事务取代，旧事务的快照仍作为恢复目标。
+// 超时时间不大于 0 时不开始事务，返回 0。
+func (tm *applyTransactionManager) begin(monitorsId monitorsId, prevConfigs SysMonitorConfigs,
+	onTimeout func(id uint32)) uint32 {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.timeout <= 0 {
+		tm.current = nil
+		return 0
+	}
+
+	if tm.current != nil {
+		tm.current.timer.Stop()
+		if tm.current.monitorsId == monitorsId {
+			prevConfigs = tm.current.prevConfigs
+		}
+	}
+
+	tm.serial++
+	if tm.serial == 0 {
+		tm.serial++
+	}
+	id := tm.serial
+	tm.current = &applyTransaction{
+		id:          id,
+		monitorsId:  monitorsId,
+		prevConfigs: prevConfigs.Clone(),
+		timer: time.AfterFunc(tm.timeout, func() {
+			onTimeout(id)
+		}),
+	}
+	return id
+}
+
+// take 取出 id 对应的事务，并停止计时器。
+func (tm *applyTransactionManager) take(id uint32) *applyTransaction {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tx := tm.current
+	if tx == nil || tx.id != id {
+		return nil
+	}
+	tx.timer.Stop()
+	tm.current = nil
+	return tx
+}
+
+// cancel 取消当前事务，不恢复配置。
+func (tm *applyTransactionManager) cancel() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.current == nil {
+		return
+	}
+	tm.current.timer.Stop()
+	tm.current = nil
+}
+
+func (m *Manager) confirmChanges(id uint32) error {
+	tx := m.applyTx.take(id)
+	if tx == nil {
+		return fmt.Errorf("not found transaction %d", id)
+	}
+	logger.Debug("confirm changes", id)
+	return nil
+}
+
+// revertChanges 事务超时未确认，恢复到应用之前的布局。
+func (m *Manager) revertChanges(id uint32) {
+	tx := m.applyTx.take(id)
+	if tx == nil {
+		return
+	}
+	logger.Info("changes not confirmed, revert", id)
+
+	m.resetChangesWithoutApply()
+	monitorMap := m.cloneMonitorMap()
+	monitorsId := getConnectedMonitors(monitorMap).getMonitorsId()
+	if monitorsId != tx.monitorsId {
+		logger.Warning("[revertChanges] monitors changed, skip revert")
+		m.markClean()
+		return
+	}
+
+	err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, tx.prevConfigs, nil)
+	if err != nil {
+		logger.Warning("[revertChanges] apply sys monitor configs failed:", err)
+	}
+	m.markClean()
+
+	err = m.service.Emit(m, "ChangesReverted", id)
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+// getPrevSysMonitorConfigs 获取显示器在修改之前的配置，用于恢复。
+func (m *Manager) getPrevSysMonitorConfigs() SysMonitorConfigs {
+	m.PropsMu.RLock()
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	m.monitorMapMu.Lock()
+	defer m.monitorMapMu.Unlock()
+
+	found := false
+	var result SysMonitorConfigs
+	for _, monitor := range getConnectedMonitors(m.monitorMap) {
+		monitor.PropsMu.RLock()
+		cfg := monitor.toPrevSysConfig()
+		monitor.PropsMu.RUnlock()
+		if !found && cfg.Name == primary {
+			cfg.Primary = true
+			found = true
+		}
+		result = append(result, cfg)
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyTransactionManager(t *testing.T) {
+	var tm applyTransactionManager
+	monitorsId := monitorsId{v1: "a,b"}
+	prevConfigs := SysMonitorConfigs{{UUID: "a", Enabled: true}}
+
+	// 超时时间为 0 时不开始事务
+	id := tm.begin(monitorsId, prevConfigs, func(id uint32) {})
+	assert.Equal(t, uint32(0), id)
+
+	tm.setTimeout(time.Hour)
+	id1 := tm.begin(monitorsId, prevConfigs, func(id uint32) {})
+	assert.NotEqual(t, uint32(0), id1)
+
+	// 新事务取代旧事务，恢复目标仍为第一次应用之前的配置
+	id2 := tm.begin(monitorsId, SysMonitorConfigs{{UUID: "b", Enabled: true}}, func(id uint32) {})
+	assert.NotEqual(t, id1, id2)
+	assert.Nil(t, tm.take(id1))
+	tx := tm.take(id2)
+	require.NotNil(t, tx)
+	assert.Equal(t, prevConfigs, tx.prevConfigs)
+	assert.Nil(t, tm.take(id2))
+
+	id3 := tm.begin(monitorsId, prevConfigs, func(id uint32) {})
+	tm.cancel()
+	assert.Nil(t, tm.take(id3))
+}
+
+func Test_applyTransactionManagerTimeout(t *testing.T) {
+	var tm applyTransactionManager
+	tm.setTimeout(10 * time.Millisecond)
+
+	ch := make(chan uint32, 1)
+	id := tm.begin(monitorsId{v1: "a"}, nil, func(id uint32) {
+		ch <- id
+	})
+	select {
+	case timeoutId := <-ch:
+		assert.Equal(t, id, timeoutId)
+	case <-time.After(time.Second):
+		t.Fatal("transaction not timeout")
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -21,6 +20,7 @@ import (
 	"github.com/linuxdeepin/go-gir/gudev-1.0"
 	"github.com/linuxdeepin/go-lib/utils"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 func getRotations(origin uint16) []uint16 {
@@ -132,44 +132,8 @@ func outputSliceContains(outputs []randr.Output, output randr.Output) bool {
 	return false
 }
 
-func getMonitorsCommonSizes(monitors []*Monitor) []Size {
-	var notUseBestMode bool
-	count := make(map[Size]int)
-	bestMode := monitors[0].BestMode
-	for _, monitor := range monitors {
-		if bestMode != monitor.BestMode {
-			notUseBestMode = true
-		}
-		smm := getSizeModeMap(monitor.Modes)
-		for size := range smm {
-			count[size]++
-		}
-	}
-
-	var commonSizes []Size
-	if !notUseBestMode {
-		return []Size{{bestMode.Width, bestMode.Height}}
-	}
-
-	for size, value := range count {
-		if value == len(monitors) {
-			commonSizes = append(commonSizes, size)
-		}
-	}
-	return commonSizes
-}
-
-func getMaxAreaSize(sizes []Size) Size {
-	if len(sizes) == 0 {
-		return Size{}
-	}
-	maxS := sizes[0]
-	for _, s := range sizes[1:] {
-		if (int(maxS.width) * int(maxS.height)) < (int(s.width) * int(s.height)) {
-			maxS = s
-		}
-	}
-	return maxS
+func getMonitorsCommonSizes(monitors []*Monitor) []core.Size {
+	return core.GetCommonSizes(Monitors(monitors).toCoreMonitors())
 }
 
 func parseEdid(edid []byte) (string, string) {
@@ -246,20 +210,6 @@ func getOutputUuidV0(name string, edid []byte) string {
 	return name + id
 }
 
-func sortMonitorsByPrimaryAndId(monitors []*Monitor, primary *Monitor) {
-	sort.Slice(monitors, func(i, j int) bool {
-		if primary == nil {
-			return monitors[i].ID < monitors[j].ID
-		}
-		if monitors[i].Name == primary.Name {
-			return true
-		} else if monitors[j].Name == primary.Name {
-			return false
-		}
-		return monitors[i].ID < monitors[j].ID
-	})
-}
-
 func getMinIdMonitor(monitors []*Monitor) *Monitor {
 	if len(monitors) == 0 {
 		return nil
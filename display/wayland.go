@@ -23,7 +23,9 @@ import (
 	gio "github.com/linuxdeepin/go-gir/gio-2.0"
 	"github.com/linuxdeepin/go-lib/dbusutil"
 	"github.com/linuxdeepin/go-lib/log"
+	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 type monitorIdGenerator struct {
@@ -333,11 +335,14 @@ type kMonitorManager struct {
 	stdNamesCache map[string]string
 
 	xSettingsGs *gio.Settings
+	// Xwayland 的连接，用于设置背光和 gamma
+	xConn *x.Conn
 }
 
-func newKMonitorManager(sessionSigLoop *dbusutil.SignalLoop) *kMonitorManager {
+func newKMonitorManager(sessionSigLoop *dbusutil.SignalLoop, xConn *x.Conn) *kMonitorManager {
 	kmm := &kMonitorManager{
 		sessionSigLoop: sessionSigLoop,
+		xConn:          xConn,
 		monitorMap:     make(map[uint32]*MonitorInfo),
 		stdNamesCache:  make(map[string]string),
 	}
@@ -449,6 +454,10 @@ func (mm *kMonitorManager) setMonitorFillMode(monitor *Monitor, fillMode string)
 	return nil
 }
 
+func (mm *kMonitorManager) addCustomMode(monitorId uint32, mode *core.CVTMode) error {
+	return errors.New("custom mode is not supported on wayland")
+}
+
 func (mm *kMonitorManager) getMonitors() []*MonitorInfo {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
@@ -680,6 +689,18 @@ func (mm *kMonitorManager) setMonitorPrimary(monitorId uint32) error {
 	return nil
 }
 
+func (mm *kMonitorManager) setBrightness(target brightnessTarget, value float64, temperature int) error {
+	return setHWBrightness(mm.xConn, target, value, temperature)
+}
+
+func (mm *kMonitorManager) getBrightnessSetter(target brightnessTarget) string {
+	return getHWBrightnessSetter(target)
+}
+
+func (mm *kMonitorManager) canSetBrightness(target brightnessTarget) bool {
+	return canSetHWBrightness(target)
+}
+
 func (mm *kMonitorManager) showCursor(show bool) error {
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"os"
 	"sync"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/linuxdeepin/go-x11-client/ext/input"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
 	"github.com/linuxdeepin/go-x11-client/ext/xfixes"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 var _xConn *x.Conn
@@ -27,10 +29,50 @@ var _useWayland bool
 
 var _inVM bool
 
-func Init(xConn *x.Conn, useWayland bool, inVM bool) {
+// 使用内存中模拟的显示器后端
+var _fakeOutputSpecs []fakeOutputSpec
+
+// InitOption 是 Init 的可选项
+type InitOption func()
+
+// WithFakeMonitors 使用内存中模拟的显示器后端，spec 的格式同环境变量 DEEPIN_DISPLAY_FAKE_MONITORS，
+// 比如 "eDP-1:1920x1080@60,HDMI-1:2560x1440,-VGA-1"，名称前加 "-" 表示未连接。
+func WithFakeMonitors(spec string) InitOption {
+	return func() {
+		specs, err := parseFakeOutputSpecs(spec)
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		_fakeOutputSpecs = specs
+	}
+}
+
+func useFakeMonitorManager() bool {
+	return len(_fakeOutputSpecs) > 0
+}
+
+// hasMultiMonitorSupport 是否支持按显示器应用配置，X 下需要 randr 版本大于等于 1.2。
+func hasMultiMonitorSupport() bool {
+	return _hasRandr1d2 || _useWayland || useFakeMonitorManager()
+}
+
+func Init(xConn *x.Conn, useWayland bool, inVM bool, opts ...InitOption) {
 	_xConn = xConn
 	_useWayland = useWayland
 	_inVM = inVM
+	if spec := os.Getenv(envFakeMonitors); spec != "" {
+		WithFakeMonitors(spec)()
+	}
+	for _, opt := range opts {
+		opt()
+	}
+	if useFakeMonitorManager() {
+		logger.Info("use fake monitor manager:", _fakeOutputSpecs)
+	}
+	if xConn == nil {
+		return
+	}
 	randrVersion, err := randr.QueryVersion(xConn, randr.MajorVersion, randr.MinorVersion).Reply(xConn)
 	if err != nil {
 		logger.Warning(err)
@@ -59,7 +101,7 @@ func Init(xConn *x.Conn, useWayland bool, inVM bool) {
 }
 
 func GetRecommendedScaleFactor() float64 {
-	if !_hasRandr1d2 {
+	if !_hasRandr1d2 || useFakeMonitorManager() {
 		return 1
 	}
 	resources, err := getScreenResources(_xConn)
@@ -127,7 +169,7 @@ func getScreenResources(xConn *x.Conn) (*randr.GetScreenResourcesReply, error) {
 const evMaskForHideCursor uint32 = input.XIEventMaskRawMotion | input.XIEventMaskRawTouchBegin
 
 func (m *Manager) listenXEvents() {
-	if _useWayland {
+	if _useWayland || useFakeMonitorManager() {
 		return
 	}
 	eventChan := m.xConn.MakeAndAddEventChan(50)
@@ -141,15 +183,20 @@ func (m *Manager) listenXEvents() {
 		return
 	}
 
-	var inputExtData *x.QueryExtensionReply
 	if _greeterMode {
 		// 仅 greeter 需要
 		err = m.doXISelectEvents(evMaskForHideCursor)
 		if err != nil {
 			logger.Warning(err)
 		}
-		inputExtData = m.xConn.GetExtensionData(input.Ext())
+	} else {
+		// 输入设备热插拔，用于刷新数位板
+		err = m.selectInputHierarchyEvent()
+		if err != nil {
+			logger.Warning(err)
+		}
 	}
+	inputExtData := m.xConn.GetExtensionData(input.Ext())
 
 	rrExtData := m.xConn.GetExtensionData(randr.Ext())
 
@@ -179,19 +226,23 @@ func (m *Manager) listenXEvents() {
 				m.handleScreenChanged(event, cfgTsChanged)
 
 			case x.GeGenericEventCode:
+				geEvent, _ := x.NewGeGenericEvent(ev)
+				if inputExtData == nil || geEvent.Extension != inputExtData.MajorOpcode {
+					continue
+				}
 				if !_greeterMode {
+					if geEvent.EventType == input.HierarchyEventCode {
+						m.handleInputDevicesChanged()
+					}
 					continue
 				}
 				// 仅 greeter 处理这个事件
-				geEvent, _ := x.NewGeGenericEvent(ev)
-				if geEvent.Extension == inputExtData.MajorOpcode {
-					switch geEvent.EventType {
-					case input.RawMotionEventCode:
-						m.beginMoveMouse()
+				switch geEvent.EventType {
+				case input.RawMotionEventCode:
+					m.beginMoveMouse()
 
-					case input.RawTouchBeginEventCode:
-						m.beginTouch()
-					}
+				case input.RawTouchBeginEventCode:
+					m.beginTouch()
 				}
 			}
 		}
@@ -213,7 +264,11 @@ type monitorManager interface {
 	apply(monitorsId monitorsId, monitorMap map[uint32]*Monitor, prevScreenSize screenSize, options applyOptions, fillModes map[string]string, primaryMonitorID uint32, displayMode byte) error
 	setMonitorPrimary(monitorId uint32) error
 	setMonitorFillMode(monitor *Monitor, fillMode string) error
+	addCustomMode(monitorId uint32, mode *core.CVTMode) error
 	showCursor(show bool) error
+	setBrightness(target brightnessTarget, value float64, temperature int) error
+	getBrightnessSetter(target brightnessTarget) string
+	canSetBrightness(target brightnessTarget) bool
 	HandleEvent(ev interface{})
 	HandleScreenChanged(e *randr.ScreenChangeNotifyEvent) (cfgTsChanged bool)
 }
@@ -858,6 +913,74 @@ func (mm *xMonitorManager) setMonitorFillMode(monitor *Monitor, fillMode string)
 	return nil
 }
 
+// addCustomMode 创建模式 mode 并添加到显示器上，已经添加过时什么也不做
+func (mm *xMonitorManager) addCustomMode(monitorId uint32, mode *core.CVTMode) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	output := randr.Output(monitorId)
+	outputInfo := mm.outputs[output]
+	if outputInfo == nil {
+		return fmt.Errorf("not found output %d", monitorId)
+	}
+
+	var modeId randr.Mode
+	for _, modeInfo := range mm.modes {
+		if modeInfo.Name == mode.Name {
+			modeId = randr.Mode(modeInfo.Id)
+			break
+		}
+	}
+	if modeId != 0 {
+		for _, id := range outputInfo.Modes {
+			if id == modeId {
+				return nil
+			}
+		}
+	} else {
+		modeInfo := &randr.ModeInfo{
+			Width:      mode.HDisplay,
+			Height:     mode.VDisplay,
+			DotClock:   mode.DotClock,
+			HSyncStart: mode.HSyncStart,
+			HSyncEnd:   mode.HSyncEnd,
+			HTotal:     mode.HTotal,
+			VSyncStart: mode.VSyncStart,
+			VSyncEnd:   mode.VSyncEnd,
+			VTotal:     mode.VTotal,
+			Name:       mode.Name,
+			ModeFlags:  randr.ModeFlagHsyncNegative | randr.ModeFlagVsyncPositive,
+		}
+		if mode.ReducedBlanking {
+			modeInfo.ModeFlags = randr.ModeFlagHsyncPositive | randr.ModeFlagVsyncNegative
+		}
+		root := mm.xConn.GetDefaultScreen().Root
+		reply, err := randr.CreateMode(mm.xConn, root, modeInfo).Reply(mm.xConn)
+		if err != nil {
+			return err
+		}
+		modeId = reply.Mode
+	}
+	logger.Debugf("add mode %v(%d) to output %v", mode.Name, modeId, outputInfo.Name)
+	err := randr.AddOutputModeChecked(mm.xConn, output, modeId).Check(mm.xConn)
+	if err != nil {
+		return err
+	}
+
+	// 添加模式不会改变配置的时间戳，需要主动刷新模式列表
+	resources, err := mm.getScreenResourcesCurrent()
+	if err != nil {
+		return err
+	}
+	mm.modes = resources.Modes
+	reply, err := mm.getOutputInfo(output)
+	if err != nil {
+		return err
+	}
+	mm.outputs[output] = (*OutputInfo)(reply)
+	mm.doDiff()
+	return nil
+}
+
 func getConnectedMonitors(monitorMap map[uint32]*Monitor) Monitors {
 	var monitors Monitors
 	for _, monitor := range monitorMap {
@@ -1243,6 +1366,18 @@ func (mm *xMonitorManager) handleScreenChanged(e *randr.ScreenChangeNotifyEvent)
 	return
 }
 
+func (mm *xMonitorManager) setBrightness(target brightnessTarget, value float64, temperature int) error {
+	return setHWBrightness(mm.xConn, target, value, temperature)
+}
+
+func (mm *xMonitorManager) getBrightnessSetter(target brightnessTarget) string {
+	return getHWBrightnessSetter(target)
+}
+
+func (mm *xMonitorManager) canSetBrightness(target brightnessTarget) bool {
+	return canSetHWBrightness(target)
+}
+
 func (mm *xMonitorManager) showCursor(show bool) error {
 	rootWin := mm.xConn.GetDefaultScreen().Root
 	var cookie x.VoidCookie
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/linuxdeepin/startdde/display/core"
 	"github.com/linuxdeepin/startdde/wl_display/brightness"
 )
 
@@ -79,13 +80,7 @@ func (m *Manager) changeBrightness(raised bool) error {
 		}
 
 		if setBr {
-			br = v + step
-			if br > 1.0 {
-				br = 1.0
-			}
-			if br < 0.1 {
-				br = 0.1
-			}
+			br = core.ClampBrightness(v+step, 0.1)
 			logger.Debug("[changeBrightness] will set to:", monitor.Name, br)
 			err := m.doSetBrightness(br, monitor.Name)
 			if err != nil {
@@ -139,13 +134,7 @@ func (m *Manager) doSetBrightnessAuxForBacklight(fake bool, value float64, name
 		}
 
 		for i := 1; i <= times; i++ {
-			br = value + step*float64(i)
-			if br > 1.0 {
-				br = 1.0
-			}
-			if br < 0.1 {
-				br = 0.1
-			}
+			br = core.ClampBrightness(value+step*float64(i), 0.1)
 			err := m.setMonitorBrightness(monitor0, br)
 			if err != nil {
 				logger.Warningf("brightness: failed to set brightness for %s: %v", name, err)
@@ -8,13 +8,16 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/linuxdeepin/go-lib/log"
 	"github.com/linuxdeepin/go-lib/xdg/basedir"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
-const configVersion = "4.0"
+// 5.0 开始使用和 X11 显示服务相同的 SysRootConfig 格式
+const configVersion = "5.0"
 
 var (
 	configFile        string
@@ -27,207 +30,57 @@ func init() {
 	configVersionFile = filepath.Join(cfgDir, "config.version")
 }
 
-type Config map[string]*ScreenConfig
-
-type ScreenConfig struct {
-	Custom  []*CustomModeConfig `json:",omitempty"`
-	Mirror  *MirrorModeConfig   `json:",omitempty"`
-	Extend  *ExtendModeConfig   `json:",omitempty"`
-	OnlyOne *OnlyOneModeConfig  `json:",omitempty"`
-	Single  *MonitorConfig      `json:",omitempty"`
-}
-
-type CustomModeConfig struct {
-	Name     string
-	Monitors []*MonitorConfig
-}
-
-type MirrorModeConfig struct {
-	Monitors []*MonitorConfig
-}
-
-type ExtendModeConfig struct {
-	Monitors []*MonitorConfig
-}
-
-type OnlyOneModeConfig struct {
-	Monitors []*MonitorConfig
-}
-
-func (s *ScreenConfig) getMonitorConfigs(mode uint8, customName string) []*MonitorConfig {
-	switch mode {
-	case DisplayModeCustom:
-		for _, custom := range s.Custom {
-			if custom.Name == customName {
-				return custom.Monitors
-			}
-		}
-	case DisplayModeMirror:
-		if s.Mirror == nil {
-			return nil
-		}
-		return s.Mirror.Monitors
-
-	case DisplayModeExtend:
-		if s.Extend == nil {
-			return nil
-		}
-		return s.Extend.Monitors
-
-	case DisplayModeOnlyOne:
-		if s.OnlyOne == nil {
-			return nil
-		}
-		return s.OnlyOne.Monitors
-	}
-
-	return nil
-}
-
-func getMonitorConfigByUuid(configs []*MonitorConfig, uuid string) *MonitorConfig {
-	for _, mc := range configs {
-		if mc.UUID == uuid {
-			return mc
-		}
-	}
-	return nil
-}
-
-func setMonitorConfigsPrimary(configs []*MonitorConfig, uuid string) {
-	for _, mc := range configs {
-		if mc.UUID == uuid {
-			mc.Primary = true
-		} else {
-			mc.Primary = false
-		}
-	}
-}
-
-func updateMonitorConfigsName(configs []*MonitorConfig, monitorMap map[uint32]*Monitor) {
-	for _, mc := range configs {
-		for _, m := range monitorMap {
-			if mc.UUID == m.uuid {
-				mc.Name = m.Name
-				break
-			}
-		}
-	}
-}
-
-func (s *ScreenConfig) setMonitorConfigs(mode uint8, customName string, configs []*MonitorConfig) {
-	switch mode {
-	case DisplayModeCustom:
-		foundName := false
-		for _, custom := range s.Custom {
-			if custom.Name == customName {
-				foundName = true
-				custom.Monitors = configs
-			}
-		}
-
-		// new custom
-		if !foundName {
-			s.Custom = append(s.Custom, &CustomModeConfig{
-				Name:     customName,
-				Monitors: configs,
-			})
-		}
-
-	case DisplayModeMirror:
-		if s.Mirror == nil {
-			s.Mirror = &MirrorModeConfig{}
-		}
-		s.Mirror.Monitors = configs
-
-	case DisplayModeExtend:
-		if s.Extend == nil {
-			s.Extend = &ExtendModeConfig{}
-		}
-		s.Extend.Monitors = configs
-
-	case DisplayModeOnlyOne:
-		s.setMonitorConfigsOnlyOne(configs)
-	}
-}
-
-func (s *ScreenConfig) setMonitorConfigsOnlyOne(configs []*MonitorConfig) {
-	if s.OnlyOne == nil {
-		s.OnlyOne = &OnlyOneModeConfig{}
-	}
-	oldConfigs := s.OnlyOne.Monitors
-	var newConfigs []*MonitorConfig
-	for _, cfg := range configs {
-		if !cfg.Enabled {
-			oldCfg := getMonitorConfigByUuid(oldConfigs, cfg.UUID)
-			if oldCfg != nil {
-				// 不设置 X,Y 是因为它们总是 0
-				cfg.Width = oldCfg.Width
-				cfg.Height = oldCfg.Height
-				cfg.RefreshRate = oldCfg.RefreshRate
-				cfg.Rotation = oldCfg.Rotation
-				cfg.Reflect = oldCfg.Reflect
-			} else {
-				continue
-			}
-		}
-		newConfigs = append(newConfigs, cfg)
-	}
-	s.OnlyOne.Monitors = newConfigs
-}
-
-type MonitorConfig struct {
-	UUID        string
-	Name        string
-	Enabled     bool
-	X           int16
-	Y           int16
-	Width       uint16
-	Height      uint16
-	Rotation    uint16
-	Reflect     uint16
-	RefreshRate float64
-	Primary     bool
-}
-
-func loadConfigV4(filename string) (Config, error) {
+// loadSysConfig 读取 SysRootConfig 格式的配置
+func loadSysConfig(filename string) (*core.SysRootConfig, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var c Config
-	err = json.Unmarshal(data, &c)
+	var rootCfg core.SysRootConfig
+	err = json.Unmarshal(data, &rootCfg)
 	if err != nil {
 		return nil, err
 	}
-
-	return c, nil
+	rootCfg.Fix()
+	return &rootCfg, nil
 }
 
-func loadConfig() (config Config) {
+// loadConfig 读取配置，旧版本的配置会被转换为 SysRootConfig 格式，在下次保存时写入。
+func loadConfig() (config *core.SysRootConfig) {
 	cfgVer, err := getConfigVersion(configVersionFile)
-	if err == nil {
-		if cfgVer == "3.3" {
-			cfg0, err := loadConfigV3_3(configFile)
-			if err == nil {
-				config = cfg0.toConfig()
-			} else if !os.IsNotExist(err) {
-				logger.Warning(err)
-			}
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warning(err)
+	}
+
+	switch cfgVer {
+	case configVersion:
+		config, err = loadSysConfig(configFile)
+
+	case "3.3":
+		var cfg0 ConfigV3_3
+		cfg0, err = loadConfigV3_3(configFile)
+		if err == nil {
+			config = cfg0.toConfig().toSysRootConfig()
 		}
 
-	} else if !os.IsNotExist(err) {
+	default:
+		// 4.0 或者没有版本文件
+		var cfg0 ConfigV4
+		cfg0, err = loadConfigV4(configFile)
+		if err == nil {
+			config = cfg0.toSysRootConfig()
+		}
+	}
+	if err != nil && !os.IsNotExist(err) {
 		logger.Warning(err)
 	}
 
-	if len(config) == 0 {
-		config, err = loadConfigV4(configFile)
-		if err != nil {
-			config = make(Config)
-			if !os.IsNotExist(err) {
-				logger.Warning(err)
-			}
+	if config == nil {
+		config = &core.SysRootConfig{
+			Version: core.SysConfigVersion,
 		}
+		config.Fix()
 	}
 
 	if logger.GetLogLevel() == log.LevelDebug {
@@ -236,21 +89,22 @@ func loadConfig() (config Config) {
 	return
 }
 
-func (c Config) save(filename string) error {
+func saveSysConfig(filename string, config *core.SysRootConfig) error {
 	var data []byte
 	var err error
+	config.Mu.Lock()
+	config.UpdateAt = time.Now().Format(time.RFC3339Nano)
 	if logger.GetLogLevel() == log.LevelDebug {
-		data, err = json.MarshalIndent(c, "", "    ")
-		if err != nil {
-			return err
-		}
+		data, err = json.MarshalIndent(config, "", "    ")
 	} else {
-		data, err = json.Marshal(c)
-		if err != nil {
-			return err
-		}
+		data, err = json.Marshal(config)
+	}
+	config.Mu.Unlock()
+	if err != nil {
+		return err
 	}
 
+	// #nosec G306
 	err = os.WriteFile(filename, data, 0644)
 	if err != nil {
 		return err
@@ -17,11 +17,11 @@ type ScreenConfigV3_3 struct {
 	BaseInfos []*MonitorConfiV3_3
 }
 
-func (sc *ScreenConfigV3_3) toMonitorConfigs() []*MonitorConfig {
-	result := make([]*MonitorConfig, len(sc.BaseInfos))
+func (sc *ScreenConfigV3_3) toMonitorConfigs() []*MonitorConfigV4 {
+	result := make([]*MonitorConfigV4, len(sc.BaseInfos))
 	for idx, bi := range sc.BaseInfos {
 		primary := bi.Name == sc.Primary
-		result[idx] = &MonitorConfig{
+		result[idx] = &MonitorConfigV4{
 			UUID:        bi.UUID,
 			Name:        bi.Name,
 			Enabled:     bi.Enabled,
@@ -68,8 +68,8 @@ func loadConfigV3_3(filename string) (ConfigV3_3, error) {
 	return c, nil
 }
 
-func (c ConfigV3_3) toConfig() Config {
-	newConfig := make(Config)
+func (c ConfigV3_3) toConfig() ConfigV4 {
+	newConfig := make(ConfigV4)
 
 	for id, sc := range c {
 		cfgKey := parseConfigKey(id)
@@ -80,12 +80,12 @@ func (c ConfigV3_3) toConfig() Config {
 				len(sc.BaseInfos) == 1 {
 
 				bi := sc.BaseInfos[0]
-				newConfig[jId] = &ScreenConfig{
+				newConfig[jId] = &ScreenConfigV4{
 					Custom:  nil,
 					Mirror:  nil,
 					Extend:  nil,
 					OnlyOne: nil,
-					Single: &MonitorConfig{
+					Single: &MonitorConfigV4{
 						UUID:        bi.UUID,
 						Name:        bi.Name,
 						Enabled:     bi.Enabled,
@@ -104,12 +104,12 @@ func (c ConfigV3_3) toConfig() Config {
 			// custom mode
 			screenCfg := newConfig[jId]
 			if screenCfg == nil {
-				screenCfg = &ScreenConfig{}
+				screenCfg = &ScreenConfigV4{}
 				newConfig[jId] = screenCfg
 			}
 
 			configs := sc.toMonitorConfigs()
-			screenCfg.setMonitorConfigs(DisplayModeCustom, cfgKey.name, configs)
+			screenCfg.setCustomMonitorConfigs(cfgKey.name, configs)
 		}
 	}
 	return newConfig
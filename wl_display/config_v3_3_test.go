@@ -80,7 +80,7 @@ func TestConfig(t *testing.T) {
 
 	screenCfg := cfg["eDP12c5a5c24e4ab14126abf8dc36e7e9d4"]
 	assert.NotNil(t, screenCfg)
-	assert.Equal(t, &MonitorConfig{
+	assert.Equal(t, &MonitorConfigV4{
 		UUID:        "eDP12c5a5c24e4ab14126abf8dc36e7e9d4",
 		Name:        "eDP-1",
 		Enabled:     true,
@@ -100,7 +100,7 @@ func TestConfig(t *testing.T) {
 	customModeCfg := screenCfg.Custom[0]
 	assert.Equal(t, "_dde_display_config_private", customModeCfg.Name)
 	assert.Len(t, customModeCfg.Monitors, 2)
-	assert.Equal(t, &MonitorConfig{
+	assert.Equal(t, &MonitorConfigV4{
 		UUID:        "eDP12c5a5c24e4ab14126abf8dc36e7e9d4",
 		Name:        "eDP-1",
 		Enabled:     true,
@@ -113,7 +113,7 @@ func TestConfig(t *testing.T) {
 		RefreshRate: 60.00471735199308,
 		Primary:     true,
 	}, customModeCfg.Monitors[0])
-	assert.Equal(t, &MonitorConfig{
+	assert.Equal(t, &MonitorConfigV4{
 		UUID:        "HDMIf5cae317c40b01139be5af61896be0cf",
 		Name:        "HDMI-2",
 		Enabled:     true,
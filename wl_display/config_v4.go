@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2023 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+type ConfigV4 map[string]*ScreenConfigV4
+
+type ScreenConfigV4 struct {
+	Custom  []*CustomModeConfigV4 `json:",omitempty"`
+	Mirror  *ModeConfigV4         `json:",omitempty"`
+	Extend  *ModeConfigV4         `json:",omitempty"`
+	OnlyOne *ModeConfigV4         `json:",omitempty"`
+	Single  *MonitorConfigV4      `json:",omitempty"`
+}
+
+type CustomModeConfigV4 struct {
+	Name     string
+	Monitors []*MonitorConfigV4
+}
+
+type ModeConfigV4 struct {
+	Monitors []*MonitorConfigV4
+}
+
+type MonitorConfigV4 struct {
+	UUID        string
+	Name        string
+	Enabled     bool
+	X           int16
+	Y           int16
+	Width       uint16
+	Height      uint16
+	Rotation    uint16
+	Reflect     uint16
+	RefreshRate float64
+	Primary     bool
+	Scale       float64 `json:",omitempty"`
+}
+
+func (s *ScreenConfigV4) setCustomMonitorConfigs(name string, configs []*MonitorConfigV4) {
+	for _, custom := range s.Custom {
+		if custom.Name == name {
+			custom.Monitors = configs
+			return
+		}
+	}
+
+	s.Custom = append(s.Custom, &CustomModeConfigV4{
+		Name:     name,
+		Monitors: configs,
+	})
+}
+
+func loadConfigV4(filename string) (ConfigV4, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var c ConfigV4
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *MonitorConfigV4) toSysMonitorConfig() *core.SysMonitorConfig {
+	return &core.SysMonitorConfig{
+		UUID:        c.UUID,
+		Name:        c.Name,
+		Enabled:     c.Enabled,
+		X:           c.X,
+		Y:           c.Y,
+		Width:       c.Width,
+		Height:      c.Height,
+		Rotation:    c.Rotation,
+		Reflect:     c.Reflect,
+		RefreshRate: c.RefreshRate,
+		Primary:     c.Primary,
+		Scale:       c.Scale,
+	}
+}
+
+func toSysMonitorConfigs(configs []*MonitorConfigV4) core.SysMonitorConfigs {
+	result := make(core.SysMonitorConfigs, len(configs))
+	for i, cfg := range configs {
+		result[i] = cfg.toSysMonitorConfig()
+	}
+	return result
+}
+
+// toSysRootConfig 转换为和 X11 显示服务相同的 SysRootConfig 格式，自定义模式的配置转换为命名布局。
+func (c ConfigV4) toSysRootConfig() *core.SysRootConfig {
+	screens := make(map[string]*core.SysScreenConfig, len(c))
+	for id, sc := range c {
+		if sc == nil {
+			continue
+		}
+		screenCfg := &core.SysScreenConfig{}
+		if sc.Single != nil {
+			screenCfg.SetSingleMonitorConfigs(core.SysMonitorConfigs{sc.Single.toSysMonitorConfig()})
+		}
+		if sc.Mirror != nil {
+			screenCfg.SetMonitorConfigs(DisplayModeMirror, "", toSysMonitorConfigs(sc.Mirror.Monitors))
+		}
+		if sc.Extend != nil {
+			screenCfg.SetMonitorConfigs(DisplayModeExtend, "", toSysMonitorConfigs(sc.Extend.Monitors))
+		}
+		if sc.OnlyOne != nil {
+			// v4 中保存了所有显示器的配置，按照 uuid 分开保存
+			for _, cfg := range sc.OnlyOne.Monitors {
+				screenCfg.SetMonitorConfigsOnlyOne(cfg.UUID, core.SysMonitorConfigs{cfg.toSysMonitorConfig()})
+				if cfg.Enabled {
+					screenCfg.OnlyOneUuid = cfg.UUID
+				}
+			}
+		}
+		for _, custom := range sc.Custom {
+			configs := toSysMonitorConfigs(custom.Monitors)
+			screenCfg.SetProfile(custom.Name, &core.SysProfileConfig{
+				DisplayMode: configs.GetRealDisplayMode(),
+				Monitors:    configs,
+			})
+		}
+		screens[id] = screenCfg
+	}
+
+	rootCfg := &core.SysRootConfig{
+		Version: core.SysConfigVersion,
+		Config: core.SysConfig{
+			Screens: screens,
+		},
+	}
+	rootCfg.Fix()
+	return rootCfg
+}
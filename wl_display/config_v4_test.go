@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/linuxdeepin/startdde/display/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cfgStrV4 = `{
+  "uuid-a,uuid-b": {
+    "Custom": [
+      {
+        "Name": "Desk",
+        "Monitors": [
+          {"UUID": "uuid-a", "Name": "eDP-1", "Enabled": true, "X": 0, "Y": 0, "Width": 1920, "Height": 1080, "Rotation": 1, "RefreshRate": 60, "Primary": true},
+          {"UUID": "uuid-b", "Name": "HDMI-1", "Enabled": true, "X": 1920, "Y": 0, "Width": 2560, "Height": 1440, "Rotation": 1, "RefreshRate": 60, "Scale": 1.5}
+        ]
+      }
+    ],
+    "Extend": {
+      "Monitors": [
+        {"UUID": "uuid-a", "Name": "eDP-1", "Enabled": true, "X": 0, "Y": 0, "Width": 1920, "Height": 1080, "Rotation": 1, "RefreshRate": 60, "Primary": true},
+        {"UUID": "uuid-b", "Name": "HDMI-1", "Enabled": true, "X": 1920, "Y": 0, "Width": 2560, "Height": 1440, "Rotation": 1, "RefreshRate": 60}
+      ]
+    },
+    "OnlyOne": {
+      "Monitors": [
+        {"UUID": "uuid-a", "Name": "eDP-1", "Enabled": false, "Width": 1600, "Height": 900, "Rotation": 1, "RefreshRate": 60},
+        {"UUID": "uuid-b", "Name": "HDMI-1", "Enabled": true, "Width": 2560, "Height": 1440, "Rotation": 1, "RefreshRate": 60, "Primary": true}
+      ]
+    }
+  },
+  "uuid-a": {
+    "Single": {"UUID": "uuid-a", "Name": "eDP-1", "Enabled": true, "Width": 1920, "Height": 1080, "Rotation": 1, "RefreshRate": 60, "Primary": true}
+  }
+}`
+
+func TestConfigV4ToSysRootConfig(t *testing.T) {
+	var cfg0 ConfigV4
+	err := json.Unmarshal([]byte(cfgStrV4), &cfg0)
+	require.NoError(t, err)
+
+	rootCfg := cfg0.toSysRootConfig()
+	assert.Equal(t, core.SysConfigVersion, rootCfg.Version)
+	assert.Len(t, rootCfg.Config.Screens, 2)
+
+	screenCfg := rootCfg.Config.Screens["uuid-a"]
+	require.NotNil(t, screenCfg)
+	single := screenCfg.GetSingleMonitorConfigs()
+	require.Len(t, single, 1)
+	assert.Equal(t, uint16(1920), single[0].Width)
+	assert.Equal(t, float64(1), single[0].Brightness)
+
+	screenCfg = rootCfg.Config.Screens["uuid-a,uuid-b"]
+	require.NotNil(t, screenCfg)
+	assert.Len(t, screenCfg.GetMonitorConfigs(DisplayModeExtend, ""), 2)
+
+	// 自定义模式转换为布局
+	assert.Equal(t, []string{"Desk"}, screenCfg.GetProfileNames())
+	profile := screenCfg.GetProfile("Desk")
+	assert.Equal(t, DisplayModeExtend, profile.DisplayMode)
+	assert.Equal(t, 1.5, profile.Monitors.GetByUuid("uuid-b").Scale)
+
+	// 单屏模式按 uuid 分开保存
+	assert.Equal(t, "uuid-b", screenCfg.OnlyOneUuid)
+	cfg := screenCfg.GetMonitorConfigs(DisplayModeOnlyOne, "uuid-a").GetByUuid("uuid-a")
+	require.NotNil(t, cfg)
+	assert.Equal(t, uint16(1600), cfg.Width)
+	assert.Len(t, getScreenMonitorConfigs(screenCfg, DisplayModeOnlyOne, ""), 1)
+	assert.Len(t, getScreenMonitorConfigs(screenCfg, DisplayModeCustom, "Desk"), 2)
+	assert.Nil(t, getScreenMonitorConfigs(screenCfg, DisplayModeCustom, "NotExist"))
+}
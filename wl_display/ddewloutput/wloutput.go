@@ -151,18 +151,24 @@ func SetOutputs(list OutputList) error {
 	return nil
 }
 
-func doSetOutput(info *Output) error {
-	var enabled = 1
-	if !info.Enabled {
-		enabled = 0
+// SetOutputsContext 用一条 dde_wloutput set 命令设置多个输出
+func SetOutputsContext(ctx context.Context, list OutputList) error {
+	if len(list) == 0 {
+		return nil
+	}
+	args := getSetOutputsArgs(list)
+	fmt.Println("[DDE] [WLOutput] will apply:", args)
+	data, err := exec.CommandContext(ctx, ddeWLOutputCmd, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s(%s)", string(data), err)
 	}
+	return nil
+}
 
+func doSetOutput(info *Output) error {
 	fmt.Println("[DDE] [WLOutput] will apply:", info.UUID, info.Enabled, info.X, info.Y,
 		info.Width, info.Height, info.Refresh, info.Transform)
-	data, err := exec.Command(ddeWLOutputCmd, "set", info.UUID, fmt.Sprintf("%d", enabled),
-		fmt.Sprintf("%d", info.X), fmt.Sprintf("%d", info.Y), fmt.Sprintf("%d", info.Width),
-		fmt.Sprintf("%d", info.Height), fmt.Sprintf("%d", int32(info.Refresh*1000)),
-		fmt.Sprintf("%d", info.Transform)).CombinedOutput()
+	data, err := exec.Command(ddeWLOutputCmd, getSetOutputsArgs(OutputList{info})...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("%s(%s)", string(data), err)
 	}
@@ -170,6 +176,23 @@ func doSetOutput(info *Output) error {
 	return nil
 }
 
+// getSetOutputsArgs 返回 dde_wloutput 的参数，每个输出依次为
+// uuid enabled x y width height refresh transform
+func getSetOutputsArgs(list OutputList) []string {
+	args := []string{"set"}
+	for _, info := range list {
+		var enabled = 1
+		if !info.Enabled {
+			enabled = 0
+		}
+		args = append(args, info.UUID, fmt.Sprintf("%d", enabled),
+			fmt.Sprintf("%d", info.X), fmt.Sprintf("%d", info.Y), fmt.Sprintf("%d", info.Width),
+			fmt.Sprintf("%d", info.Height), fmt.Sprintf("%d", int32(info.Refresh*1000)),
+			fmt.Sprintf("%d", info.Transform))
+	}
+	return args
+}
+
 func parseWLOutputData(data []byte) (OutputList, error) {
 	var list OutputList
 	var info *Output
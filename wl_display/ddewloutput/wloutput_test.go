@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2023 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package ddewloutput
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getSetOutputsArgs(t *testing.T) {
+	list := OutputList{
+		{UUID: "uuid-1", Enabled: true, X: 0, Y: 0, Width: 1920, Height: 1080, Refresh: 60, Transform: 0, ScaleF: 1.25},
+		{UUID: "uuid-2", Enabled: true, X: 1920, Y: 0, Width: 2560, Height: 1440, Refresh: 59.951, Transform: 1, ScaleF: 1.5},
+		{UUID: "uuid-3", Enabled: false},
+	}
+	args := getSetOutputsArgs(list)
+	assert.Equal(t, []string{"set",
+		"uuid-1", "1", "0", "0", "1920", "1080", "60000", "0",
+		"uuid-2", "1", "1920", "0", "2560", "1440", "59951", "1",
+		"uuid-3", "0", "0", "0", "0", "0", "0", "0",
+	}, args)
+	// 每个输出固定 8 个参数
+	assert.Equal(t, 1+8*len(list), len(args))
+
+	assert.Equal(t, []string{"set"}, getSetOutputsArgs(nil))
+}
@@ -338,6 +338,27 @@ func (v *Monitor) emitPropChangedRefreshRate(value float64) error {
 	return v.service.EmitPropertyChanged(v, "RefreshRate", value)
 }
 
+func (v *Monitor) setPropScale(value float64) (changed bool) {
+	if v.Scale != value {
+		v.Scale = value
+		v.emitPropChangedScale(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) setScale(value float64) (changed bool) {
+	if v.Scale != value {
+		v.Scale = value
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedScale(value float64) error {
+	return v.service.EmitPropertyChanged(v, "Scale", value)
+}
+
 func (v *Monitor) setPropCurrentMode(value ModeInfo) {
 	v.CurrentMode = value
 	v.emitPropChangedCurrentMode(value)
@@ -10,22 +10,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_getMaxAreaSize(t *testing.T) {
-	size := getMaxAreaSize([]Size{
-		{1024, 768},
-		{640, 480},
-		{1280, 720},
-		{800, 600},
-	})
-	assert.Equal(t, Size{1280, 720}, size)
-	size = getMaxAreaSize(nil)
-	assert.Equal(t, Size{}, size)
-	size = getMaxAreaSize([]Size{
-		{1024, 768},
-	})
-	assert.Equal(t, Size{1024, 768}, size)
-}
-
 func Test_filterModeInfos(t *testing.T) {
 	modes := []ModeInfo{
 		{
@@ -197,3 +181,31 @@ func TestCalcRecommendedScaleFactor(t *testing.T) {
 			rec.widthPx, rec.heightPx, rec.widthMm, rec.heightMm)
 	}
 }
+
+func Test_getScreenScaleFactors(t *testing.T) {
+	monitors := Monitors{
+		{Name: "eDP-1", Enabled: true, Scale: 1.25},
+		{Name: "HDMI-1", Enabled: true, Scale: 1.25},
+		{Name: "DP-1", Enabled: false, Scale: 2},
+	}
+	assert.Equal(t, map[string]float64{"ALL": 1.25}, getScreenScaleFactors(monitors, "eDP-1"))
+
+	monitors[1].Scale = 1.5
+	assert.Equal(t, map[string]float64{
+		"ALL":    1.5,
+		"eDP-1":  1.25,
+		"HDMI-1": 1.5,
+	}, getScreenScaleFactors(monitors, "HDMI-1"))
+
+	assert.Nil(t, getScreenScaleFactors(Monitors{{Name: "eDP-1"}}, "eDP-1"))
+
+	assert.True(t, isValidScaleFactor(1))
+	assert.True(t, isValidScaleFactor(2.75))
+	assert.False(t, isValidScaleFactor(0.5))
+	assert.False(t, isValidScaleFactor(3.25))
+
+	// SetScale 使用的缩放比例和 XSettings 的一样按 0.25 取整
+	assert.Equal(t, 1.25, toListedScaleFactor(1.2))
+	assert.Equal(t, 1.5, toListedScaleFactor(1.4))
+	assert.Equal(t, 3.0, toListedScaleFactor(3.0))
+}
@@ -153,5 +153,10 @@ func (v *Monitor) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetRotation,
 			InArgs: []string{"value"},
 		},
+		{
+			Name:   "SetScale",
+			Fn:     v.SetScale,
+			InArgs: []string{"value"},
+		},
 	}
 }
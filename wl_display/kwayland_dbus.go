@@ -9,7 +9,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os/exec"
 	"sort"
 	"strings"
 	"sync"
@@ -113,54 +112,105 @@ func newKOutputInfoByUUID(uuid string) (*KOutputInfo, error) {
 	return &kinfo, nil
 }
 
-func (m *Manager) applyByWLOutput() error {
-	var disabledMonitors []*Monitor
-	args := make([]string, 0)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+// getWLOutputs 按显示器的设置生成要应用的输出，返回启用的和禁用的输出
+func (m *Manager) getWLOutputs() (enabled, disabled ddewloutput.OutputList) {
 	for _, monitor := range m.monitorMap {
-		trans := int32(randrRotationToTransform(int(monitor.Rotation)))
 		if !monitor.Enabled {
-			disabledMonitors = append(disabledMonitors, monitor)
+			disabled = append(disabled, &ddewloutput.Output{
+				Name: monitor.Name,
+				UUID: monitor.uuid,
+			})
 			continue
 		}
-		logger.Debug("---------Will apply:", monitor.Name, monitor.uuid, monitor.Enabled, monitor.X, monitor.Y, monitor.CurrentMode, trans)
-
-		args = append(args, monitor.uuid, fmt.Sprint("1"),
-			fmt.Sprintf("%d", monitor.X), fmt.Sprintf("%d", monitor.Y), fmt.Sprintf("%d", monitor.CurrentMode.Width),
-			fmt.Sprintf("%d", monitor.CurrentMode.Height), fmt.Sprintf("%d", int32(monitor.CurrentMode.Rate*1000)),
-			fmt.Sprintf("%d", trans))
+		scale := monitor.Scale
+		if scale <= 0 {
+			scale = 1
+		}
+		enabled = append(enabled, &ddewloutput.Output{
+			Name:      monitor.Name,
+			UUID:      monitor.uuid,
+			Enabled:   true,
+			X:         int32(monitor.X),
+			Y:         int32(monitor.Y),
+			Width:     int32(monitor.CurrentMode.Width),
+			Height:    int32(monitor.CurrentMode.Height),
+			Transform: int32(randrRotationToTransform(int(monitor.Rotation))),
+			Refresh:   monitor.CurrentMode.Rate,
+			ScaleF:    scale,
+		})
 	}
+	return
+}
 
-	if len(args) > 0 {
-		cmdline := exec.CommandContext(ctx, "dde_wloutput", "set")
-		cmdline.Args = append(cmdline.Args, args...)
-		logger.Info("cmd line args:", cmdline.Args)
+func (m *Manager) applyByWLOutput() error {
+	enabledOutputs, disabledOutputs := m.getWLOutputs()
+	for _, out := range enabledOutputs {
+		logger.Debug("---------Will apply:", out.Name, out.UUID, out.X, out.Y, out.Width, out.Height,
+			out.Refresh, out.Transform, out.ScaleF)
+	}
 
-		data, err := cmdline.CombinedOutput()
+	if len(enabledOutputs) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		err := ddewloutput.SetOutputsContext(ctx, enabledOutputs)
 		cancel()
 		// ignore timeout signal
 		if err != nil && !strings.Contains(err.Error(), "killed") {
-			logger.Warningf("%s(%s)", string(data), err)
+			logger.Warning(err)
 			return err
 		}
 		// wait request done
 		//time.Sleep(time.Millisecond * 500)
 	}
 
-	for _, monitor := range disabledMonitors {
-		logger.Debug("-----------Will disable output:", monitor.Name)
+	for _, out := range disabledOutputs {
+		logger.Debug("-----------Will disable output:", out.Name)
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-		data, err := exec.CommandContext(ctx, "dde_wloutput", "set", monitor.uuid, "0", "0", "0", "0", "0", "0", "0").CombinedOutput()
+		err := ddewloutput.SetOutputsContext(ctx, ddewloutput.OutputList{out})
 		cancel()
 		// ignore timeout signal
 		if err != nil && !strings.Contains(err.Error(), "killed") {
-			logger.Warningf("%s(%s)", string(data), err)
+			logger.Warning(err)
 			return err
 		}
 		// wait request done
 		//time.Sleep(time.Millisecond * 500)
 	}
-	return nil
+
+	return m.applyScales(enabledOutputs)
+}
+
+// applyScales dde_wloutput set 不支持缩放比例，缩放比例有变化的输出通过 kwayland 的 Apply 设置
+func (m *Manager) applyScales(outputs ddewloutput.OutputList) error {
+	sinfo, err := ddewloutput.GetScreenInfo()
+	if err != nil {
+		return err
+	}
+
+	var outputInfos []*KOutputInfo
+	for _, out := range outputs {
+		current := sinfo.Outputs.Get(out.UUID)
+		if current != nil && current.ScaleF == out.ScaleF {
+			continue
+		}
+		outputInfos = append(outputInfos, &KOutputInfo{
+			Uuid:        out.UUID,
+			Enabled:     1,
+			X:           out.X,
+			Y:           out.Y,
+			Width:       out.Width,
+			Height:      out.Height,
+			RefreshRate: int32(out.Refresh * 1000),
+			Transform:   out.Transform,
+			Scale:       out.ScaleF,
+		})
+	}
+	if len(outputInfos) == 0 {
+		return nil
+	}
+
+	outputInfosJson := jsonMarshal(&outputInfoWrap{OutputInfo: outputInfos})
+	logger.Debug("Will apply scale:", outputInfosJson)
+	return m.management.Apply(0, outputInfosJson)
 }
 
 func (oi *KOutputInfo) getModes() (result []ModeInfo) {
@@ -24,15 +24,17 @@ import (
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
 	display "github.com/linuxdeepin/startdde/display"
+	"github.com/linuxdeepin/startdde/display/core"
 	"github.com/linuxdeepin/startdde/wl_display/brightness"
 )
 
 const (
-	DisplayModeCustom uint8 = iota
-	DisplayModeMirror
-	DisplayModeExtend
-	DisplayModeOnlyOne
-	DisplayModeMirrorOnlyOne
+	DisplayModeCustom  = core.DisplayModeCustom
+	DisplayModeMirror  = core.DisplayModeMirror
+	DisplayModeExtend  = core.DisplayModeExtend
+	DisplayModeOnlyOne = core.DisplayModeOnlyOne
+	// 以下的模式只在 Wayland 下使用，不保存在配置中
+	DisplayModeMirrorOnlyOne uint8 = iota
 	DisplayModeExtendOnlyOne
 	DisplayModeUnknow
 )
@@ -74,7 +76,7 @@ type Manager struct {
 	management kwayland.OutputManagement
 
 	PropsMu              sync.RWMutex
-	config               Config
+	sysConfig            core.SysRootConfig
 	recommendScaleFactor float64
 	monitorMap           map[uint32]*Monitor
 	monitorMapMu         sync.Mutex
@@ -106,6 +108,8 @@ type Manager struct {
 	ScreenHeight    uint16
 	primarysettings *gio.Settings
 	mutiMonitorsPos uint8
+	// 触摸屏映射的配置，格式和 display 的一样
+	touchscreenMap map[string]core.TouchscreenMapValue
 
 	methods *struct {
 		AssociateTouch         func() `in:"outputName,touch"`
@@ -138,6 +142,14 @@ type ModeInfo struct {
 
 type ModeInfos []ModeInfo
 
+func (mi ModeInfo) toCoreMode() core.Mode {
+	return core.Mode{
+		Width:  mi.Width,
+		Height: mi.Height,
+		Rate:   mi.Rate,
+	}
+}
+
 func (infos ModeInfos) Len() int {
 	return len(infos)
 }
@@ -176,7 +188,7 @@ func newManager(service *dbusutil.Service) *Manager {
 	m.customDisplayMode = uint8(m.settings.GetInt("custom-display-mode"))
 	m.CurrentCustomId = m.settings.GetString(gsKeyCustomMode)
 
-	m.config = loadConfig()
+	m.sysConfig.CopyFrom(loadConfig())
 	sessionBus := service.Conn()
 	m.management = kwayland.NewOutputManagement(sessionBus)
 	m.mig = newMonitorIdGenerator()
@@ -363,9 +375,9 @@ func (m *Manager) applyDisplayMode() {
 	if len(monitors) == 1 {
 		// 单屏
 		screenCfg := m.getScreenConfig()
-		var config *MonitorConfig
-		if screenCfg.Single != nil {
-			config = screenCfg.Single
+		var config *core.SysMonitorConfig
+		if configs := screenCfg.GetSingleMonitorConfigs(); len(configs) > 0 {
+			config = configs[0]
 		} else {
 			config = monitors[0].toConfig()
 			config.Enabled = true
@@ -379,7 +391,7 @@ func (m *Manager) applyDisplayMode() {
 			config.Rotation = randr.RotationRotate0
 		}
 
-		err = m.applyConfigs([]*MonitorConfig{config})
+		err = m.applyConfigs(core.SysMonitorConfigs{config})
 		if err != nil {
 			logger.Warning("failed to apply configs:", err)
 		}
@@ -601,6 +613,10 @@ func (m *Manager) addMonitor(outputInfo *KOutputInfo) error {
 	monitor.Width = monitor.CurrentMode.Width
 	monitor.Height = monitor.CurrentMode.Height
 	monitor.RefreshRate = monitor.CurrentMode.Rate
+	monitor.Scale = outputInfo.Scale
+	if monitor.Scale <= 0 {
+		monitor.Scale = 1
+	}
 
 	monitor.Rotations = []uint16{randr.RotationRotate0, randr.RotationRotate90,
 		randr.RotationRotate180, randr.RotationRotate270}
@@ -655,6 +671,9 @@ func (m *Manager) updateMonitor(monitor *Monitor, outputInfo *KOutputInfo) {
 	monitor.setPropHeight(uint16(outputInfo.Height))
 	monitor.setPropRefreshRate(monitor.CurrentMode.Rate)
 	monitor.setPropRotation(outputInfo.rotation())
+	if outputInfo.Scale > 0 {
+		monitor.setPropScale(outputInfo.Scale)
+	}
 	//monitor.setPropReflect(0) //TODO
 
 	monitor.manufacturer = outputInfo.Manufacturer
@@ -700,55 +719,16 @@ func (m *Manager) updateScreenSize() {
 
 func (m *Manager) switchModeMirror() (err error) {
 	logger.Debug("switch mode mirror")
-	// screenCfg := m.getScreenConfig()
-	// configs := screenCfg.getMonitorConfigs(DisplayModeMirror, "")
 	monitors := m.getConnectedMonitors()
-	commonSizes := getMonitorsCommonSizes(monitors)
-	if len(commonSizes) == 0 {
-		err = errors.New("not found common size")
-		return
-	}
-	maxSize := getMaxAreaSize(commonSizes)
-	logger.Debug("max common size:", maxSize)
-	for _, monitor := range m.monitorMap {
-		if monitor.Connected {
-			monitor.enable(true)
-			// cfg := getMonitorConfigByUuid(configs, monitor.uuid)
-			var mode ModeInfo
-			// if cfg != nil {
-			// mode = monitor.selectMode(cfg.Width, cfg.Height, cfg.RefreshRate)
-			// } else {
-			mode, _ = getFirstModeBySize(monitor.Modes, maxSize.width, maxSize.height)
-			// }
-			monitor.setMode(mode)
-			monitor.setPosition(0, 0)
-			monitor.setRotation(randr.RotationRotate0)
-			monitor.setReflect(0)
-
-		} else {
-			monitor.enable(false)
-		}
+	primaryMonitor := getDefaultPrimaryMonitor(monitors)
+	if primaryMonitor == nil {
+		return errors.New("not found primary monitor")
 	}
-
-	err = m.apply()
+	configs, err := core.BuildMirrorConfigs(Monitors(monitors).toCoreMonitors(), primaryMonitor.toCoreMonitor())
 	if err != nil {
 		return
 	}
-
-	monitor0 := getMinIDMonitor(m.getConnectedMonitors())
-	if monitor0 != nil {
-		err = m.setMonitorPrimary(monitor0)
-		if err != nil {
-			return
-		}
-	}
-
-	//save config
-	err = m.setPrimarySettings(monitor0.Name)
-	if err != nil {
-		logger.Warning(err)
-	}
-	return
+	return m.applyConfigs(configs)
 }
 
 //func (m *Manager) getScreenSize1() screenSize {
@@ -784,7 +764,12 @@ func (m *Manager) switchModeMirror() (err error) {
 
 func (m *Manager) apply() error {
 	m.AdjustPositonAfterSetMode()
-	return m.applyByWLOutput()
+	err := m.applyByWLOutput()
+	if err != nil {
+		return err
+	}
+	m.syncScaleFactorsToXSettings()
+	return nil
 
 	// var outputInfos []*KOutputInfo
 	// for _, monitor := range m.monitorMap {
@@ -1037,7 +1022,7 @@ func (m *Manager) setPrimary(name string) error {
 
 	case DisplayModeExtend, DisplayModeCustom:
 		screenCfg := m.getScreenConfig()
-		configs := screenCfg.getMonitorConfigs(m.DisplayMode, m.CurrentCustomId)
+		configs := getScreenMonitorConfigs(screenCfg, m.DisplayMode, m.CurrentCustomId)
 
 		var monitor0 *Monitor
 		for _, monitor := range m.monitorMap {
@@ -1065,7 +1050,7 @@ func (m *Manager) setPrimary(name string) error {
 		} else {
 			// modify configs
 			updateMonitorConfigsName(configs, m.monitorMap)
-			setMonitorConfigsPrimary(configs, monitor0.uuid)
+			configs.SetPrimary(monitor0.uuid)
 		}
 
 		err := m.setMonitorPrimary(monitor0)
@@ -1073,7 +1058,7 @@ func (m *Manager) setPrimary(name string) error {
 			return err
 		}
 
-		screenCfg.setMonitorConfigs(m.DisplayMode, m.CurrentCustomId, configs)
+		m.setScreenMonitorConfigs(screenCfg, m.DisplayMode, m.CurrentCustomId, configs)
 
 		err = m.saveConfig()
 		if err != nil {
@@ -1093,100 +1078,104 @@ func (m *Manager) setPrimary(name string) error {
 
 func (m *Manager) switchModeExtend(primary string) (err error) {
 	logger.Debug("switch mode extend")
-	var monitors []*Monitor
-	for _, monitor := range m.monitorMap {
-		monitors = append(monitors, monitor)
-	}
-	sortMonitorsByID(monitors)
+	monitors := m.getConnectedMonitors()
 	screenCfg := m.getScreenConfig()
-	configs := screenCfg.getMonitorConfigs(DisplayModeExtend, "")
-
-	var xOffset int = 0
-	var monitor0 *Monitor
-	for _, monitor := range monitors {
-		if monitor.Connected {
-			monitor.enable(true)
-
-			cfg := getMonitorConfigByUuid(configs, monitor.uuid)
-			var mode ModeInfo
-			if cfg != nil {
-				mode = monitor.selectMode(cfg.Width, cfg.Height, cfg.RefreshRate)
-				if monitor0 == nil && cfg.Primary {
-					monitor0 = monitor
-				}
-
-			} else {
-				mode = monitor.BestMode
-			}
-
-			monitor.setMode(mode)
-
-			if cfg != nil {
-				monitor.setPosition(cfg.X, 0)
-				logger.Debug("setPosition from config ", cfg.X)
-			} else {
-				if xOffset > math.MaxInt16 {
-					xOffset = math.MaxInt16
-				}
-				logger.Debug("setPosition ---- sxOffset", xOffset)
-				monitor.setPosition(int16(xOffset), 0)
-				xOffset += int(monitor.Width)
-			}
-			monitor.setRotation(randr.RotationRotate0)
-			monitor.setReflect(0)
+	configs := screenCfg.GetMonitorConfigs(DisplayModeExtend, "")
+	if !hasAllMonitorConfigs(configs, monitors) {
+		primaryMonitor := getDefaultPrimaryMonitor(monitors)
+		if primaryMonitor == nil {
+			return errors.New("not found primary monitor")
+		}
+		configs = core.BuildExtendConfigs(Monitors(monitors).toCoreMonitors(), primaryMonitor.toCoreMonitor())
+	} else {
+		configs = configs.Clone()
+	}
 
-		} else {
-			monitor.enable(false)
+	if primary != "" {
+		if monitor := Monitors(monitors).GetByName(primary); monitor != nil {
+			configs.SetPrimary(monitor.uuid)
 		}
 	}
+	return m.applyConfigs(configs)
+}
 
-	if monitor0 == nil {
-		monitor0 = getMinIDMonitor(m.getConnectedMonitors())
+// hasAllMonitorConfigs configs 中是否有所有显示器的配置
+func hasAllMonitorConfigs(configs core.SysMonitorConfigs, monitors []*Monitor) bool {
+	if len(configs) == 0 {
+		return false
+	}
+	for _, monitor := range monitors {
+		if configs.GetByUuid(monitor.uuid) == nil {
+			return false
+		}
 	}
+	return true
+}
 
-	err = m.apply()
-	if err != nil {
-		return
+func (m *Manager) getScreenConfig() *core.SysScreenConfig {
+	id := m.getMonitorsId()
+	return m.getScreenConfigById(id)
+}
+
+func (m *Manager) getScreenConfigById(id string) *core.SysScreenConfig {
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
+
+	screens := m.sysConfig.Config.Screens
+	if screens == nil {
+		screens = make(map[string]*core.SysScreenConfig)
+		m.sysConfig.Config.Screens = screens
 	}
+	screenCfg := screens[id]
+	if screenCfg == nil {
+		screenCfg = &core.SysScreenConfig{}
+		screens[id] = screenCfg
+	}
+	return screenCfg
+}
 
-	if primary != "" {
-		for _, m := range monitors {
-			if m.Enabled && m.Name == primary {
-				monitor0 = m
-			}
+// getScreenMonitorConfigs 获取 mode 模式下的显示器配置，自定义模式的配置保存为名称是 customName 的布局。
+func getScreenMonitorConfigs(screenCfg *core.SysScreenConfig, mode uint8, customName string) core.SysMonitorConfigs {
+	if mode == DisplayModeCustom {
+		profile := screenCfg.GetProfile(customName)
+		if profile == nil {
+			return nil
 		}
+		return profile.Monitors
 	}
+	return screenCfg.GetMonitorConfigs(mode, screenCfg.OnlyOneUuid)
+}
 
-	if monitor0 != nil {
-		err = m.setMonitorPrimary(monitor0)
-		if err != nil {
+func (m *Manager) setScreenMonitorConfigs(screenCfg *core.SysScreenConfig, mode uint8, customName string,
+	configs core.SysMonitorConfigs) {
+	switch mode {
+	case DisplayModeCustom:
+		if customName == "" {
 			return
 		}
+		screenCfg.SetProfile(customName, &core.SysProfileConfig{
+			DisplayMode: configs.GetRealDisplayMode(),
+			Monitors:    configs,
+		})
 
-		err = m.setPrimarySettings(monitor0.Name)
-		if err != nil {
-			return
+	case DisplayModeOnlyOne:
+		for _, cfg := range configs {
+			if cfg.Enabled {
+				screenCfg.SetMonitorConfigsOnlyOne(cfg.UUID, configs)
+				screenCfg.OnlyOneUuid = cfg.UUID
+				break
+			}
 		}
-	}
 
-	return
-}
-
-func (m *Manager) getScreenConfig() *ScreenConfig {
-	id := m.getMonitorsId()
-	screenCfg := m.config[id]
-	if screenCfg == nil {
-		screenCfg = &ScreenConfig{}
-		m.config[id] = screenCfg
+	default:
+		screenCfg.SetMonitorConfigs(mode, "", configs)
 	}
-	return screenCfg
 }
 
 func (m *Manager) switchModeOnlyOne(name string) (err error) {
 	logger.Debug("switch mode only one", name)
 
 	screenCfg := m.getScreenConfig()
-	configs := screenCfg.getMonitorConfigs(DisplayModeOnlyOne, "")
 
 	var monitor0 *Monitor
 	var needSaveCfg bool
@@ -1209,13 +1198,7 @@ func (m *Manager) switchModeOnlyOne(name string) (err error) {
 			return
 		}
 	} else {
-		var enableUuid string
-		for _, cfg := range configs {
-			if cfg.Enabled {
-				enableUuid = cfg.UUID
-				break
-			}
-		}
+		enableUuid := screenCfg.OnlyOneUuid
 		if enableUuid != "" {
 			for _, monitor := range m.monitorMap {
 				if monitor.uuid == enableUuid {
@@ -1227,7 +1210,7 @@ func (m *Manager) switchModeOnlyOne(name string) (err error) {
 
 		if monitor0 == nil {
 			needSaveCfg = true
-			monitor0 = getMinIDMonitor(m.getConnectedMonitors())
+			monitor0 = getDefaultPrimaryMonitor(m.getConnectedMonitors())
 		}
 
 	}
@@ -1236,44 +1219,24 @@ func (m *Manager) switchModeOnlyOne(name string) (err error) {
 		return
 	}
 
-	for _, monitor := range m.monitorMap {
-		if monitor.uuid == monitor0.uuid {
-			monitor.enable(true)
-			cfg := getMonitorConfigByUuid(configs, monitor.uuid)
-			var mode ModeInfo
-			var rotation uint16 = randr.RotationRotate0
-			var reflect uint16
-			if cfg != nil {
-				mode = monitor.selectMode(cfg.Width, cfg.Height, cfg.RefreshRate)
-				rotation = cfg.Rotation
-				reflect = cfg.Reflect
-			} else {
-				mode = monitor.BestMode
-			}
-
-			monitor.setMode(mode)
-			monitor.setPosition(0, 0)
-			monitor.setRotation(rotation)
-			monitor.setReflect(reflect)
-
-		} else {
-			monitor.enable(false)
-		}
-	}
-
-	err = m.apply()
-	if err != nil {
-		return
+	// 只需要 monitor0 的配置，其余的显示器在 applyConfigs 中被禁用
+	var configs core.SysMonitorConfigs
+	if saved := screenCfg.GetMonitorConfigs(DisplayModeOnlyOne, monitor0.uuid).GetByUuid(monitor0.uuid); saved != nil {
+		cfg := *saved
+		cfg.Enabled = true
+		cfg.Primary = true
+		cfg.X, cfg.Y = 0, 0
+		configs = core.SysMonitorConfigs{&cfg}
+	} else {
+		configs = core.BuildOnlyOneConfigs(Monitors(m.getConnectedMonitors()).toCoreMonitors(), monitor0.uuid)
 	}
-
-	// set primary output
-	err = m.setMonitorPrimary(monitor0)
+	err = m.applyConfigs(configs)
 	if err != nil {
 		return
 	}
 
 	if needSaveCfg {
-		screenCfg.setMonitorConfigs(DisplayModeOnlyOne, "",
+		m.setScreenMonitorConfigs(screenCfg, DisplayModeOnlyOne, "",
 			toMonitorConfigs(m.getConnectedMonitors(), monitor0.Name))
 
 		err = m.saveConfig()
@@ -1293,7 +1256,7 @@ func (m *Manager) switchModeCustom(name string) (err error) {
 	}
 
 	screenCfg := m.getScreenConfig()
-	configs := screenCfg.getMonitorConfigs(DisplayModeCustom, name)
+	configs := getScreenMonitorConfigs(screenCfg, DisplayModeCustom, name)
 	if len(configs) > 0 {
 		// switch monitor should reset displaymode for centercontrl
 		err = m.applyConfigs(configs)
@@ -1344,7 +1307,7 @@ func (m *Manager) switchModeCustom(name string) (err error) {
 		m.SetCustomDisplayMode(DisplayModeMirror)
 	}
 
-	screenCfg.setMonitorConfigs(DisplayModeCustom, name,
+	m.setScreenMonitorConfigs(screenCfg, DisplayModeCustom, name,
 		toMonitorConfigs(m.getConnectedMonitors(), m.Primary))
 
 	err = m.saveConfig()
@@ -1407,17 +1370,13 @@ func (m *Manager) save() (err error) {
 		return
 	}
 
-	screenCfg := m.config[id]
-	if screenCfg == nil {
-		screenCfg = &ScreenConfig{}
-		m.config[id] = screenCfg
-	}
+	screenCfg := m.getScreenConfigById(id)
 	monitors := m.getConnectedMonitors()
 
 	if len(monitors) == 1 {
-		screenCfg.Single = monitors[0].toConfig()
+		screenCfg.SetSingleMonitorConfigs(core.SysMonitorConfigs{monitors[0].toConfig()})
 	} else {
-		screenCfg.setMonitorConfigs(m.DisplayMode, m.CurrentCustomId,
+		m.setScreenMonitorConfigs(screenCfg, m.DisplayMode, m.CurrentCustomId,
 			toMonitorConfigs(monitors, m.Primary))
 	}
 
@@ -1458,11 +1417,11 @@ func (m *Manager) setCurrentCustomId(name string) {
 	m.settings.SetString(gsKeyCustomMode, name)
 }
 
-func (m *Manager) applyConfigs(configs []*MonitorConfig) error {
+func (m *Manager) applyConfigs(configs core.SysMonitorConfigs) error {
 	logger.Debug("applyConfigs", spew.Sdump(configs))
 	var primaryMonitor *Monitor
 	for _, monitor := range m.monitorMap {
-		monitorCfg := getMonitorConfigByUuid(configs, monitor.uuid)
+		monitorCfg := configs.GetByUuid(monitor.uuid)
 		if monitorCfg == nil {
 			monitor.enable(false)
 		} else {
@@ -1481,6 +1440,9 @@ func (m *Manager) applyConfigs(configs []*MonitorConfig) error {
 			}
 			mode := monitor.selectMode(width, height, monitorCfg.RefreshRate)
 			monitor.setMode(mode)
+			if monitorCfg.Scale > 0 {
+				monitor.setScaleFactor(monitorCfg.Scale)
+			}
 		}
 	}
 	err := m.apply()
@@ -1488,7 +1450,7 @@ func (m *Manager) applyConfigs(configs []*MonitorConfig) error {
 		return err
 	}
 	if primaryMonitor == nil {
-		primaryMonitor = getMinIDMonitor(m.getConnectedMonitors())
+		primaryMonitor = getDefaultPrimaryMonitor(m.getConnectedMonitors())
 	}
 	err = m.setMonitorPrimary(primaryMonitor)
 	if err != nil {
@@ -1505,18 +1467,14 @@ func (m *Manager) applyConfigs(configs []*MonitorConfig) error {
 
 func (m *Manager) getCustomIdList() []string {
 	id := m.getMonitorsId()
+	return m.findScreenConfig(id).GetProfileNames()
+}
 
-	screenCfg := m.config[id]
-	if screenCfg == nil {
-		return nil
-	}
-
-	result := make([]string, len(screenCfg.Custom))
-	for idx, custom := range screenCfg.Custom {
-		result[idx] = custom.Name
-	}
-	sort.Strings(result)
-	return result
+// findScreenConfig 查找 id 对应的屏幕配置，找不到时返回 nil
+func (m *Manager) findScreenConfig(id string) *core.SysScreenConfig {
+	m.sysConfig.Mu.Lock()
+	defer m.sysConfig.Mu.Unlock()
+	return m.sysConfig.Config.Screens[id]
 }
 
 func (m *Manager) getMonitorsId() string {
@@ -1560,35 +1518,20 @@ func (m *Manager) modifyConfigName(name, newName string) (err error) {
 		return
 	}
 
-	screenCfg := m.config[id]
+	screenCfg := m.findScreenConfig(id)
 	if screenCfg == nil {
 		err = errors.New("not found screen config")
 		return
 	}
 
-	var customConfig *CustomModeConfig
-	for _, custom := range screenCfg.Custom {
-		if custom.Name == name {
-			customConfig = custom
-			break
-		}
-	}
-	if customConfig == nil {
-		err = fmt.Errorf("not found custom mode config %q", name)
+	err = screenCfg.RenameProfile(name, newName)
+	if err != nil {
 		return
 	}
 	if name == newName {
 		return nil
 	}
 
-	for _, custom := range screenCfg.Custom {
-		if custom.Name == newName {
-			err = fmt.Errorf("same name config %q already exists", newName)
-			return
-		}
-	}
-
-	customConfig.Name = newName
 	m.setPropCustomIdList(m.getCustomIdList())
 	if name == m.CurrentCustomId {
 		m.setCurrentCustomId(newName)
@@ -1620,30 +1563,23 @@ func (m *Manager) deleteCustomMode(name string) (err error) {
 		return
 	}
 
-	screenCfg := m.config[id]
+	screenCfg := m.findScreenConfig(id)
 	if screenCfg == nil {
 		err = errors.New("not found screen config")
 		return
 	}
 
-	var customConfigs []*CustomModeConfig
-	foundName := false
-	for _, custom := range screenCfg.Custom {
-		if custom.Name == name {
-			foundName = true
-		} else {
-			customConfigs = append(customConfigs, custom)
+	err = screenCfg.DeleteProfile(name)
+	if err != nil {
+		var notFoundErr core.ProfileNotFoundError
+		if errors.As(err, &notFoundErr) {
+			logger.Warning("not found custom mode config:", name)
+			// not found
+			return nil
 		}
+		return
 	}
 
-	if !foundName {
-		logger.Warning("not found custom mode config:", name)
-		// not found
-		return nil
-	}
-
-	screenCfg.Custom = customConfigs
-
 	if m.CurrentCustomId == name {
 		m.setCurrentCustomId("")
 	}
@@ -1663,40 +1599,39 @@ func (m *Manager) isCustomModeBeingUsed(name string) bool {
 }
 
 func (m *Manager) initTouchMap() {
+	m.touchscreenMap = make(map[string]core.TouchscreenMapValue)
 	value := m.settings.GetString(gsKeyMapOutput)
-	if len(value) == 0 {
-		m.TouchMap = make(map[string]string)
-		m.setPropTouchMap(m.TouchMap)
-		return
-	}
-
-	err := jsonUnmarshal(value, &m.TouchMap)
+	touchscreenMap, err := core.ParseTouchscreenMap(value)
 	if err != nil {
 		logger.Warningf("[initTouchMap] unmarshal (%s) failed: %v",
 			value, err)
-		return
+	} else {
+		m.touchscreenMap = touchscreenMap
 	}
 
+	m.syncTouchMap()
 	for touch, output := range m.TouchMap {
-		err := m.doSetTouchMap(touch, output)
+		err := m.doSetTouchMap(output, touch)
 		if err != nil {
 			logger.Warning("failed to set touchMap", err)
 		}
 	}
+}
+
+// syncTouchMap 根据映射配置更新属性 TouchMap，只包含已连接的显示器
+func (m *Manager) syncTouchMap() {
+	touchscreens := make([]string, 0, len(m.touchscreenMap))
+	for touch := range m.touchscreenMap {
+		touchscreens = append(touchscreens, touch)
+	}
+	m.TouchMap = core.GetTouchMap(m.touchscreenMap, touchscreens,
+		Monitors(m.getConnectedMonitors()).toCoreMonitors())
 	m.setPropTouchMap(m.TouchMap)
 }
 
 func (m *Manager) doSetTouchMap(output, touch string) error {
 	// TODO
-	monitors := m.getConnectedMonitors()
-	found := false
-	for _, monitor := range monitors {
-		if monitor.Name == output {
-			found = true
-			break
-		}
-	}
-	if !found {
+	if Monitors(m.getConnectedMonitors()).GetByName(output) == nil {
 		return fmt.Errorf("Invalid output name: %s", output)
 	}
 
@@ -1705,7 +1640,11 @@ func (m *Manager) doSetTouchMap(output, touch string) error {
 }
 
 func (m *Manager) associateTouch(outputName, touch string) error {
-	if m.TouchMap[touch] == outputName {
+	monitor := Monitors(m.getConnectedMonitors()).GetByName(outputName)
+	if monitor == nil {
+		return fmt.Errorf("Invalid output name: %s", outputName)
+	}
+	if v, ok := m.touchscreenMap[touch]; ok && v.MonitorUuid == monitor.uuid && v.OutputName == outputName {
 		return nil
 	}
 
@@ -1715,9 +1654,12 @@ func (m *Manager) associateTouch(outputName, touch string) error {
 		return err
 	}
 
-	m.TouchMap[touch] = outputName
-	m.setPropTouchMap(m.TouchMap)
-	m.settings.SetString(gsKeyMapOutput, jsonMarshal(m.TouchMap))
+	m.touchscreenMap[touch] = core.TouchscreenMapValue{
+		OutputName:  outputName,
+		MonitorUuid: monitor.uuid,
+	}
+	m.settings.SetString(gsKeyMapOutput, jsonMarshal(m.touchscreenMap))
+	m.syncTouchMap()
 	return nil
 }
 
@@ -1736,7 +1678,7 @@ func (m *Manager) saveConfig() error {
 		return err
 	}
 
-	err = m.config.save(configFile)
+	err = saveSysConfig(configFile, &m.sysConfig)
 	if err != nil {
 		return err
 	}
@@ -1867,44 +1809,18 @@ func (m *Manager) AdjustPositonAfterSetMode() Monitors {
 
 func (m *Manager) initPrimary() {
 	logger.Info("initPrimary-get mode", m.DisplayMode)
-	var find bool = false
-	var defaultName string = ""
-	var builtInName string = ""
-	var vgaName string = ""
-	var hdmiName string = ""
 	m.Primary = m.primarysettings.GetString("primary-monitor-name")
 	logger.Debug("primary==>", m.Primary)
 	monitors := m.getConnectedMonitors()
-	for _, monitor := range monitors {
-		if monitor.Name == m.Primary {
-			find = true
-			logger.Debug("primary==>same", m.Primary)
-			break
-		}
-		name := strings.ToLower(monitor.Name)
-		if strings.HasPrefix(name, "hdmi") {
-			hdmiName = monitor.Name
-		} else if strings.HasPrefix(name, "vga") {
-			vgaName = monitor.Name
-		} else if strings.HasPrefix(name, "edp") {
-			builtInName = monitor.Name
-		} else {
-			defaultName = monitor.Name
-		}
+	if Monitors(monitors).GetByName(m.Primary) != nil {
+		return
 	}
-	if find == false {
-		if builtInName != "" {
-			m.Primary = defaultName
-		} else if hdmiName != "" {
-			m.Primary = hdmiName
-		} else if vgaName != "" {
-			m.Primary = vgaName
-		} else {
-			m.Primary = defaultName
-		}
-		logger.Debug("PrimaryName==>", m.Primary)
+	if monitor := getDefaultPrimaryMonitor(monitors); monitor != nil {
+		m.Primary = monitor.Name
+	} else {
+		m.Primary = ""
 	}
-	return
+	logger.Debug("PrimaryName==>", m.Primary)
 }
 
 func (m *Manager) getMonitorsPosition() uint8 {
@@ -6,13 +6,12 @@ package display
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"os"
 
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/linuxdeepin/go-lib/dbusutil"
-	"github.com/linuxdeepin/go-lib/strv"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 func (m *Manager) GetInterfaceName() string {
@@ -96,7 +95,7 @@ func (m *Manager) ListOutputsCommonModes() ([]ModeInfo, *dbus.Error) {
 	commonSizes := getMonitorsCommonSizes(monitors)
 	result := make([]ModeInfo, len(commonSizes))
 	for i, size := range commonSizes {
-		result[i], _ = getFirstModeBySize(monitors[0].Modes, size.width, size.height)
+		result[i], _ = getFirstModeBySize(monitors[0].Modes, size.Width, size.Height)
 	}
 	return result, nil
 }
@@ -157,13 +156,7 @@ func (m *Manager) SetAndSaveBrightness(outputName string, value float64) *dbus.E
 	}
 	if v <= 0.3 && value <= 0.3 {
 		for i := 1; i <= int(times); i++ {
-			br = v + step*float64(i)
-			if br > 1.0 {
-				br = 1.0
-			}
-			if br < 0.1 {
-				br = 0.1
-			}
+			br = core.ClampBrightness(v+step*float64(i), 0.1)
 			logger.Info("[changeBrightness] will set to:", outputName, br)
 			err = m.doSetBrightness(br, outputName)
 			if err == nil {
@@ -218,32 +211,11 @@ func (m *Manager) CanSwitchMode() (bool, *dbus.Error) {
 
 func (m *Manager) GetRealDisplayMode() (uint8, *dbus.Error) {
 	monitors := m.getConnectedMonitors()
-
-	mode := DisplayModeUnknow
-	var pairs strv.Strv
-	for _, m := range monitors {
-		if !m.Enabled {
-			continue
-		}
-
-		pair := fmt.Sprintf("%d,%d", m.X, m.Y)
-
-		// 左上角座标相同，是复制
-		if pairs.Contains(pair) {
-			mode = DisplayModeMirror
-		}
-
-		pairs = append(pairs, pair)
-	}
-
-	if mode == DisplayModeUnknow && len(pairs) != 0 {
-		if len(pairs) == 1 {
-			mode = DisplayModeOnlyOne
-		} else {
-			mode = DisplayModeExtend
-		}
+	mode := toMonitorConfigs(monitors, "").GetRealDisplayMode()
+	if mode == core.DisplayModeUnknown {
+		// DisplayModeUnknow 和 core 中的值不同
+		mode = DisplayModeUnknow
 	}
-
 	return mode, nil
 }
 
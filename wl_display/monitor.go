@@ -15,6 +15,7 @@ import (
 
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 const (
@@ -56,6 +57,8 @@ type Monitor struct {
 	Rotation    uint16
 	Reflect     uint16
 	RefreshRate float64
+	// 显示器的缩放比例，由 KWin 按照这个比例缩放
+	Scale float64
 
 	// dbusutil-gen: equal=nil
 	CurrentMode ModeInfo
@@ -81,6 +84,7 @@ type MonitorBackup struct {
 	X, Y     int16
 	Reflect  uint16
 	Rotation uint16
+	Scale    float64
 }
 
 func (m *Monitor) markChanged() {
@@ -93,6 +97,7 @@ func (m *Monitor) markChanged() {
 			Y:        m.Y,
 			Reflect:  m.Reflect,
 			Rotation: m.Rotation,
+			Scale:    m.Scale,
 		}
 	}
 }
@@ -350,6 +355,32 @@ func (m *Monitor) setRotation(value uint16) {
 	m.PropsMu.Unlock()
 }
 
+func (m *Monitor) SetScale(value float64) *dbus.Error {
+	if !isValidScaleFactor(value) {
+		return dbusutil.ToError(fmt.Errorf("invalid scale factor %v", value))
+	}
+	// 和 XSettings 使用的缩放比例保持一致
+	value = toListedScaleFactor(value)
+	m.PropsMu.Lock()
+	defer m.PropsMu.Unlock()
+	if m.Scale == value {
+		return nil
+	}
+	m.markChanged()
+	m.setPropScale(value)
+	return nil
+}
+
+func (m *Monitor) setScaleFactor(value float64) {
+	if !isValidScaleFactor(value) {
+		return
+	}
+	value = toListedScaleFactor(value)
+	m.PropsMu.Lock()
+	m.setPropScale(value)
+	m.PropsMu.Unlock()
+}
+
 func (m *Monitor) resetChanges() {
 	if m.backup == nil {
 		return
@@ -367,6 +398,9 @@ func (m *Monitor) resetChanges() {
 	m.setPropWidth(b.Mode.Width)
 	m.setPropHeight(b.Mode.Height)
 	m.setPropRefreshRate(b.Mode.Rate)
+	if b.Scale > 0 {
+		m.setPropScale(b.Scale)
+	}
 
 	m.backup = nil
 }
@@ -413,9 +447,32 @@ func (m *Monitor) resetChanges() {
 //	return nil
 //}
 
-func toMonitorConfigs(monitors []*Monitor, primary string) []*MonitorConfig {
+func (m *Monitor) toCoreMonitor() core.Monitor {
+	modes := make([]core.Mode, len(m.Modes))
+	for i, mode := range m.Modes {
+		modes[i] = mode.toCoreMode()
+	}
+	return core.Monitor{
+		ID:       m.ID,
+		UUID:     m.uuid,
+		Name:     m.Name,
+		Builtin:  isBuiltinOutput(m.Name),
+		BestMode: m.BestMode.toCoreMode(),
+		Modes:    modes,
+	}
+}
+
+func (monitors Monitors) toCoreMonitors() []core.Monitor {
+	result := make([]core.Monitor, len(monitors))
+	for i, monitor := range monitors {
+		result[i] = monitor.toCoreMonitor()
+	}
+	return result
+}
+
+func toMonitorConfigs(monitors []*Monitor, primary string) core.SysMonitorConfigs {
 	found := false
-	result := make([]*MonitorConfig, len(monitors))
+	result := make(core.SysMonitorConfigs, len(monitors))
 	for i, m := range monitors {
 		cfg := m.toConfig()
 		if !found && m.Name == primary {
@@ -427,8 +484,8 @@ func toMonitorConfigs(monitors []*Monitor, primary string) []*MonitorConfig {
 	return result
 }
 
-func (m *Monitor) toConfig() *MonitorConfig {
-	return &MonitorConfig{
+func (m *Monitor) toConfig() *core.SysMonitorConfig {
+	return &core.SysMonitorConfig{
 		UUID:        m.uuid,
 		Name:        m.Name,
 		Enabled:     m.Enabled,
@@ -439,6 +496,18 @@ func (m *Monitor) toConfig() *MonitorConfig {
 		Rotation:    m.Rotation,
 		Reflect:     m.Reflect,
 		RefreshRate: m.RefreshRate,
+		Scale:       m.Scale,
+	}
+}
+
+func updateMonitorConfigsName(configs core.SysMonitorConfigs, monitorMap map[uint32]*Monitor) {
+	for _, mc := range configs {
+		for _, m := range monitorMap {
+			if mc.UUID == m.uuid {
+				mc.Name = m.Name
+				break
+			}
+		}
 	}
 }
 
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2023 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"reflect"
+
+	xsettings "github.com/linuxdeepin/go-dbus-factory/session/org.deepin.dde.xsettings1"
+)
+
+const (
+	scaleFactorMin = 1.0
+	scaleFactorMax = 3.0
+	// xsettings 中表示所有屏幕的 key
+	scaleFactorsKeyAll = "ALL"
+)
+
+func isValidScaleFactor(value float64) bool {
+	return value >= scaleFactorMin && value <= scaleFactorMax
+}
+
+// getScreenScaleFactors 根据各个启用的显示器的缩放比例，生成 XSettings SetScreenScaleFactors 的参数。
+// 缩放比例都相同时只设置 ALL，否则按显示器名称设置，并用主屏的缩放比例作为 ALL，
+// 使 Xwayland 下的 X 应用使用主屏的 DPI。
+func getScreenScaleFactors(monitors Monitors, primary string) map[string]float64 {
+	factors := make(map[string]float64)
+	allSame := true
+	var first float64
+	for _, monitor := range monitors {
+		if !monitor.Enabled || monitor.Scale <= 0 {
+			continue
+		}
+		if first == 0 {
+			first = monitor.Scale
+		} else if first != monitor.Scale {
+			allSame = false
+		}
+		factors[monitor.Name] = monitor.Scale
+	}
+	if len(factors) == 0 {
+		return nil
+	}
+	if allSame {
+		return map[string]float64{scaleFactorsKeyAll: first}
+	}
+
+	all, ok := factors[primary]
+	if !ok {
+		all = first
+	}
+	factors[scaleFactorsKeyAll] = all
+	return factors
+}
+
+// syncScaleFactorsToXSettings 将显示器的缩放比例同步给 XSettings，使 Xwayland 下的 X 应用有相符的 DPI。
+func (m *Manager) syncScaleFactorsToXSettings() {
+	m.PropsMu.RLock()
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	factors := getScreenScaleFactors(m.getConnectedMonitors(), primary)
+	if len(factors) == 0 {
+		return
+	}
+
+	go func() {
+		xs := xsettings.NewXSettings(m.service.Conn())
+		current, err := xs.GetScreenScaleFactors(0)
+		if err != nil {
+			logger.Warning("failed to get screen scale factors:", err)
+			return
+		}
+		if reflect.DeepEqual(current, factors) {
+			return
+		}
+		logger.Debug("sync scale factors to xsettings:", factors)
+		err = xs.SetScreenScaleFactors(0, factors)
+		if err != nil {
+			logger.Warning("failed to set screen scale factors:", err)
+		}
+	}()
+}
@@ -11,12 +11,12 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/linuxdeepin/go-lib/strv"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/core"
 )
 
 var regMode = regexp.MustCompile(`^(\d+)x(\d+)(\D+)$`)
@@ -87,87 +87,19 @@ func findFirstMode(modes []ModeInfo, fn func(mode ModeInfo) bool) *ModeInfo {
 	return nil
 }
 
-func getMonitorsCommonSizes(monitors []*Monitor) []Size {
-	count := make(map[Size]int)
-	for _, monitor := range monitors {
-		smm := getSizeModeMap(monitor.Modes)
-		for size := range smm {
-			count[size]++
-		}
-	}
-	var commonSizes []Size
-	for size, value := range count {
-		if value == len(monitors) {
-			commonSizes = append(commonSizes, size)
-		}
-	}
-	return commonSizes
-}
-
-func getMaxAreaSize(sizes []Size) Size {
-	if len(sizes) == 0 {
-		return Size{}
-	}
-	maxS := sizes[0]
-	for _, s := range sizes[1:] {
-		if (int(maxS.width) * int(maxS.height)) < (int(s.width) * int(s.height)) {
-			maxS = s
-		}
-	}
-	return maxS
+func getMonitorsCommonSizes(monitors []*Monitor) []core.Size {
+	return core.GetCommonSizes(Monitors(monitors).toCoreMonitors())
 }
 
-type Size struct {
-	width  uint16
-	height uint16
-}
-
-func getSizeModeMap(modes []ModeInfo) map[Size][]uint32 {
-	result := make(map[Size][]uint32)
-	for _, modeInfo := range modes {
-		result[Size{modeInfo.Width, modeInfo.Height}] = append(
-			result[Size{modeInfo.Width, modeInfo.Height}], modeInfo.Id)
-	}
-	return result
-}
-
-func sortMonitorsByID(monitors []*Monitor) {
-	sort.Slice(monitors, func(i, j int) bool {
-		iname := isBuiltinOutput(monitors[i].Name)
-		jname := isBuiltinOutput(monitors[j].Name)
-		if iname && jname {
-			return monitors[i].ID < monitors[j].ID
-		} else if iname {
-			return true
-		} else if jname {
-			return false
-		}
-		return monitors[i].ID < monitors[j].ID
-	})
-}
-
-func getMinIDMonitor(monitors []*Monitor) *Monitor {
-	if len(monitors) == 0 {
+// getDefaultPrimaryMonitor 获取默认的主屏，规则见 core.GetPrimaryMonitor
+func getDefaultPrimaryMonitor(monitors []*Monitor) *Monitor {
+	primary, ok := core.GetPrimaryMonitor(Monitors(monitors).toCoreMonitors(), nil)
+	if !ok {
 		return nil
 	}
-
-	if m := guassPrimaryMonitor(monitors); m != nil {
-		return m
-	}
-
-	minMonitor := monitors[0]
-	for _, monitor := range monitors[1:] {
-		if minMonitor.ID > monitor.ID {
-			minMonitor = monitor
-		}
-	}
-	return minMonitor
-}
-
-func guassPrimaryMonitor(monitors []*Monitor) *Monitor {
-	for _, m := range monitors {
-		if isBuiltinOutput(m.Name) {
-			return m
+	for _, monitor := range monitors {
+		if monitor.ID == primary.ID {
+			return monitor
 		}
 	}
 	return nil
@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package xsettings
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/linuxdeepin/dde-api/userenv"
+	"github.com/linuxdeepin/go-lib/keyfile"
+)
+
+// 单个应用的缩放比例，不影响会话默认的缩放比例。应用用 desktop id（如 foo.desktop）
+// 或者可执行文件（名称或者绝对路径）标识。
+// 只有 Qt 应用会读取 qt-theme.ini 中的设置，其他应用（GTK2、Java 等）需要启动器根据
+// DEEPIN_APP_SCALE_FACTORS 在启动应用时设置 GDK_SCALE 等环境变量。
+// Xft.dpi 不能按应用设置，GTK2 也只读取 XSETTINGS 中的 Xft/DPI，所以不写入 Xresources。
+
+const (
+	EnvDeepinAppScaleFactors = "DEEPIN_APP_SCALE_FACTORS"
+	gsKeyAppScaleFactors     = "app-scale-factors"
+
+	qtThemeSectionAppScaleFactors = "AppScaleFactors"
+	desktopExt                    = ".desktop"
+
+	// 和全局缩放比例可选的范围一致
+	appScaleFactorMin = 1.0
+	appScaleFactorMax = 3.0
+)
+
+// normalizeAppScaleKey 检查并规范化应用标识，desktop id 只保留文件名。
+func normalizeAppScaleKey(app string) (string, error) {
+	app = strings.TrimSpace(app)
+	if app == "" {
+		return "", errors.New("app is empty")
+	}
+	if strings.ContainsAny(app, ";=\n") {
+		return "", fmt.Errorf("invalid app %q", app)
+	}
+	if strings.HasSuffix(app, desktopExt) {
+		app = filepath.Base(app)
+		if app == desktopExt {
+			return "", fmt.Errorf("invalid app %q", app)
+		}
+	}
+	return app, nil
+}
+
+func joinAppScaleFactors(factors map[string]float64) string {
+	keys := make([]string, 0, len(factors))
+	for key := range factors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for idx, key := range keys {
+		pairs[idx] = fmt.Sprintf("%s=%.2f", key, factors[key])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// matchAppScaleFactor 按 desktop id、可执行文件路径、可执行文件名称的顺序查找应用的缩放比例。
+func matchAppScaleFactor(factors map[string]float64, desktopId, exe string) (float64, bool) {
+	if desktopId != "" {
+		desktopId = filepath.Base(desktopId)
+		if !strings.HasSuffix(desktopId, desktopExt) {
+			desktopId += desktopExt
+		}
+		if v, ok := factors[desktopId]; ok {
+			return v, true
+		}
+	}
+	if exe != "" {
+		if v, ok := factors[exe]; ok {
+			return v, true
+		}
+		if v, ok := factors[filepath.Base(exe)]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func (m *XSManager) getAppScaleFactors() map[string]float64 {
+	return parseScreenFactors(m.cfgHelper.GetString(gsKeyAppScaleFactors))
+}
+
+func (m *XSManager) getAppScaleFactor(desktopId, exe string) (float64, bool) {
+	return matchAppScaleFactor(m.getAppScaleFactors(), desktopId, exe)
+}
+
+// checkAppScaleFactor 检查应用的缩放比例，超出范围的值调整到 [appScaleFactorMin, appScaleFactorMax] 内，0 表示删除。
+func checkAppScaleFactor(scale float64) (float64, error) {
+	if math.IsNaN(scale) || math.IsInf(scale, 0) || scale < 0 {
+		return 0, fmt.Errorf("invalid scale factor %v", scale)
+	}
+	if scale == 0 {
+		return 0, nil
+	}
+	return math.Min(math.Max(scale, appScaleFactorMin), appScaleFactorMax), nil
+}
+
+// setAppScaleFactor 设置应用的缩放比例，scale 为 0 表示删除。
+func (m *XSManager) setAppScaleFactor(app string, scale float64) error {
+	key, err := normalizeAppScaleKey(app)
+	if err != nil {
+		return err
+	}
+	scale, err = checkAppScaleFactor(scale)
+	if err != nil {
+		return err
+	}
+
+	m.appScaleMu.Lock()
+	defer m.appScaleMu.Unlock()
+
+	factors := m.getAppScaleFactors()
+	if scale == 0 {
+		if _, ok := factors[key]; !ok {
+			return nil
+		}
+		delete(factors, key)
+	} else {
+		factors[key] = scale
+	}
+	logger.Debug("setAppScaleFactor", key, scale)
+	if !m.cfgHelper.SetString(gsKeyAppScaleFactors, joinAppScaleFactors(factors)) {
+		return fmt.Errorf("failed to save %s", gsKeyAppScaleFactors)
+	}
+	return m.publishAppScaleFactors(factors)
+}
+
+// publishAppScaleFactors 将应用缩放比例写入环境变量和 qt-theme.ini，应用在下次启动时生效。
+func (m *XSManager) publishAppScaleFactors(factors map[string]float64) error {
+	err := setAppScaleFactorsForEnv(factors)
+	if err != nil {
+		logger.Warning("failed to set app scale factors for env:", err)
+	}
+
+	err1 := setAppScaleFactorsForQt(factors)
+	if err1 != nil {
+		logger.Warning("failed to set app scale factors for qt:", err1)
+		err = err1
+	}
+	return err
+}
+
+func setAppScaleFactorsForEnv(factors map[string]float64) error {
+	value := joinAppScaleFactors(factors)
+	if value == "" {
+		return userenv.Delete(EnvDeepinAppScaleFactors)
+	}
+	return userenv.Set(EnvDeepinAppScaleFactors, value)
+}
+
+func setAppScaleFactorsForQt(factors map[string]float64) error {
+	filename := getQtThemeFile()
+	kf := keyfile.NewKeyFile()
+	err := kf.LoadFromFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warning("failed to load qt-theme.ini:", err)
+		} else if len(factors) == 0 {
+			return nil
+		}
+	}
+
+	kf.DeleteSection(qtThemeSectionAppScaleFactors)
+	for key, value := range factors {
+		kf.SetValue(qtThemeSectionAppScaleFactors, key, strconv.FormatFloat(value, 'f', 2, 64))
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	return kf.SaveToFile(filename)
+}
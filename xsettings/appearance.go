@@ -0,0 +1,319 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package xsettings
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+	timedate1 "github.com/linuxdeepin/go-dbus-factory/system/org.freedesktop.timedate1"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/startdde/display/core"
+)
+
+// 浅色和深色外观的切换。两套外观的属性保存在 DConfig 中，格式和 extra-settings 一样，
+// auto 模式下按固定时间或者当前时区的日出日落时间切换，切换时和 Net/ColorScheme 一起写入 xsettings 属性。
+// 外观的属性不保存到 DConfig，manual 模式下恢复 DConfig 中保存的用户设置。
+
+const (
+	gsKeyAppearanceMode          = "appearance-mode"
+	gsKeyAppearanceLightTime     = "appearance-light-time"
+	gsKeyAppearanceDarkTime      = "appearance-dark-time"
+	gsKeyAppearanceLightSettings = "appearance-light-settings"
+	gsKeyAppearanceDarkSettings  = "appearance-dark-settings"
+
+	// 不切换外观
+	appearanceModeManual = "manual"
+	appearanceModeLight  = "light"
+	appearanceModeDark   = "dark"
+	// 按 appearance-light-time 和 appearance-dark-time 切换
+	appearanceModeAuto = "auto"
+
+	xsKeyColorScheme = "Net/ColorScheme"
+
+	// 系统休眠或者修改时间后，定时器可能不准，所以最多间隔这么久重新计算一次
+	appearanceCheckInterval = 10 * time.Minute
+)
+
+// org.freedesktop.appearance color-scheme 的值
+const (
+	colorSchemeDefault uint32 = iota
+	colorSchemePreferDark
+	colorSchemePreferLight
+)
+
+func getColorSchemeName(colorScheme uint32) string {
+	switch colorScheme {
+	case colorSchemePreferDark:
+		return "prefer-dark"
+	case colorSchemePreferLight:
+		return "prefer-light"
+	}
+	return "default"
+}
+
+// appearanceSchedule 浅色和深色外观的切换时间，格式和亮度计划的时间点一样，
+// 可以是 "HH:MM"，也可以相对日出日落时间，例如 "sunrise"，"sunset+30"。
+type appearanceSchedule struct {
+	light string
+	dark  string
+}
+
+func (s appearanceSchedule) validate() error {
+	for _, t := range []string{s.light, s.dark} {
+		err := core.ValidateScheduleTime(t)
+		if err != nil {
+			return err
+		}
+	}
+	if s.light == s.dark {
+		return errors.New("light and dark time are the same")
+	}
+	return nil
+}
+
+// isDark 返回 now 时是否使用深色外观，以及下一次切换的时间，pos 为 nil 时使用默认的日出日落时间
+func (s appearanceSchedule) isDark(now time.Time, pos *core.GeoPosition) (dark bool, next time.Time, err error) {
+	err = s.validate()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	index, _, next, err := core.FindScheduleTime([]string{s.light, s.dark}, now, pos)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	return index == 1, next, nil
+}
+
+type appearanceState struct {
+	mu          sync.Mutex
+	timer       *time.Timer
+	colorScheme uint32
+	// 外观已经按 colorScheme 写入过
+	applied bool
+
+	timezone string
+	// 时区名称到经纬度的映射
+	zonePositions map[string]core.GeoPosition
+}
+
+func (m *XSManager) initAppearance(systemBus *dbus.Conn) {
+	zonePositions, err := core.LoadZonePositions()
+	if err != nil {
+		logger.Warning("failed to load zone positions:", err)
+	}
+
+	td := timedate1.NewTimedate(systemBus)
+	timezone, err := td.Timezone().Get(0)
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	m.appearance.mu.Lock()
+	m.appearance.zonePositions = zonePositions
+	m.appearance.timezone = timezone
+	m.appearance.mu.Unlock()
+
+	sigLoop := dbusutil.NewSignalLoop(systemBus, 10)
+	sigLoop.Start()
+	td.InitSignalExt(sigLoop, true)
+	err = td.Timezone().ConnectChanged(func(hasValue bool, value string) {
+		if !hasValue {
+			return
+		}
+		logger.Info("timezone changed to", value)
+		m.appearance.mu.Lock()
+		m.appearance.timezone = value
+		m.appearance.mu.Unlock()
+		// 经纬度变了，日出日落时间也变了
+		m.updateAppearance(false)
+	})
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	m.updateAppearance(false)
+}
+
+// getTimeZoneNow 返回当前时区的当前时间，调用时需要持有 appearance.mu
+func (m *XSManager) getTimeZoneNow() time.Time {
+	now := time.Now()
+	loc, err := time.LoadLocation(m.appearance.timezone)
+	if err != nil {
+		return now
+	}
+	return now.In(loc)
+}
+
+// getGeoPosition 返回当前时区的经纬度，找不到时返回 nil，调用时需要持有 appearance.mu
+func (m *XSManager) getGeoPosition() *core.GeoPosition {
+	pos, ok := m.appearance.zonePositions[m.appearance.timezone]
+	if !ok {
+		return nil
+	}
+	return &pos
+}
+
+// updateAppearance 根据模式写入外观，auto 模式下设置下一次检查的定时器，force 为 true 时即使明暗没有变化也重新写入
+func (m *XSManager) updateAppearance(force bool) {
+	m.appearance.mu.Lock()
+	defer m.appearance.mu.Unlock()
+
+	if m.appearance.timer != nil {
+		m.appearance.timer.Stop()
+		m.appearance.timer = nil
+	}
+
+	var err error
+	mode := m.cfgHelper.GetString(gsKeyAppearanceMode)
+	switch mode {
+	case appearanceModeManual, "":
+		err = m.applyColorScheme(colorSchemeDefault, force)
+	case appearanceModeLight:
+		err = m.applyColorScheme(colorSchemePreferLight, force)
+	case appearanceModeDark:
+		err = m.applyColorScheme(colorSchemePreferDark, force)
+	case appearanceModeAuto:
+		schedule := appearanceSchedule{
+			light: m.cfgHelper.GetString(gsKeyAppearanceLightTime),
+			dark:  m.cfgHelper.GetString(gsKeyAppearanceDarkTime),
+		}
+		now := m.getTimeZoneNow()
+		dark, next, err1 := schedule.isDark(now, m.getGeoPosition())
+		if err1 != nil {
+			err = err1
+			break
+		}
+		colorScheme := colorSchemePreferLight
+		if dark {
+			colorScheme = colorSchemePreferDark
+		}
+		err = m.applyColorScheme(colorScheme, force)
+
+		delay := next.Sub(now)
+		if delay > appearanceCheckInterval {
+			delay = appearanceCheckInterval
+		}
+		logger.Debugf("next appearance switch at %v, check after %v", next, delay)
+		m.appearance.timer = time.AfterFunc(delay, func() {
+			m.updateAppearance(false)
+		})
+	default:
+		err = fmt.Errorf("invalid appearance mode %q", mode)
+	}
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+// getAppearanceSettings 返回浅色或者深色外观的属性，以及对应的 Net/ColorScheme。
+// 没有偏好时返回两套外观中的属性在 DConfig 中保存的值，以及没有保存、需要删除的属性。
+func (m *XSManager) getAppearanceSettings(colorScheme uint32) ([]xsSetting, []string) {
+	var settings []xsSetting
+	var removed []string
+	switch colorScheme {
+	case colorSchemePreferLight:
+		settings = extraSettingsToXSSettings(parseExtraSettings(m.cfgHelper.GetString(gsKeyAppearanceLightSettings)))
+	case colorSchemePreferDark:
+		settings = extraSettingsToXSSettings(parseExtraSettings(m.cfgHelper.GetString(gsKeyAppearanceDarkSettings)))
+	default:
+		settings, removed = m.getSavedAppearanceSettings()
+	}
+	return append(settings, xsSetting{
+		sType: settingTypeString,
+		prop:  xsKeyColorScheme,
+		value: getColorSchemeName(colorScheme),
+	}), removed
+}
+
+// getSavedAppearanceSettings 返回浅色和深色外观中的属性在 DConfig 中保存的值，
+// 既不在 gsInfos 中也没有保存在 extra-settings 中的属性只由外观写入过，放在 removed 中。
+func (m *XSManager) getSavedAppearanceSettings() (settings []xsSetting, removed []string) {
+	props := make(map[string]xsPropValue)
+	for _, key := range []string{gsKeyAppearanceLightSettings, gsKeyAppearanceDarkSettings} {
+		for prop, value := range parseExtraSettings(m.cfgHelper.GetString(key)) {
+			props[prop] = value
+		}
+	}
+
+	extraSettings := m.getExtraSettings()
+	for _, setting := range extraSettingsToXSSettings(props) {
+		if info := gsInfos.getByXSKey(setting.prop); info != nil {
+			value, err := info.getValue(m.cfgHelper)
+			if err != nil {
+				logger.Warning(err)
+				continue
+			}
+			settings = append(settings, xsSetting{
+				sType: info.getKeySType(),
+				prop:  info.xsKey,
+				value: value,
+			})
+		} else if value, ok := extraSettings[setting.prop]; ok {
+			saved, err := value.toXSSetting(setting.prop)
+			if err != nil {
+				logger.Warning(err)
+				continue
+			}
+			settings = append(settings, saved)
+		} else {
+			removed = append(removed, setting.prop)
+		}
+	}
+	return settings, removed
+}
+
+// checkSettingsType 去掉和已有属性类型不一致的属性
+func (m *XSManager) checkSettingsType(settings []xsSetting) []xsSetting {
+	var result []xsSetting
+	for _, setting := range settings {
+		sType := setting.sType
+		if info := gsInfos.getByXSKey(setting.prop); info != nil {
+			sType = info.getKeySType()
+		} else if _, t, err := m.getSettingValue(setting.prop); err == nil {
+			sType = t
+		}
+		if sType != setting.sType {
+			logger.Warningf("%s: %v", setting.prop, errPropTypeNotMatch)
+			continue
+		}
+		result = append(result, setting)
+	}
+	return result
+}
+
+// applyColorScheme 把外观的属性一次写入，调用时需要持有 appearance.mu
+func (m *XSManager) applyColorScheme(colorScheme uint32, force bool) error {
+	if m.appearance.applied && m.appearance.colorScheme == colorScheme && !force {
+		return nil
+	}
+	logger.Info("apply color scheme", getColorSchemeName(colorScheme))
+	// 只写入 xsettings 属性，不覆盖 DConfig 中用户的设置
+	settings, removed := m.getAppearanceSettings(colorScheme)
+	err := m.updateSettings(m.checkSettingsType(settings), removed)
+	if err != nil {
+		return err
+	}
+	// 启动时第一次写入还没有导出到 dbus，不发送信号
+	changed := m.appearance.applied && m.appearance.colorScheme != colorScheme
+	m.appearance.colorScheme = colorScheme
+	m.appearance.applied = true
+	if changed {
+		err = m.service.Emit(m, "ColorSchemeChanged", colorScheme)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	return nil
+}
+
+func (m *XSManager) getColorScheme() uint32 {
+	m.appearance.mu.Lock()
+	defer m.appearance.mu.Unlock()
+	return m.appearance.colorScheme
+}
@@ -12,18 +12,39 @@ import (
 
 func (v *XSManager) GetExportedMethods() dbusutil.ExportedMethods {
 	return dbusutil.ExportedMethods{
+		{
+			Name:    "GetAppScaleFactor",
+			Fn:      v.GetAppScaleFactor,
+			InArgs:  []string{"desktopId", "exe"},
+			OutArgs: []string{"scale", "ok"},
+		},
+		{
+			Name:    "GetAppScaleFactors",
+			Fn:      v.GetAppScaleFactors,
+			OutArgs: []string{"factors"},
+		},
 		{
 			Name:    "GetColor",
 			Fn:      v.GetColor,
 			InArgs:  []string{"prop"},
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "GetColorScheme",
+			Fn:      v.GetColorScheme,
+			OutArgs: []string{"colorScheme"},
+		},
 		{
 			Name:    "GetInteger",
 			Fn:      v.GetInteger,
 			InArgs:  []string{"prop"},
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "GetManagedScreens",
+			Fn:      v.GetManagedScreens,
+			OutArgs: []string{"screens"},
+		},
 		{
 			Name:    "GetScaleFactor",
 			Fn:      v.GetScaleFactor,
@@ -45,6 +66,15 @@ func (v *XSManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.ListProps,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name: "Reclaim",
+			Fn:   v.Reclaim,
+		},
+		{
+			Name:   "SetAppScaleFactor",
+			Fn:     v.SetAppScaleFactor,
+			InArgs: []string{"app", "scale"},
+		},
 		{
 			Name:   "SetColor",
 			Fn:     v.SetColor,
@@ -55,6 +85,11 @@ func (v *XSManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetInteger,
 			InArgs: []string{"prop", "v"},
 		},
+		{
+			Name:   "SetMany",
+			Fn:     v.SetMany,
+			InArgs: []string{"values"},
+		},
 		{
 			Name:   "SetScaleFactor",
 			Fn:     v.SetScaleFactor,
@@ -70,5 +105,10 @@ func (v *XSManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetString,
 			InArgs: []string{"prop", "v"},
 		},
+		{
+			Name:   "Unset",
+			Fn:     v.Unset,
+			InArgs: []string{"prop"},
+		},
 	}
 }
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package xsettings
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// 不在 gsInfos 中的 xsettings 属性保存在 DConfig 的 extra-settings 里，值为 json 对象，
+// 键为属性名，值为 xsPropValue（不带 name），启动时和其他属性一起恢复。
+
+const gsKeyExtraSettings = "extra-settings"
+
+const (
+	settingTypeNameInteger = "integer"
+	settingTypeNameString  = "string"
+	settingTypeNameColor   = "color"
+)
+
+// xsPropValue 带类型的属性值，color 的值为 [red, green, blue, alpha]
+type xsPropValue struct {
+	Name  string          `json:"name,omitempty"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func getSettingTypeName(sType uint8) (string, error) {
+	switch sType {
+	case settingTypeInteger:
+		return settingTypeNameInteger, nil
+	case settingTypeString:
+		return settingTypeNameString, nil
+	case settingTypeColor:
+		return settingTypeNameColor, nil
+	}
+	return "", fmt.Errorf("invalid setting type %d", sType)
+}
+
+// getValueSettingType 根据 xsSetting 中值的类型确定属性的类型
+func getValueSettingType(value interface{}) (uint8, error) {
+	switch value.(type) {
+	case int32:
+		return settingTypeInteger, nil
+	case string:
+		return settingTypeString, nil
+	case [4]uint16:
+		return settingTypeColor, nil
+	}
+	return 0, fmt.Errorf("invalid value type %T", value)
+}
+
+// newXSPropValue value 的类型和 xsSetting 中的一样
+func newXSPropValue(name string, sType uint8, value interface{}) (xsPropValue, error) {
+	typeName, err := getSettingTypeName(sType)
+	if err != nil {
+		return xsPropValue{}, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return xsPropValue{}, err
+	}
+	return xsPropValue{
+		Name:  name,
+		Type:  typeName,
+		Value: data,
+	}, nil
+}
+
+func (v *xsPropValue) toXSSetting(prop string) (xsSetting, error) {
+	var setting = xsSetting{prop: prop}
+	var err error
+	switch v.Type {
+	case settingTypeNameInteger:
+		var value int32
+		err = json.Unmarshal(v.Value, &value)
+		setting.sType, setting.value = settingTypeInteger, value
+	case settingTypeNameString:
+		var value string
+		err = json.Unmarshal(v.Value, &value)
+		setting.sType, setting.value = settingTypeString, value
+	case settingTypeNameColor:
+		var value [4]uint16
+		err = json.Unmarshal(v.Value, &value)
+		setting.sType, setting.value = settingTypeColor, value
+	default:
+		return setting, fmt.Errorf("invalid type %q of %s", v.Type, prop)
+	}
+	if err != nil {
+		return setting, fmt.Errorf("invalid value of %s: %v", prop, err)
+	}
+	return setting, nil
+}
+
+func parseExtraSettings(str string) map[string]xsPropValue {
+	settings := make(map[string]xsPropValue)
+	if str == "" {
+		return settings
+	}
+	err := json.Unmarshal([]byte(str), &settings)
+	if err != nil {
+		logger.Warningf("invalid %s: %v", gsKeyExtraSettings, err)
+		return make(map[string]xsPropValue)
+	}
+	return settings
+}
+
+// extraSettingsToXSSettings 按属性名排序，忽略无效的值
+func extraSettingsToXSSettings(settings map[string]xsPropValue) []xsSetting {
+	props := make([]string, 0, len(settings))
+	for prop := range settings {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	var result []xsSetting
+	for _, prop := range props {
+		value := settings[prop]
+		setting, err := value.toXSSetting(prop)
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		result = append(result, setting)
+	}
+	return result
+}
+
+func (m *XSManager) getExtraSettings() map[string]xsPropValue {
+	return parseExtraSettings(m.cfgHelper.GetString(gsKeyExtraSettings))
+}
+
+// updateExtraSettingProps 返回 extra-settings 中的属性，以及上次调用之后从 extra-settings 中删除的属性
+func (m *XSManager) updateExtraSettingProps() ([]xsSetting, []string) {
+	m.extraSettingsMu.Lock()
+	defer m.extraSettingsMu.Unlock()
+	settings := m.getExtraSettings()
+	var removed []string
+	for _, prop := range m.extraSettingProps {
+		if _, ok := settings[prop]; !ok && gsInfos.getByXSKey(prop) == nil {
+			removed = append(removed, prop)
+		}
+	}
+
+	list := extraSettingsToXSSettings(settings)
+	m.extraSettingProps = m.extraSettingProps[:0]
+	for _, setting := range list {
+		m.extraSettingProps = append(m.extraSettingProps, setting.prop)
+	}
+	return list, removed
+}
+
+func (m *XSManager) saveExtraSettings(settings map[string]xsPropValue) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if !m.cfgHelper.SetString(gsKeyExtraSettings, string(data)) {
+		return fmt.Errorf("failed to save %s", gsKeyExtraSettings)
+	}
+	return nil
+}
+
+// setExtraSetting 保存不在 gsInfos 中的属性
+func (m *XSManager) setExtraSetting(prop string, v interface{}) error {
+	sType, err := getValueSettingType(v)
+	if err != nil {
+		return err
+	}
+	return m.setExtraSettings([]xsSetting{{sType: sType, prop: prop, value: v}})
+}
+
+func (m *XSManager) setExtraSettings(list []xsSetting) error {
+	m.extraSettingsMu.Lock()
+	defer m.extraSettingsMu.Unlock()
+	settings := m.getExtraSettings()
+	for _, s := range list {
+		value, err := newXSPropValue("", s.sType, s.value)
+		if err != nil {
+			return err
+		}
+		settings[s.prop] = value
+	}
+	return m.saveExtraSettings(settings)
+}
+
+// unsetSetting 删除属性，只能删除不在 gsInfos 中的属性
+func (m *XSManager) unsetSetting(prop string) error {
+	if gsInfos.getByXSKey(prop) != nil {
+		return fmt.Errorf("can not unset builtin property %s", prop)
+	}
+
+	m.extraSettingsMu.Lock()
+	defer m.extraSettingsMu.Unlock()
+	settings := m.getExtraSettings()
+	_, saved := settings[prop]
+	removed, err := m.removeSetting(prop)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		if !removed {
+			return errPropNotFound
+		}
+		return nil
+	}
+	delete(settings, prop)
+	return m.saveExtraSettings(settings)
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"reflect"
 	"sync"
+	"time"
 
 	dbus "github.com/godbus/dbus/v5"
 	configManager "github.com/linuxdeepin/go-dbus-factory/org.desktopspec.ConfigManager"
@@ -17,6 +18,7 @@ import (
 	gio "github.com/linuxdeepin/go-gir/gio-2.0"
 	"github.com/linuxdeepin/go-lib/dbusutil"
 	"github.com/linuxdeepin/go-lib/log"
+	"github.com/linuxdeepin/go-lib/strv"
 	x "github.com/linuxdeepin/go-x11-client"
 )
 
@@ -31,6 +33,9 @@ const (
 	xsDBusIFC              = xsDBusService
 	dsettingsAppID         = "org.deepin.startdde"
 	dsettingsXSettingsName = "org.deepin.XSettings"
+
+	// 合并 DConfig 中短时间内的多次修改
+	settingsChangedDelay = 50 * time.Millisecond
 )
 
 type displayScaleFactorsHelper interface {
@@ -59,7 +64,11 @@ var logger = log.NewLogger("xsettings")
 type XSManager struct {
 	service *dbusutil.Service
 	conn    *x.Conn
-	owner   x.Window
+	// 默认 screen 上的 owner 窗口，从它读取设置
+	owner x.Window
+
+	screensMu sync.Mutex
+	screens   []*xsScreen
 
 	cfgHelper configHeler
 	greeter   greeter.Greeter
@@ -76,9 +85,32 @@ type XSManager struct {
 	dsfHelper      displayScaleFactorsHelper
 	dConfigManager configManager.Manager
 
+	// 保护应用缩放比例的读写
+	appScaleMu sync.Mutex
+	// 保护 extra-settings 的读写
+	extraSettingsMu sync.Mutex
+	// 上次写入 xsettings 属性的 extra-settings 中的属性
+	extraSettingProps []string
+
+	// 短时间内 DConfig 中变化的键，合并成一次 setSettings
+	pendingKeysMu    sync.Mutex
+	pendingKeys      []string
+	pendingKeysTimer *time.Timer
+
+	appearance appearanceState
+
 	//nolint
 	signals *struct {
 		SetScaleFactorStarted, SetScaleFactorDone struct{}
+		// 其他程序拿走了 screen 的 XSETTINGS 选择，pid 为新 owner 窗口的 _NET_WM_PID，未知时为 0
+		OwnershipLost struct {
+			screen int32
+			pid    uint32
+		}
+		// 浅色和深色外观切换后发送，值和 org.freedesktop.appearance color-scheme 一样
+		ColorSchemeChanged struct {
+			colorScheme uint32
+		}
 	}
 }
 
@@ -95,18 +127,13 @@ func NewXSManager(conn *x.Conn, recommendedScaleFactor float64, service *dbusuti
 		dsfHelper: helper,
 	}
 
-	var err error
-	m.owner, err = createSettingWindow(m.conn)
+	err := m.initScreens()
 	if err != nil {
+		logger.Error("Init xsettings screens failed:", err)
 		return nil, err
 	}
 	logger.Debug("owner:", m.owner)
 
-	if !isSelectionOwned(settingPropScreen, m.owner, m.conn) {
-		logger.Errorf("Owned '%s' failed", settingPropSettings)
-		return nil, fmt.Errorf("Owned '%s' failed", settingPropSettings)
-	}
-
 	systemBus, err := dbus.SystemBus()
 	if err != nil {
 		return nil, err
@@ -117,6 +144,7 @@ func NewXSManager(conn *x.Conn, recommendedScaleFactor float64, service *dbusuti
 	if m.cfgHelper == nil {
 		return nil, fmt.Errorf("new dconfig failed")
 	}
+	m.listenXEvents()
 
 	m.handleLocalCenterSF()
 	m.adjustScaleFactor(recommendedScaleFactor)
@@ -124,6 +152,7 @@ func NewXSManager(conn *x.Conn, recommendedScaleFactor float64, service *dbusuti
 	if err != nil {
 		logger.Warning("Change xsettings property failed:", err)
 	}
+	m.initAppearance(systemBus)
 
 	return m, nil
 }
@@ -238,6 +267,11 @@ func (m *XSManager) adjustScaleFactor(recommendedScaleFactor float64) {
 }
 
 func (m *XSManager) setSettings(settings []xsSetting) error {
+	return m.updateSettings(settings, nil)
+}
+
+// updateSettings 修改 settings 中的属性并删除 removed 中的属性，只写入一次 xsettings 属性
+func (m *XSManager) updateSettings(settings []xsSetting, removed []string) error {
 	m.settingsLocker.Lock()
 	defer m.settingsLocker.Unlock()
 	datas, err := getSettingPropValue(m.owner, m.conn)
@@ -246,34 +280,30 @@ func (m *XSManager) setSettings(settings []xsSetting) error {
 	}
 
 	xsInfo := unmarshalSettingData(datas)
-	xsInfo.serial++ // auto increment
-	for _, s := range settings {
-		item := xsInfo.getPropItem(s.prop)
-		if item != nil {
-			xsInfo.items = xsInfo.modifyProperty(s)
-			continue
-		}
-
-		if s.value == nil {
-			continue
-		}
+	if !xsInfo.applySettings(settings, removed) {
+		return nil
+	}
 
-		var tmp *xsItemInfo
-		switch s.sType {
-		case settingTypeInteger:
-			tmp = newXSItemInteger(s.prop, s.value.(int32))
-		case settingTypeString:
-			tmp = newXSItemString(s.prop, s.value.(string))
-		case settingTypeColor:
-			tmp = newXSItemColor(s.prop, s.value.([4]uint16))
-		}
+	data := marshalSettingData(xsInfo)
+	return m.changeSettingProp(data)
+}
 
-		xsInfo.items = append(xsInfo.items, *tmp)
-		xsInfo.numSettings++
+// removeSetting 从 xsettings 属性中删除 prop，返回 prop 是否存在
+func (m *XSManager) removeSetting(prop string) (bool, error) {
+	m.settingsLocker.Lock()
+	defer m.settingsLocker.Unlock()
+	datas, err := getSettingPropValue(m.owner, m.conn)
+	if err != nil {
+		return false, err
 	}
 
+	xsInfo := unmarshalSettingData(datas)
+	if !xsInfo.removeProperty(prop) {
+		return false, nil
+	}
+	xsInfo.serial++
 	data := marshalSettingData(xsInfo)
-	return changeSettingProp(m.owner, data, m.conn)
+	return true, m.changeSettingProp(data)
 }
 
 func (m *XSManager) getSettingsInSchema() []xsSetting {
@@ -296,7 +326,8 @@ func (m *XSManager) getSettingsInSchema() []xsSetting {
 			value: value,
 		})
 	}
-	return settings
+	extraSettings, _ := m.updateExtraSettingProps()
+	return append(settings, extraSettings...)
 }
 
 func (m *XSManager) handleGSettingsChangedCb(key string) {
@@ -319,25 +350,84 @@ func (m *XSManager) handleGSettingsChangedCb(key string) {
 	case gsKeyWindowScale:
 		// 删除m.updateDPI()，保证设置屏幕缩放比例不会立刻生效
 		return
+	case gsKeyExtraSettings:
+		m.queueSettingsChange(key)
+		return
+	case gsKeyAppearanceMode, gsKeyAppearanceLightTime, gsKeyAppearanceDarkTime,
+		gsKeyAppearanceLightSettings, gsKeyAppearanceDarkSettings:
+		m.updateAppearance(true)
+		return
+	case gsKeyAutoReclaimOwnership:
+		if m.cfgHelper.GetBoolean(key) {
+			err := m.reclaim()
+			if err != nil {
+				logger.Warning(err)
+			}
+		}
+		return
+	case gsKeyAppScaleFactors:
+		m.appScaleMu.Lock()
+		err := m.publishAppScaleFactors(m.getAppScaleFactors())
+		m.appScaleMu.Unlock()
+		if err != nil {
+			logger.Warning(err)
+		}
+		return
 	}
-	info := gsInfos.getByGSKey(key)
-	if info == nil {
+	if gsInfos.getByGSKey(key) == nil {
 		return
 	}
+	m.queueSettingsChange(key)
+}
 
-	value, err := info.getValue(m.cfgHelper)
-	if err == nil {
-		err = m.setSettings([]xsSetting{
-			{
-				sType: info.getKeySType(),
-				prop:  info.xsKey,
-				value: value,
-			},
-		})
+// queueSettingsChange 等待 settingsChangedDelay 后把这段时间内变化的键一起写到 xsettings 属性
+func (m *XSManager) queueSettingsChange(key string) {
+	m.pendingKeysMu.Lock()
+	defer m.pendingKeysMu.Unlock()
+	if !strv.Strv(m.pendingKeys).Contains(key) {
+		m.pendingKeys = append(m.pendingKeys, key)
+	}
+	if m.pendingKeysTimer == nil {
+		m.pendingKeysTimer = time.AfterFunc(settingsChangedDelay, m.applyPendingSettings)
+	}
+}
+
+func (m *XSManager) applyPendingSettings() {
+	m.pendingKeysMu.Lock()
+	keys := m.pendingKeys
+	m.pendingKeys = nil
+	m.pendingKeysTimer = nil
+	m.pendingKeysMu.Unlock()
+
+	var settings []xsSetting
+	var removed []string
+	for _, key := range keys {
+		if key == gsKeyExtraSettings {
+			var extraSettings []xsSetting
+			extraSettings, removed = m.updateExtraSettingProps()
+			settings = append(settings, extraSettings...)
+			continue
+		}
+		info := gsInfos.getByGSKey(key)
+		if info == nil {
+			continue
+		}
+		value, err := info.getValue(m.cfgHelper)
 		if err != nil {
 			logger.Warning(err)
+			continue
 		}
-	} else {
+		settings = append(settings, xsSetting{
+			sType: info.getKeySType(),
+			prop:  info.xsKey,
+			value: value,
+		})
+	}
+	if len(settings) == 0 && len(removed) == 0 {
+		return
+	}
+	err := m.updateSettings(settings, removed)
+	if err != nil {
 		logger.Warning(err)
 	}
 }
@@ -352,6 +442,10 @@ func Start(conn *x.Conn, recommendedScaleFactor float64, service *dbusutil.Servi
 	}
 	m.updateDPI()
 	m.updateXResources()
+	err = m.publishAppScaleFactors(m.getAppScaleFactors())
+	if err != nil {
+		logger.Warning("failed to publish app scale factors:", err)
+	}
 	go m.updateFirefoxDPI()
 
 	err = service.Export(xsDBusPath, m)
@@ -7,6 +7,7 @@ package xsettings
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/linuxdeepin/go-lib/dbusutil"
@@ -27,7 +28,8 @@ func (m *XSManager) ListProps() (string, *dbus.Error) {
 	if infos == nil || len(infos.items) == 0 {
 		return "", nil
 	}
-	return infos.items.listProps(), nil
+	props, err := infos.items.listProps()
+	return props, dbusutil.ToError(err)
 }
 
 func (m *XSManager) SetInteger(prop string, v int32) *dbus.Error {
@@ -162,12 +164,113 @@ func (m *XSManager) getSettingValue(prop string) (interface{}, uint8, error) {
 func (m *XSManager) setGSettingsByXProp(prop string, v interface{}) error {
 	info := gsInfos.getByXSKey(prop)
 	if info == nil {
-		return errPropNotFound
+		return m.setExtraSetting(prop, v)
 	}
 
 	return info.setValue(m.cfgHelper, v)
 }
 
+// SetMany 一次设置多个属性，值的类型为 int32、string 或者长度为 4 的 []uint16（color），
+// 所有修改只写一次 xsettings 属性，serial 只增加一次。
+func (m *XSManager) SetMany(values map[string]dbus.Variant) *dbus.Error {
+	err := m.setMany(values)
+	return dbusutil.ToError(err)
+}
+
+func variantToXSSetting(prop string, v dbus.Variant) (xsSetting, error) {
+	var setting = xsSetting{prop: prop}
+	switch value := v.Value().(type) {
+	case int32:
+		setting.sType, setting.value = settingTypeInteger, value
+	case string:
+		setting.sType, setting.value = settingTypeString, value
+	case []uint16:
+		if len(value) != 4 {
+			return setting, fmt.Errorf("length of %s value is not 4", prop)
+		}
+		var val [4]uint16
+		copy(val[:], value)
+		setting.sType, setting.value = settingTypeColor, val
+	default:
+		return setting, fmt.Errorf("invalid type %s of %s", v.Signature(), prop)
+	}
+	return setting, nil
+}
+
+func (m *XSManager) setMany(values map[string]dbus.Variant) error {
+	props := make([]string, 0, len(values))
+	for prop := range values {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	settings := make([]xsSetting, 0, len(props))
+	for _, prop := range props {
+		setting, err := variantToXSSetting(prop, values[prop])
+		if err != nil {
+			return err
+		}
+		settings = append(settings, setting)
+	}
+	return m.setManySettings(settings)
+}
+
+// setManySettings 一次写入多个属性并保存到 DConfig
+func (m *XSManager) setManySettings(settings []xsSetting) error {
+	for _, setting := range settings {
+		// 类型不一致时不能修改已有的属性
+		if info := gsInfos.getByXSKey(setting.prop); info != nil {
+			if info.getKeySType() != setting.sType {
+				return fmt.Errorf("%s: %v", setting.prop, errPropTypeNotMatch)
+			}
+		} else if _, sType, err := m.getSettingValue(setting.prop); err == nil && sType != setting.sType {
+			return fmt.Errorf("%s: %v", setting.prop, errPropTypeNotMatch)
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	err := m.setSettings(settings)
+	if err != nil {
+		logger.Debugf("Set %v failed: %v", settings, err)
+		return err
+	}
+
+	var extras []xsSetting
+	for _, setting := range settings {
+		info := gsInfos.getByXSKey(setting.prop)
+		if info == nil {
+			extras = append(extras, setting)
+			continue
+		}
+		err = info.setValue(m.cfgHelper, setting.value)
+		if err != nil {
+			logger.Warningf("failed to save %s: %v", setting.prop, err)
+		}
+	}
+	if len(extras) > 0 {
+		return m.setExtraSettings(extras)
+	}
+	return nil
+}
+
+// Unset 删除通过 SetInteger、SetString、SetColor 设置的额外属性
+func (m *XSManager) Unset(prop string) *dbus.Error {
+	err := m.unsetSetting(prop)
+	return dbusutil.ToError(err)
+}
+
+// GetColorScheme 获取当前外观，值和 org.freedesktop.appearance color-scheme 一样，0 为没有偏好，1 为深色，2 为浅色
+func (m *XSManager) GetColorScheme() (colorScheme uint32, busErr *dbus.Error) {
+	return m.getColorScheme(), nil
+}
+
+// GetManagedScreens 获取当前管理的 X screen 的序号
+func (m *XSManager) GetManagedScreens() (screens []int32, busErr *dbus.Error) {
+	return m.getManagedScreens(), nil
+}
+
 func (m *XSManager) GetScaleFactor() (float64, *dbus.Error) {
 	return m.getScaleFactor(), nil
 }
@@ -186,3 +289,25 @@ func (m *XSManager) GetScreenScaleFactors() (map[string]float64, *dbus.Error) {
 	v := m.getScreenScaleFactors()
 	return v, nil
 }
+
+// SetAppScaleFactor 设置单个应用的缩放比例，app 为 desktop id 或者可执行文件，scale 为 0 表示删除。
+func (m *XSManager) SetAppScaleFactor(app string, scale float64) *dbus.Error {
+	err := m.setAppScaleFactor(app, scale)
+	return dbusutil.ToError(err)
+}
+
+func (m *XSManager) GetAppScaleFactors() (factors map[string]float64, busErr *dbus.Error) {
+	return m.getAppScaleFactors(), nil
+}
+
+// GetAppScaleFactor 获取应用的缩放比例，没有单独设置时 ok 为 false。
+func (m *XSManager) GetAppScaleFactor(desktopId, exe string) (scale float64, ok bool, busErr *dbus.Error) {
+	scale, ok = m.getAppScaleFactor(desktopId, exe)
+	return scale, ok, nil
+}
+
+// Reclaim 重新获取被其他程序拿走的 XSETTINGS 选择
+func (m *XSManager) Reclaim() *dbus.Error {
+	err := m.reclaim()
+	return dbusutil.ToError(err)
+}
@@ -7,7 +7,7 @@ package xsettings
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
+	"encoding/json"
 	"io"
 )
 
@@ -61,15 +61,34 @@ type xsDataInfo struct {
 	items xsItemInfos
 }
 
-func (infos xsItemInfos) listProps() string {
-	var content = "["
-	for i, info := range infos {
-		if i != 0 {
-			content += ","
+// listProps 返回 json 数组，元素为带名字和类型的属性值
+func (infos xsItemInfos) listProps() (string, error) {
+	props := make([]xsPropValue, 0, len(infos))
+	for _, info := range infos {
+		prop, err := newXSPropValue(info.header.name, info.header.sType, info.getValue())
+		if err != nil {
+			return "", err
 		}
-		content += fmt.Sprintf("%q", info.header.name)
+		props = append(props, prop)
 	}
-	return content + "]"
+	data, err := json.Marshal(props)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getValue 返回的值的类型和 xsSetting 中的一样
+func (item *xsItemInfo) getValue() interface{} {
+	switch v := item.value.(type) {
+	case *integerValueInfo:
+		return v.value
+	case *stringValueInfo:
+		return v.value
+	case *colorValueInfo:
+		return [4]uint16{v.red, v.green, v.blue, v.alpha}
+	}
+	return nil
 }
 
 func (info *xsDataInfo) getPropItem(prop string) *xsItemInfo {
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package xsettings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/wm/ewmh"
+)
+
+// 每个 X screen 有自己的 _XSETTINGS_S<n> 选择，在每个 screen 的根窗口下创建一个 owner 窗口，
+// 所有 owner 窗口上的 _XSETTINGS_SETTINGS 属性保持一致。
+// 选择被其他 xsettings 管理器拿走后会收到 SelectionClear 事件或者根窗口上的 MANAGER 消息，
+// 此时不再管理这个 screen，发送 OwnershipLost 信号，但仍然更新它的 owner 窗口上的属性，
+// 以便重新获取选择后直接生效。
+
+const (
+	// 丢失选择后是否自动重新获取
+	gsKeyAutoReclaimOwnership = "auto-reclaim-ownership"
+
+	autoReclaimDelay = time.Second
+	// 在这段时间内反复丢失选择时累计自动重新获取的次数，超过 maxAutoReclaimCount 次后不再争抢
+	autoReclaimInterval = time.Minute
+	maxAutoReclaimCount = 3
+)
+
+type xsScreen struct {
+	num   int
+	root  x.Window
+	owner x.Window
+	// 是否是选择的 owner
+	managed bool
+
+	lostTime     time.Time
+	reclaimCount int
+}
+
+// markLost 记录丢失选择的时间，和上次丢失间隔较久时重新计数
+func (s *xsScreen) markLost(now time.Time) {
+	if now.Sub(s.lostTime) > autoReclaimInterval {
+		s.reclaimCount = 0
+	}
+	s.lostTime = now
+}
+
+// takeReclaimChance 返回是否还可以自动重新获取选择
+func (s *xsScreen) takeReclaimChance() bool {
+	if s.reclaimCount >= maxAutoReclaimCount {
+		return false
+	}
+	s.reclaimCount++
+	return true
+}
+
+// initScreens 为每个 screen 创建 owner 窗口并获取选择，默认 screen 失败时返回错误
+func (m *XSManager) initScreens() error {
+	roots := m.conn.GetSetup().Roots
+	for num := range roots {
+		root := roots[num].Root
+		owner, err := createSettingWindow(m.conn, root)
+		if err != nil {
+			if num == m.conn.ScreenNumber {
+				return err
+			}
+			logger.Warningf("failed to create owner window on screen %d: %v", num, err)
+			continue
+		}
+		screen := &xsScreen{
+			num:   num,
+			root:  root,
+			owner: owner,
+		}
+		m.screens = append(m.screens, screen)
+		if num == m.conn.ScreenNumber {
+			m.owner = owner
+		}
+		// 接收其他管理器在根窗口上发送的 MANAGER 消息
+		err = selectStructureNotify(m.conn, root)
+		if err != nil {
+			logger.Warningf("failed to select events on root window of screen %d: %v", num, err)
+		}
+
+		err = acquireSelection(m.conn, owner, root, getSettingPropScreen(num))
+		if err != nil {
+			logger.Warningf("failed to acquire xsettings selection of screen %d: %v", num, err)
+			continue
+		}
+		screen.managed = true
+		logger.Debugf("screen %d owner: %d", num, owner)
+	}
+
+	if m.owner == 0 {
+		return errors.New("not found default screen")
+	}
+	if len(m.getManagedScreens()) == 0 {
+		return errors.New("failed to acquire xsettings selection of any screen")
+	}
+	return nil
+}
+
+func (m *XSManager) getManagedScreens() []int32 {
+	m.screensMu.Lock()
+	defer m.screensMu.Unlock()
+	var result []int32
+	for _, screen := range m.screens {
+		if screen.managed {
+			result = append(result, int32(screen.num))
+		}
+	}
+	return result
+}
+
+// changeSettingProp 设置所有 owner 窗口上的属性
+func (m *XSManager) changeSettingProp(data []byte) error {
+	var errs []error
+	for _, screen := range m.screens {
+		err := changeSettingProp(screen.owner, data, m.conn)
+		if err != nil {
+			if screen.owner == m.owner {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	for _, err := range errs {
+		logger.Warning(err)
+	}
+	return nil
+}
+
+func (m *XSManager) listenXEvents() {
+	eventChan := m.conn.MakeAndAddEventChan(10)
+	go func() {
+		for ev := range eventChan {
+			switch ev.GetEventCode() {
+			case x.SelectionClearEventCode:
+				event, err := x.NewSelectionClearEvent(ev)
+				if err != nil {
+					logger.Warning(err)
+					continue
+				}
+				m.handleSelectionClear(event)
+			case x.ClientMessageEventCode:
+				event, err := x.NewClientMessageEvent(ev)
+				if err != nil {
+					logger.Warning(err)
+					continue
+				}
+				m.handleManagerMessage(event)
+			}
+		}
+	}()
+}
+
+func (m *XSManager) getScreenBySelection(selection x.Atom) *xsScreen {
+	for _, screen := range m.screens {
+		atom, err := getAtomByProp(getSettingPropScreen(screen.num), m.conn)
+		if err == nil && atom == selection {
+			return screen
+		}
+	}
+	return nil
+}
+
+// handleSelectionClear 选择被其他程序拿走了
+func (m *XSManager) handleSelectionClear(event *x.SelectionClearEvent) {
+	screen := m.getScreenBySelection(event.Selection)
+	if screen == nil || screen.owner != event.Owner {
+		return
+	}
+	newOwner, err := getSelectionOwner(getSettingPropScreen(screen.num), m.conn)
+	if err != nil {
+		logger.Warning(err)
+	}
+	m.handleOwnershipLost(screen, newOwner)
+}
+
+// handleManagerMessage 其他管理器获取选择后会在根窗口上发送 MANAGER 消息
+func (m *XSManager) handleManagerMessage(event *x.ClientMessageEvent) {
+	managerAtom, err := getAtomByProp(settingPropManager, m.conn)
+	if err != nil || event.Type != managerAtom || event.Format != 32 {
+		return
+	}
+	data := event.Data.GetData32()
+	screen := m.getScreenBySelection(x.Atom(data[1]))
+	newOwner := x.Window(data[2])
+	if screen == nil || screen.root != event.Window || screen.owner == newOwner {
+		return
+	}
+	m.handleOwnershipLost(screen, newOwner)
+}
+
+// handleOwnershipLost 不再管理这个 screen，发送 OwnershipLost 信号，根据配置自动重新获取选择
+func (m *XSManager) handleOwnershipLost(screen *xsScreen, newOwner x.Window) {
+	m.screensMu.Lock()
+	if !screen.managed {
+		m.screensMu.Unlock()
+		return
+	}
+	screen.managed = false
+	screen.markLost(time.Now())
+	m.screensMu.Unlock()
+
+	var pid uint32
+	if newOwner != 0 {
+		var err error
+		pid, err = ewmh.GetWMPid(m.conn, newOwner).Reply(m.conn)
+		if err != nil {
+			logger.Debugf("failed to get pid of window %d: %v", newOwner, err)
+		}
+	}
+	logger.Warningf("lost xsettings selection of screen %d, new owner: %d, pid: %d",
+		screen.num, newOwner, pid)
+	err := m.service.Emit(m, "OwnershipLost", int32(screen.num), pid)
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	if m.cfgHelper.GetBoolean(gsKeyAutoReclaimOwnership) {
+		m.scheduleReclaim(screen)
+	}
+}
+
+func (m *XSManager) scheduleReclaim(screen *xsScreen) {
+	m.screensMu.Lock()
+	ok := screen.takeReclaimChance()
+	m.screensMu.Unlock()
+	if !ok {
+		logger.Warningf("xsettings selection of screen %d lost too many times, stop reclaiming", screen.num)
+		return
+	}
+	time.AfterFunc(autoReclaimDelay, func() {
+		err := m.reclaimScreen(screen)
+		if err != nil {
+			logger.Warningf("failed to reclaim xsettings selection of screen %d: %v", screen.num, err)
+		}
+	})
+}
+
+func (m *XSManager) reclaimScreen(screen *xsScreen) error {
+	m.screensMu.Lock()
+	defer m.screensMu.Unlock()
+	if screen.managed {
+		return nil
+	}
+	err := acquireSelection(m.conn, screen.owner, screen.root, getSettingPropScreen(screen.num))
+	if err != nil {
+		return err
+	}
+	screen.managed = true
+	logger.Infof("reclaimed xsettings selection of screen %d", screen.num)
+	return nil
+}
+
+// reclaim 重新获取所有丢失的选择，同时重置自动重新获取的次数
+func (m *XSManager) reclaim() error {
+	var failed []int
+	for _, screen := range m.screens {
+		m.screensMu.Lock()
+		screen.reclaimCount = 0
+		m.screensMu.Unlock()
+
+		err := m.reclaimScreen(screen)
+		if err != nil {
+			logger.Warningf("failed to reclaim xsettings selection of screen %d: %v", screen.num, err)
+			failed = append(failed, screen.num)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to reclaim xsettings selection of screens %v", failed)
+	}
+	return nil
+}
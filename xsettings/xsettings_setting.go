@@ -5,15 +5,19 @@
 package xsettings
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/util/wm/ewmh"
 )
 
 const (
-	settingPropScreen   = "_XSETTINGS_S0"
-	settingPropSettings = "_XSETTINGS_SETTINGS"
+	// 后面加上 screen 的序号
+	settingPropScreenPrefix = "_XSETTINGS_S"
+	settingPropSettings     = "_XSETTINGS_SETTINGS"
+	settingPropManager      = "MANAGER"
 
 	xsDataOrder  = 0
 	xsDataSerial = 0
@@ -77,19 +81,18 @@ func changeSettingProp(owner x.Window, data []byte, conn *x.Conn) error {
 		xsDataFormat, data).Check(conn)
 }
 
-func createSettingWindow(conn *x.Conn) (x.Window, error) {
-	screenAtom, err := getAtomByProp(settingPropScreen, conn)
-	if err != nil {
-		return 0, err
-	}
+func getSettingPropScreen(num int) string {
+	return settingPropScreenPrefix + strconv.Itoa(num)
+}
 
+// createSettingWindow 在根窗口 root 下创建 owner 窗口
+func createSettingWindow(conn *x.Conn, root x.Window) (x.Window, error) {
 	xid, err := conn.AllocID()
 	if err != nil {
 		return 0, err
 	}
 	wid := x.Window(xid)
 
-	root := conn.GetDefaultScreen().Root
 	err = x.CreateWindowChecked(conn, 0, wid, root,
 		0, 0, 1, 1, 0,
 		x.WindowClassInputOnly, x.CopyFromParent,
@@ -102,14 +105,48 @@ func createSettingWindow(conn *x.Conn) (x.Window, error) {
 	if err != nil {
 		return 0, err
 	}
+	return wid, nil
+}
 
-	err = x.SetSelectionOwnerChecked(conn, wid, screenAtom,
+// acquireSelection 让 wid 成为选择 prop 的 owner，并按 XSETTINGS 规范在根窗口上发送 MANAGER 消息
+func acquireSelection(conn *x.Conn, wid, root x.Window, prop string) error {
+	selection, err := getAtomByProp(prop, conn)
+	if err != nil {
+		return err
+	}
+	err = x.SetSelectionOwnerChecked(conn, wid, selection,
 		x.CurrentTime).Check(conn)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if !isSelectionOwned(prop, wid, conn) {
+		return fmt.Errorf("owned '%s' failed", prop)
 	}
 
-	return wid, nil
+	managerAtom, err := getAtomByProp(settingPropManager, conn)
+	if err != nil {
+		return err
+	}
+	var data x.ClientMessageData
+	data.SetData32(&[5]uint32{uint32(x.CurrentTime), uint32(selection), uint32(wid), 0, 0})
+	w := x.NewWriter()
+	x.WriteClientMessageEvent(w, &x.ClientMessageEvent{
+		Format: 32,
+		Window: root,
+		Type:   managerAtom,
+		Data:   data,
+	})
+	return x.SendEventChecked(conn, false, root, x.EventMaskStructureNotify, w.Bytes()).Check(conn)
+}
+
+// selectStructureNotify 在 win 上增加 StructureNotify 事件，保留这个连接已选择的其他事件
+func selectStructureNotify(conn *x.Conn, win x.Window) error {
+	attrs, err := x.GetWindowAttributes(conn, win).Reply(conn)
+	if err != nil {
+		return err
+	}
+	mask := attrs.YourEventMask | x.EventMaskStructureNotify
+	return x.ChangeWindowAttributesChecked(conn, win, x.CWEventMask, []uint32{mask}).Check(conn)
 }
 
 func changeWindowPid(conn *x.Conn, wid x.Window) error {
@@ -6,9 +6,12 @@ package xsettings
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"testing"
+	"time"
 
+	dbus "github.com/godbus/dbus/v5"
 	"github.com/linuxdeepin/go-lib/utils"
 	C "gopkg.in/check.v1"
 )
@@ -237,3 +240,249 @@ user_pref("toolkit.telemetry.reportingpolicy.firstRun", false);
 		os.Remove(info.dest)
 	}
 }
+
+func (*testWrapper) TestNormalizeAppScaleKey(c *C.C) {
+	key, err := normalizeAppScaleKey(" /usr/share/applications/deepin-terminal.desktop ")
+	c.Check(err, C.IsNil)
+	c.Check(key, C.Equals, "deepin-terminal.desktop")
+
+	key, err = normalizeAppScaleKey("/usr/bin/java")
+	c.Check(err, C.IsNil)
+	c.Check(key, C.Equals, "/usr/bin/java")
+
+	for _, app := range []string{"", " ", "a=b", "a;b", ".desktop"} {
+		_, err = normalizeAppScaleKey(app)
+		c.Check(err, C.NotNil)
+	}
+}
+
+func (*testWrapper) TestCheckAppScaleFactor(c *C.C) {
+	for _, info := range []struct {
+		scale  float64
+		result float64
+	}{
+		{0, 0},
+		{0.5, 1},
+		{1.25, 1.25},
+		{1000, 3},
+	} {
+		scale, err := checkAppScaleFactor(info.scale)
+		c.Check(err, C.IsNil)
+		c.Check(scale, C.Equals, info.result)
+	}
+
+	for _, scale := range []float64{-1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err := checkAppScaleFactor(scale)
+		c.Check(err, C.NotNil)
+	}
+}
+
+func (*testWrapper) TestMatchAppScaleFactor(c *C.C) {
+	factors := parseScreenFactors(joinAppScaleFactors(map[string]float64{
+		"deepin-terminal.desktop": 1,
+		"/opt/app/bin/tool":       1.5,
+		"java":                    2,
+	}))
+	c.Check(joinAppScaleFactors(factors), C.Equals,
+		"/opt/app/bin/tool=1.50;deepin-terminal.desktop=1.00;java=2.00")
+
+	var infos = []struct {
+		desktopId string
+		exe       string
+		scale     float64
+		ok        bool
+	}{
+		{"deepin-terminal", "/usr/bin/deepin-terminal", 1, true},
+		{"/usr/share/applications/deepin-terminal.desktop", "", 1, true},
+		{"", "/opt/app/bin/tool", 1.5, true},
+		{"", "/usr/bin/tool", 0, false},
+		{"", "/usr/lib/jvm/bin/java", 2, true},
+		{"other.desktop", "other", 0, false},
+	}
+	for _, info := range infos {
+		scale, ok := matchAppScaleFactor(factors, info.desktopId, info.exe)
+		c.Check(scale, C.Equals, info.scale)
+		c.Check(ok, C.Equals, info.ok)
+	}
+}
+
+func (*testWrapper) TestGetSettingPropScreen(c *C.C) {
+	c.Check(getSettingPropScreen(0), C.Equals, "_XSETTINGS_S0")
+	c.Check(getSettingPropScreen(1), C.Equals, "_XSETTINGS_S1")
+}
+
+func (*testWrapper) TestScreenReclaimChance(c *C.C) {
+	var screen xsScreen
+	now := time.Now()
+	for i := 0; i < maxAutoReclaimCount; i++ {
+		screen.markLost(now)
+		c.Check(screen.takeReclaimChance(), C.Equals, true)
+	}
+	screen.markLost(now)
+	c.Check(screen.takeReclaimChance(), C.Equals, false)
+
+	// 间隔较久后重新计数
+	screen.markLost(now.Add(autoReclaimInterval + time.Second))
+	c.Check(screen.takeReclaimChance(), C.Equals, true)
+}
+
+func (*testWrapper) TestListProps(c *C.C) {
+	info := unmarshalSettingData(xsTestDatas)
+	props, err := info.items.listProps()
+	c.Assert(err, C.IsNil)
+	c.Check(props, C.Equals, `[{"name":"Net/DoubleClick","type":"integer","value":5},`+
+		`{"name":"Net/ThemeName","type":"string","value":"Deepin"},`+
+		`{"name":"Net/SchemaColor","type":"color","value":[0,128,255,100]}]`)
+}
+
+func (*testWrapper) TestRemoveProperty(c *C.C) {
+	info := unmarshalSettingData(xsTestDatas)
+	c.Check(info.removeProperty("Net/ThemeName"), C.Equals, true)
+	c.Check(info.numSettings, C.Equals, uint32(2))
+	c.Check(len(info.items), C.Equals, 2)
+	c.Check(info.getPropItem("Net/ThemeName"), C.IsNil)
+	c.Check(info.getPropItem("Net/SchemaColor"), C.NotNil)
+	c.Check(info.removeProperty("Net/ThemeName"), C.Equals, false)
+}
+
+func (*testWrapper) TestExtraSettings(c *C.C) {
+	settings := parseExtraSettings(`{"Net/Foo":{"type":"integer","value":3},` +
+		`"Gtk/Bar":{"type":"color","value":[1,2,3,4]},"Bad":{"type":"double","value":1}}`)
+	c.Check(len(settings), C.Equals, 3)
+	c.Check(extraSettingsToXSSettings(settings), C.DeepEquals, []xsSetting{
+		{sType: settingTypeColor, prop: "Gtk/Bar", value: [4]uint16{1, 2, 3, 4}},
+		{sType: settingTypeInteger, prop: "Net/Foo", value: int32(3)},
+	})
+	c.Check(len(parseExtraSettings("invalid")), C.Equals, 0)
+	c.Check(len(parseExtraSettings("")), C.Equals, 0)
+
+	value, err := newXSPropValue("", settingTypeString, "deepin")
+	c.Assert(err, C.IsNil)
+	setting, err := value.toXSSetting("Net/ThemeName")
+	c.Assert(err, C.IsNil)
+	c.Check(setting, C.DeepEquals, xsSetting{sType: settingTypeString, prop: "Net/ThemeName", value: "deepin"})
+
+	sType, err := getValueSettingType([4]uint16{})
+	c.Assert(err, C.IsNil)
+	c.Check(sType, C.Equals, settingTypeColor)
+	_, err = getValueSettingType(1.0)
+	c.Check(err, C.NotNil)
+}
+
+func (*testWrapper) TestVariantToXSSetting(c *C.C) {
+	setting, err := variantToXSSetting("Net/DoubleClick", dbus.MakeVariant(int32(5)))
+	c.Assert(err, C.IsNil)
+	c.Check(setting, C.DeepEquals, xsSetting{sType: settingTypeInteger, prop: "Net/DoubleClick", value: int32(5)})
+
+	setting, err = variantToXSSetting("Net/ThemeName", dbus.MakeVariant("deepin"))
+	c.Assert(err, C.IsNil)
+	c.Check(setting, C.DeepEquals, xsSetting{sType: settingTypeString, prop: "Net/ThemeName", value: "deepin"})
+
+	setting, err = variantToXSSetting("Qt/ActiveColor", dbus.MakeVariant([]uint16{1, 2, 3, 4}))
+	c.Assert(err, C.IsNil)
+	c.Check(setting, C.DeepEquals, xsSetting{sType: settingTypeColor, prop: "Qt/ActiveColor", value: [4]uint16{1, 2, 3, 4}})
+
+	_, err = variantToXSSetting("Qt/ActiveColor", dbus.MakeVariant([]uint16{1, 2, 3}))
+	c.Check(err, C.NotNil)
+	_, err = variantToXSSetting("Net/DoubleClick", dbus.MakeVariant(1.5))
+	c.Check(err, C.NotNil)
+}
+
+func (*testWrapper) TestGetColorSchemeName(c *C.C) {
+	c.Check(getColorSchemeName(colorSchemeDefault), C.Equals, "default")
+	c.Check(getColorSchemeName(colorSchemePreferDark), C.Equals, "prefer-dark")
+	c.Check(getColorSchemeName(colorSchemePreferLight), C.Equals, "prefer-light")
+}
+
+func (*testWrapper) TestApplySettings(c *C.C) {
+	info := unmarshalSettingData(xsTestDatas)
+	serial := info.serial
+	settings := []xsSetting{
+		{sType: settingTypeInteger, prop: "Net/DoubleClick", value: int32(6)},
+		{sType: settingTypeString, prop: "Net/ThemeName", value: "deepin-dark"},
+		{sType: settingTypeString, prop: "Net/Foo", value: "bar"},
+	}
+	// SetMany 只增加一次 serial
+	c.Check(info.applySettings(settings, nil), C.Equals, true)
+	c.Check(info.serial, C.Equals, serial+1)
+	c.Check(info.numSettings, C.Equals, uint32(4))
+	c.Check(info.getPropItem("Net/ThemeName").getValue(), C.Equals, "deepin-dark")
+	c.Check(info.getPropItem("Net/Foo").getValue(), C.Equals, "bar")
+
+	// 保存到 DConfig 后的回调写入相同的值，serial 不变
+	c.Check(info.applySettings(settings[:2], nil), C.Equals, false)
+	c.Check(info.applySettings(settings[2:], nil), C.Equals, false)
+	c.Check(info.serial, C.Equals, serial+1)
+
+	c.Check(info.applySettings([]xsSetting{{sType: settingTypeString, prop: "Net/Foo"}}, nil), C.Equals, false)
+	c.Check(info.serial, C.Equals, serial+1)
+
+	// extra-settings 中删除的属性和修改的属性一起写入
+	c.Check(info.applySettings([]xsSetting{
+		{sType: settingTypeInteger, prop: "Net/DoubleClick", value: int32(7)},
+	}, []string{"Net/Foo", "Net/Bar"}), C.Equals, true)
+	c.Check(info.serial, C.Equals, serial+2)
+	c.Check(info.numSettings, C.Equals, uint32(3))
+	c.Check(info.getPropItem("Net/Foo"), C.IsNil)
+	c.Check(info.getPropItem("Net/DoubleClick").getValue(), C.Equals, int32(7))
+	c.Check(info.applySettings(nil, []string{"Net/Foo"}), C.Equals, false)
+}
+
+// testConfig 只实现读取字符串
+type testConfig struct {
+	configHeler
+	values map[string]string
+}
+
+func (cfg *testConfig) GetString(key string) string {
+	return cfg.values[key]
+}
+
+func (*testWrapper) TestGetSavedAppearanceSettings(c *C.C) {
+	m := &XSManager{cfgHelper: &testConfig{values: map[string]string{
+		"theme-name":                 "deepin",
+		gsKeyExtraSettings:           `{"Gtk/Saved":{"type":"string","value":"saved"}}`,
+		gsKeyAppearanceLightSettings: `{"Net/ThemeName":{"type":"string","value":"deepin"},"Gtk/Saved":{"type":"string","value":"light"}}`,
+		gsKeyAppearanceDarkSettings:  `{"Net/ThemeName":{"type":"string","value":"deepin-dark"},"Gtk/DarkOnly":{"type":"integer","value":1}}`,
+	}}}
+
+	// 只有外观设置过的属性回到 manual 时删除
+	settings, removed := m.getSavedAppearanceSettings()
+	c.Check(settings, C.DeepEquals, []xsSetting{
+		{sType: settingTypeString, prop: "Gtk/Saved", value: "saved"},
+		{sType: settingTypeString, prop: "Net/ThemeName", value: "deepin"},
+	})
+	c.Check(removed, C.DeepEquals, []string{"Gtk/DarkOnly"})
+}
+
+func (*testWrapper) TestAppearanceSchedule(c *C.C) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2022, 6, day, hour, min, 0, 0, time.UTC)
+	}
+
+	// 没有位置时日出日落是 06:00 和 18:00
+	schedule := appearanceSchedule{light: "sunrise", dark: "sunset"}
+	dark, next, err := schedule.isDark(at(1, 12, 0), nil)
+	c.Assert(err, C.IsNil)
+	c.Check(dark, C.Equals, false)
+	c.Check(next, C.Equals, at(1, 18, 0))
+
+	dark, next, err = schedule.isDark(at(1, 20, 0), nil)
+	c.Assert(err, C.IsNil)
+	c.Check(dark, C.Equals, true)
+	c.Check(next, C.Equals, at(2, 6, 0))
+
+	schedule = appearanceSchedule{light: "07:30", dark: "sunset+30"}
+	dark, next, err = schedule.isDark(at(1, 3, 0), nil)
+	c.Assert(err, C.IsNil)
+	c.Check(dark, C.Equals, true)
+	c.Check(next, C.Equals, at(1, 7, 30))
+
+	dark, next, err = schedule.isDark(at(1, 18, 10), nil)
+	c.Assert(err, C.IsNil)
+	c.Check(dark, C.Equals, false)
+	c.Check(next, C.Equals, at(1, 18, 30))
+
+	c.Check(appearanceSchedule{light: "abc", dark: "sunset"}.validate(), C.NotNil)
+	c.Check(appearanceSchedule{light: "sunset", dark: "sunset"}.validate(), C.NotNil)
+}
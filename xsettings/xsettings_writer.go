@@ -28,6 +28,69 @@ func (info *xsDataInfo) modifyProperty(setting xsSetting) xsItemInfos {
 	return tmp
 }
 
+// applySettings 修改或者添加 settings 中的属性，并删除 removed 中的属性，
+// 都没有变化时返回 false，否则 serial 加一并返回 true
+func (info *xsDataInfo) applySettings(settings []xsSetting, removed []string) bool {
+	var removedProps []string
+	for _, prop := range removed {
+		if info.getPropItem(prop) != nil {
+			removedProps = append(removedProps, prop)
+		}
+	}
+
+	var changed []xsSetting
+	for _, s := range settings {
+		if s.value == nil {
+			continue
+		}
+		if item := info.getPropItem(s.prop); item != nil && item.getValue() == s.value {
+			continue
+		}
+		changed = append(changed, s)
+	}
+	if len(changed) == 0 && len(removedProps) == 0 {
+		return false
+	}
+
+	info.serial++ // auto increment
+	for _, prop := range removedProps {
+		info.removeProperty(prop)
+	}
+	for _, s := range changed {
+		item := info.getPropItem(s.prop)
+		if item != nil {
+			info.items = info.modifyProperty(s)
+			continue
+		}
+
+		var tmp *xsItemInfo
+		switch s.sType {
+		case settingTypeInteger:
+			tmp = newXSItemInteger(s.prop, s.value.(int32))
+		case settingTypeString:
+			tmp = newXSItemString(s.prop, s.value.(string))
+		case settingTypeColor:
+			tmp = newXSItemColor(s.prop, s.value.([4]uint16))
+		}
+
+		info.items = append(info.items, *tmp)
+		info.numSettings++
+	}
+	return true
+}
+
+// removeProperty 删除属性，返回属性是否存在
+func (info *xsDataInfo) removeProperty(prop string) bool {
+	for i, item := range info.items {
+		if item.header.name == prop {
+			info.items = append(info.items[:i:i], info.items[i+1:]...)
+			info.numSettings--
+			return true
+		}
+	}
+	return false
+}
+
 func (item *xsItemInfo) changePropValue(value interface{}) {
 	switch item.header.sType {
 	case settingTypeInteger: